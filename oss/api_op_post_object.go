@@ -0,0 +1,252 @@
+package oss
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"path"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/credentials"
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/signer"
+)
+
+type PresignPostObjectRequest struct {
+	// The name of the bucket.
+	Bucket *string
+
+	// The policy of the form.
+	Policy *PostPolicy
+}
+
+type PresignPostObjectResult struct {
+	// The url that the form is posted to.
+	URL string
+
+	// The form fields of the policy and the signature, the key and the file fields are added by the caller.
+	Fields map[string]string
+
+	// The expiration time of the policy.
+	Expiration time.Time
+}
+
+// PresignPostObject signs the policy of the PostObject form, e.g. a browser uploads the object to OSS directly with the form fields.
+// The form fields of the V1 or V4 signature are returned according to the signer of the client.
+func (c *Client) PresignPostObject(ctx context.Context, request *PresignPostObjectRequest, optFns ...func(*Options)) (*PresignPostObjectResult, error) {
+	if request == nil {
+		return nil, NewErrParamNull("request")
+	}
+	if !isValidBucketName(request.Bucket) {
+		return nil, NewErrParamInvalid("request.Bucket")
+	}
+	if request.Policy == nil {
+		return nil, NewErrParamNull("request.Policy")
+	}
+
+	options := c.options.Copy()
+	opOpt := Options{}
+	for _, fn := range optFns {
+		fn(&opOpt)
+	}
+	applyOperationOpt(&options, &opOpt)
+
+	if !isValidEndpoint(options.Endpoint) {
+		return nil, NewErrParamInvalid("Endpoint")
+	}
+	if options.CredentialsProvider == nil {
+		return nil, NewErrParamNull("CredentialsProvider")
+	}
+	cred, err := options.CredentialsProvider.GetCredentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !cred.HasKeys() {
+		return nil, fmt.Errorf("credentials is null or empty")
+	}
+
+	policy := request.Policy.clone()
+	fields := map[string]string{}
+	if cred.SecurityToken != "" {
+		fields["x-oss-security-token"] = cred.SecurityToken
+	}
+
+	switch s := options.Signer.(type) {
+	case *signer.SignerV1:
+		encoded, err := encodePostPolicy(policy)
+		if err != nil {
+			return nil, err
+		}
+		fields["OSSAccessKeyId"] = cred.AccessKeyID
+		fields["policy"] = encoded
+		fields["Signature"] = s.SignPostPolicy(&cred, encoded)
+	case *signer.SignerV4:
+		signTime := time.Now().Add(c.inner.ClockOffset).UTC()
+		credential := s.PostPolicyCredential(&cred, options.Region, options.Product, signTime)
+		datetime := signTime.Format("20060102T150405Z")
+		policy.withField("x-oss-signature-version", "OSS4-HMAC-SHA256")
+		policy.withField("x-oss-credential", credential)
+		policy.withField("x-oss-date", datetime)
+		if cred.SecurityToken != "" {
+			policy.withField("x-oss-security-token", cred.SecurityToken)
+		}
+		encoded, err := encodePostPolicy(policy)
+		if err != nil {
+			return nil, err
+		}
+		fields["policy"] = encoded
+		fields["x-oss-signature-version"] = "OSS4-HMAC-SHA256"
+		fields["x-oss-credential"] = credential
+		fields["x-oss-date"] = datetime
+		fields["x-oss-signature"] = s.SignPostPolicy(&cred, options.Region, options.Product, signTime, encoded)
+	default:
+		return nil, fmt.Errorf("PostObject does not support the signer %T", options.Signer)
+	}
+
+	input := &OperationInput{Method: "POST", Bucket: request.Bucket}
+	var strUrl string
+	if options.EndpointProvider != nil {
+		strUrl = options.EndpointProvider.BuildURL(input)
+	} else {
+		host, path := buildURL(input, &options)
+		strUrl = fmt.Sprintf("%s://%s%s", options.Endpoint.Scheme, host, path)
+	}
+
+	return &PresignPostObjectResult{
+		URL:        strUrl,
+		Fields:     fields,
+		Expiration: policy.Expiration(),
+	}, nil
+}
+
+func encodePostPolicy(policy *PostPolicy) (string, error) {
+	data, err := json.Marshal(policy)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+type PostObjectRequest struct {
+	// The name of the bucket.
+	Bucket *string
+
+	// The name of the object.
+	Key *string
+
+	// The policy of the form.
+	Policy *PostPolicy
+
+	// The other form fields, e.g. Content-Type, x-oss-meta-*, success_action_status.
+	Fields map[string]string
+
+	// The size of the object data, required if it can not be got from the Body.
+	ContentLength *int64
+
+	// Object data.
+	Body io.Reader
+}
+
+type PostObjectResult struct {
+	// The entity tag (ETag). An ETag is created when an object is created to identify the content of the object.
+	ETag *string `output:"header,ETag"`
+
+	// The 64-bit CRC value of the object.
+	HashCRC64 *string `output:"header,x-oss-hash-crc64ecma"`
+
+	// Version of the object.
+	VersionId *string `output:"header,x-oss-version-id"`
+
+	ResultCommon
+}
+
+// PostObject uploads an object with the PostObject form, e.g. to check a policy before it is handed out to the browsers.
+// The form is signed by PresignPostObject, the request itself is sent anonymously.
+func (c *Client) PostObject(ctx context.Context, request *PostObjectRequest, optFns ...func(*Options)) (*PostObjectResult, error) {
+	if request == nil {
+		return nil, NewErrParamNull("request")
+	}
+	if !isValidObjectName(request.Key) {
+		return nil, NewErrParamInvalid("request.Key")
+	}
+
+	var body io.Reader = bytes.NewReader(nil)
+	if request.Body != nil {
+		body = request.Body
+	}
+	// the form is not sent chunked, its length must be known
+	size := GetReaderLen(body)
+	if request.ContentLength != nil {
+		size = *request.ContentLength
+	}
+	if size < 0 {
+		return nil, NewErrParamRequired("request.ContentLength")
+	}
+
+	presignResult, err := c.PresignPostObject(ctx, &PresignPostObjectRequest{
+		Bucket: request.Bucket,
+		Policy: request.Policy,
+	}, optFns...)
+	if err != nil {
+		return nil, err
+	}
+
+	// the file field must be the last one
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	w.WriteField("key", ToString(request.Key))
+	for _, fields := range []map[string]string{request.Fields, presignResult.Fields} {
+		keys := make([]string, 0, len(fields))
+		for k := range fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			w.WriteField(k, fields[k])
+		}
+	}
+	if _, err = w.CreateFormFile("file", path.Base(ToString(request.Key))); err != nil {
+		return nil, err
+	}
+	header := append([]byte{}, buf.Bytes()...)
+	buf.Reset()
+	w.Close()
+	trailer := buf.Bytes()
+
+	input := &OperationInput{
+		OpName: "PostObject",
+		Method: "POST",
+		Bucket: request.Bucket,
+		Headers: map[string]string{
+			HTTPHeaderContentType:   w.FormDataContentType(),
+			HTTPHeaderContentLength: strconv.FormatInt(int64(len(header))+size+int64(len(trailer)), 10),
+		},
+	}
+	// the form can be rewound by the retries if the body is seekable
+	if rs, ok := body.(io.ReadSeeker); ok && isReaderSeekable(body) {
+		if input.Body, err = newMultiReadSeeker(
+			[]io.ReadSeeker{bytes.NewReader(header), rs, bytes.NewReader(trailer)},
+			[]int64{int64(len(header)), size, int64(len(trailer))}); err != nil {
+			return nil, err
+		}
+	} else {
+		input.Body = io.MultiReader(bytes.NewReader(header), io.LimitReader(body, size), bytes.NewReader(trailer))
+	}
+
+	opOpts := append(append([]func(*Options){}, optFns...), OpCredentialsProvider(credentials.NewAnonymousCredentialsProvider()))
+	output, err := c.invokeOperation(ctx, input, opOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PostObjectResult{}
+	if err = c.unmarshalOutput(result, output, unmarshalHeader, discardBody); err != nil {
+		return nil, c.toClientError(err, "UnmarshalOutputFail", output)
+	}
+	return result, nil
+}
@@ -0,0 +1,316 @@
+package oss
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/credentials"
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/signer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostPolicy(t *testing.T) {
+	expiration := time.Date(2024, 12, 1, 12, 0, 0, 0, time.UTC)
+	policy := NewPostPolicy(expiration)
+	data, err := json.Marshal(policy)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"expiration":"2024-12-01T12:00:00.000Z","conditions":[]}`, string(data))
+
+	policy = NewPostPolicy(expiration.In(time.FixedZone("UTC+8", 8*3600))).
+		WithBucket("bucket").
+		WithKey("dir/key").
+		WithKeyPrefix("dir/").
+		WithContentLengthRange(1, 1024).
+		WithContentType("image/jpeg").
+		WithContentTypePrefix("image/").
+		WithMetadata("User", "value").
+		WithCondition(PostPolicyIn, "Content-Disposition", []string{"inline", "attachment"})
+	data, err = json.Marshal(policy)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"expiration":"2024-12-01T12:00:00.000Z","conditions":[{"bucket":"bucket"},`+
+		`["eq","$key","dir/key"],["starts-with","$key","dir/"],["content-length-range",1,1024],`+
+		`["eq","$content-type","image/jpeg"],["starts-with","$content-type","image/"],["eq","$x-oss-meta-user","value"],`+
+		`["in","$content-disposition",["inline","attachment"]]]}`, string(data))
+	assert.Equal(t, expiration, policy.Expiration().UTC())
+}
+
+func decodePostPolicy(t *testing.T, encoded string) map[string]any {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	assert.Nil(t, err)
+	policy := map[string]any{}
+	assert.Nil(t, json.Unmarshal(data, &policy))
+	return policy
+}
+
+func TestPresignPostObject(t *testing.T) {
+	cfg := LoadDefaultConfig().
+		WithCredentialsProvider(credentials.NewStaticCredentialsProvider("ak", "sk", "token")).
+		WithRegion("cn-hangzhou").
+		WithEndpoint("oss-cn-hangzhou.aliyuncs.com")
+	client := NewClient(cfg)
+	policy := NewPostPolicy(time.Now().Add(time.Hour)).WithBucket("bucket").WithKeyPrefix("dir/")
+
+	// V4
+	result, err := client.PresignPostObject(context.TODO(), &PresignPostObjectRequest{Bucket: Ptr("bucket"), Policy: policy})
+	assert.Nil(t, err)
+	assert.Equal(t, "https://bucket.oss-cn-hangzhou.aliyuncs.com/", result.URL)
+	assert.Equal(t, policy.Expiration(), result.Expiration)
+	assert.Equal(t, "OSS4-HMAC-SHA256", result.Fields["x-oss-signature-version"])
+	assert.Equal(t, "token", result.Fields["x-oss-security-token"])
+	assert.True(t, strings.HasPrefix(result.Fields["x-oss-credential"], "ak/"))
+	assert.True(t, strings.HasSuffix(result.Fields["x-oss-credential"], "/cn-hangzhou/oss/aliyun_v4_request"))
+	signTime, err := time.Parse("20060102T150405Z", result.Fields["x-oss-date"])
+	assert.Nil(t, err)
+	cred := credentials.Credentials{AccessKeyID: "ak", AccessKeySecret: "sk"}
+	assert.Equal(t, (&signer.SignerV4{}).SignPostPolicy(&cred, "cn-hangzhou", "oss", signTime, result.Fields["policy"]), result.Fields["x-oss-signature"])
+	conditions := decodePostPolicy(t, result.Fields["policy"])["conditions"].([]any)
+	assert.Len(t, conditions, 6)
+	assert.Equal(t, map[string]any{"bucket": "bucket"}, conditions[0])
+	assert.Equal(t, []any{"starts-with", "$key", "dir/"}, conditions[1])
+	assert.Equal(t, map[string]any{"x-oss-signature-version": "OSS4-HMAC-SHA256"}, conditions[2])
+	assert.Equal(t, map[string]any{"x-oss-credential": result.Fields["x-oss-credential"]}, conditions[3])
+	assert.Equal(t, map[string]any{"x-oss-date": result.Fields["x-oss-date"]}, conditions[4])
+	assert.Equal(t, map[string]any{"x-oss-security-token": "token"}, conditions[5])
+
+	// the policy of the caller is not changed
+	data, _ := json.Marshal(policy)
+	assert.NotContains(t, string(data), "x-oss-credential")
+
+	// V1
+	result, err = client.PresignPostObject(context.TODO(), &PresignPostObjectRequest{Bucket: Ptr("bucket"), Policy: policy},
+		OpSigner(&signer.SignerV1{}))
+	assert.Nil(t, err)
+	assert.Equal(t, "ak", result.Fields["OSSAccessKeyId"])
+	assert.Equal(t, "token", result.Fields["x-oss-security-token"])
+	assert.Equal(t, string(data), string(func() []byte { b, _ := base64.StdEncoding.DecodeString(result.Fields["policy"]); return b }()))
+	h := hmac.New(sha1.New, []byte("sk"))
+	io.WriteString(h, result.Fields["policy"])
+	assert.Equal(t, base64.StdEncoding.EncodeToString(h.Sum(nil)), result.Fields["Signature"])
+	assert.Empty(t, result.Fields["x-oss-signature"])
+
+	// path style
+	client = NewClient(cfg, func(o *Options) { o.UrlStyle = UrlStylePath })
+	result, err = client.PresignPostObject(context.TODO(), &PresignPostObjectRequest{Bucket: Ptr("bucket"), Policy: policy})
+	assert.Nil(t, err)
+	assert.Equal(t, "https://oss-cn-hangzhou.aliyuncs.com/bucket/", result.URL)
+
+	// invalid arguments
+	_, err = client.PresignPostObject(context.TODO(), nil)
+	assert.Contains(t, err.Error(), "null field, request")
+	_, err = client.PresignPostObject(context.TODO(), &PresignPostObjectRequest{Policy: policy})
+	assert.Contains(t, err.Error(), "invalid field, request.Bucket")
+	_, err = client.PresignPostObject(context.TODO(), &PresignPostObjectRequest{Bucket: Ptr("bucket")})
+	assert.Contains(t, err.Error(), "null field, request.Policy")
+	_, err = client.PresignPostObject(context.TODO(), &PresignPostObjectRequest{Bucket: Ptr("bucket"), Policy: policy},
+		OpSigner(&signer.NopSigner{}))
+	assert.Contains(t, err.Error(), "PostObject does not support the signer")
+	_, err = client.PresignPostObject(context.TODO(), &PresignPostObjectRequest{Bucket: Ptr("bucket"), Policy: policy},
+		OpCredentialsProvider(credentials.NewAnonymousCredentialsProvider()))
+	assert.Contains(t, err.Error(), "credentials is null or empty")
+}
+
+func TestPostObject(t *testing.T) {
+	var fieldNames []string
+	var fields map[string]string
+	var content string
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		fieldNames = nil
+		fields = map[string]string{}
+		reader, err := r.MultipartReader()
+		if err != nil {
+			w.WriteHeader(400)
+			return
+		}
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				w.WriteHeader(400)
+				return
+			}
+			data, _ := io.ReadAll(part)
+			fieldNames = append(fieldNames, part.FormName())
+			if part.FormName() == "file" {
+				content = string(data)
+			} else {
+				fields[part.FormName()] = string(data)
+			}
+		}
+		if !strings.HasPrefix(fields["key"], "dir/") {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(403)
+			io.WriteString(w, `<?xml version="1.0" encoding="UTF-8"?>
+<Error>
+  <Code>AccessDenied</Code>
+  <Message>Invalid according to Policy: Policy Condition failed: ["starts-with", "$key", "dir/"]</Message>
+  <RequestId>id-1234</RequestId>
+</Error>`)
+			return
+		}
+		w.Header().Set("x-oss-request-id", "id-1234")
+		w.Header().Set("ETag", "\"D41D8CD98F00B204E9800998ECF8427E\"")
+		w.Header().Set("x-oss-hash-crc64ecma", "870718044876840")
+		w.WriteHeader(204)
+	}))
+	defer server.Close()
+
+	cfg := LoadDefaultConfig().
+		WithCredentialsProvider(credentials.NewStaticCredentialsProvider("ak", "sk")).
+		WithRegion("cn-hangzhou").
+		WithEndpoint(server.URL)
+	client := NewClient(cfg)
+	policy := NewPostPolicy(time.Now().Add(time.Hour)).WithKeyPrefix("dir/")
+
+	result, err := client.PostObject(context.TODO(), &PostObjectRequest{
+		Bucket: Ptr("bucket"),
+		Key:    Ptr("dir/key.txt"),
+		Policy: policy,
+		Fields: map[string]string{"Content-Type": "text/plain", "x-oss-meta-user": "value"},
+		Body:   strings.NewReader("hello world"),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 204, result.StatusCode)
+	assert.Equal(t, "\"D41D8CD98F00B204E9800998ECF8427E\"", ToString(result.ETag))
+	assert.Equal(t, "870718044876840", ToString(result.HashCRC64))
+	assert.Equal(t, "", auth)
+	assert.Equal(t, "hello world", content)
+	assert.Equal(t, "key", fieldNames[0])
+	assert.Equal(t, "file", fieldNames[len(fieldNames)-1])
+	assert.Equal(t, "dir/key.txt", fields["key"])
+	assert.Equal(t, "text/plain", fields["Content-Type"])
+	assert.Equal(t, "value", fields["x-oss-meta-user"])
+	assert.Equal(t, "OSS4-HMAC-SHA256", fields["x-oss-signature-version"])
+	assert.NotEmpty(t, fields["x-oss-signature"])
+	assert.NotEmpty(t, fields["policy"])
+
+	// the body of unknown length
+	pr, pw := io.Pipe()
+	go func() {
+		io.WriteString(pw, "streaming")
+		pw.Close()
+	}()
+	_, err = client.PostObject(context.TODO(), &PostObjectRequest{
+		Bucket: Ptr("bucket"),
+		Key:    Ptr("dir/key.txt"),
+		Policy: policy,
+		Body:   pr,
+	})
+	assert.Contains(t, err.Error(), "missing required field, request.ContentLength")
+	_, err = client.PostObject(context.TODO(), &PostObjectRequest{
+		Bucket:        Ptr("bucket"),
+		Key:           Ptr("dir/key.txt"),
+		Policy:        policy,
+		ContentLength: Ptr(int64(9)),
+		Body:          pr,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "streaming", content)
+
+	// rejected by the policy
+	_, err = client.PostObject(context.TODO(), &PostObjectRequest{
+		Bucket: Ptr("bucket"),
+		Key:    Ptr("key.txt"),
+		Policy: policy,
+		Body:   strings.NewReader("hello world"),
+	})
+	var serr *ServiceError
+	assert.ErrorAs(t, err, &serr)
+	assert.Equal(t, 403, serr.StatusCode)
+	assert.Equal(t, "AccessDenied", serr.Code)
+
+	_, err = client.PostObject(context.TODO(), &PostObjectRequest{Bucket: Ptr("bucket"), Policy: policy})
+	assert.Contains(t, err.Error(), "invalid field, request.Key")
+}
+
+func TestPostObject_Retry(t *testing.T) {
+	var count int32
+	var contents []string
+	var lengths []int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&count, 1)
+		lengths = append(lengths, r.ContentLength)
+		reader, err := r.MultipartReader()
+		if err != nil {
+			w.WriteHeader(400)
+			return
+		}
+		for {
+			part, err := reader.NextPart()
+			if err != nil {
+				break
+			}
+			data, _ := io.ReadAll(part)
+			if part.FormName() == "file" {
+				contents = append(contents, string(data))
+			}
+		}
+		if n == 1 {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(500)
+			io.WriteString(w, `<?xml version="1.0" encoding="UTF-8"?>
+<Error>
+  <Code>InternalError</Code>
+  <Message>error</Message>
+  <RequestId>id-1234</RequestId>
+</Error>`)
+			return
+		}
+		w.Header().Set("x-oss-request-id", "id-1234")
+		w.WriteHeader(204)
+	}))
+	defer server.Close()
+
+	cfg := LoadDefaultConfig().
+		WithCredentialsProvider(credentials.NewStaticCredentialsProvider("ak", "sk")).
+		WithRegion("cn-hangzhou").
+		WithEndpoint(server.URL).
+		WithRetryMaxAttempts(3)
+	client := NewClient(cfg)
+	policy := NewPostPolicy(time.Now().Add(time.Hour))
+
+	// the form is sent again from the position of the body
+	body := strings.NewReader("xxhello world")
+	body.Seek(2, io.SeekStart)
+	result, err := client.PostObject(context.TODO(), &PostObjectRequest{
+		Bucket: Ptr("bucket"),
+		Key:    Ptr("key.txt"),
+		Policy: policy,
+		Body:   body,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 204, result.StatusCode)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&count))
+	assert.Equal(t, []string{"hello world", "hello world"}, contents)
+	assert.True(t, lengths[0] > int64(len("hello world")))
+	assert.Equal(t, lengths[0], lengths[1])
+
+	// the non-seekable body is not retried
+	atomic.StoreInt32(&count, 0)
+	contents = nil
+	_, err = client.PostObject(context.TODO(), &PostObjectRequest{
+		Bucket:        Ptr("bucket"),
+		Key:           Ptr("key.txt"),
+		Policy:        policy,
+		ContentLength: Ptr(int64(11)),
+		Body:          io.MultiReader(strings.NewReader("hello world")),
+	})
+	var serr *ServiceError
+	assert.ErrorAs(t, err, &serr)
+	assert.Equal(t, 500, serr.StatusCode)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&count))
+	assert.Equal(t, []string{"hello world"}, contents)
+}
@@ -0,0 +1,80 @@
+package oss
+
+import (
+	"bytes"
+	"fmt"
+	"hash"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/signer"
+)
+
+const (
+	defaultChunkedPayloadSize = 64 * 1024
+
+	chunkedPayloadTrailerSignature = "x-oss-trailer-signature"
+)
+
+// chunkedPayloadReader encodes the body into the signed chunks, and appends the crc64 of the body in the trailer.
+//
+//	<hex size>;chunk-signature=<signature>\r\n<data>\r\n
+//	...
+//	0;chunk-signature=<signature>\r\n
+//	x-oss-hash-crc64ecma:<crc64>\r\n
+//	x-oss-trailer-signature:<signature>\r\n
+//	\r\n
+type chunkedPayloadReader struct {
+	body   io.ReadCloser
+	signer *signer.ChunkSigner
+	hash   hash.Hash64
+	chunk  []byte
+	buf    bytes.Buffer
+	err    error
+}
+
+func newChunkedPayloadReader(body io.ReadCloser, s *signer.ChunkSigner, chunkSize int) *chunkedPayloadReader {
+	return &chunkedPayloadReader{
+		body:   body,
+		signer: s,
+		hash:   NewCRC64(0),
+		chunk:  make([]byte, chunkSize),
+	}
+}
+
+func (r *chunkedPayloadReader) Read(p []byte) (int, error) {
+	for r.buf.Len() == 0 && r.err == nil {
+		r.err = r.next()
+	}
+	if r.buf.Len() > 0 {
+		return r.buf.Read(p)
+	}
+	return 0, r.err
+}
+
+func (r *chunkedPayloadReader) Close() error {
+	return r.body.Close()
+}
+
+// next reads a chunk from the body, and encodes it. The last chunk and the trailer are encoded at the end of the body.
+func (r *chunkedPayloadReader) next() error {
+	n, err := io.ReadFull(r.body, r.chunk)
+	if n > 0 {
+		r.hash.Write(r.chunk[:n])
+		fmt.Fprintf(&r.buf, "%x;chunk-signature=%s\r\n", n, r.signer.SignChunk(r.chunk[:n]))
+		r.buf.Write(r.chunk[:n])
+		r.buf.WriteString("\r\n")
+	}
+	if err == nil {
+		return nil
+	}
+	if err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err
+	}
+
+	fmt.Fprintf(&r.buf, "0;chunk-signature=%s\r\n", r.signer.SignChunk(nil))
+	trailer := strings.ToLower(HeaderOssCRC64) + ":" + strconv.FormatUint(r.hash.Sum64(), 10)
+	fmt.Fprintf(&r.buf, "%s\r\n%s:%s\r\n\r\n", trailer, chunkedPayloadTrailerSignature, r.signer.SignTrailer([]byte(trailer+"\n")))
+	return io.EOF
+}
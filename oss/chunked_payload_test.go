@@ -0,0 +1,304 @@
+package oss
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/credentials"
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/signer"
+	"github.com/stretchr/testify/assert"
+)
+
+// decodeChunkedPayload verifies the chunk signatures and the trailer, and returns the decoded body.
+func decodeChunkedPayload(r *http.Request, sk string) ([]byte, error) {
+	auth := r.Header.Get("Authorization")
+	seed := auth[strings.Index(auth, "Signature=")+len("Signature="):]
+	signTime, err := time.Parse("20060102T150405Z", r.Header.Get("x-oss-date"))
+	if err != nil {
+		return nil, err
+	}
+	cs := signer.NewChunkSigner(sk, "cn-hangzhou", "oss", signTime, seed)
+
+	br := bufio.NewReader(r.Body)
+	readLine := func() (string, error) {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		if !strings.HasSuffix(line, "\r\n") {
+			return "", fmt.Errorf("invalid line %q", line)
+		}
+		return strings.TrimSuffix(line, "\r\n"), nil
+	}
+
+	var data []byte
+	for {
+		line, err := readLine()
+		if err != nil {
+			return nil, err
+		}
+		size, sig, found := strings.Cut(line, ";chunk-signature=")
+		if !found {
+			return nil, fmt.Errorf("invalid chunk header %q", line)
+		}
+		n, err := strconv.ParseInt(size, 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		chunk := make([]byte, n)
+		if _, err = io.ReadFull(br, chunk); err != nil {
+			return nil, err
+		}
+		if expected := cs.SignChunk(chunk); expected != sig {
+			return nil, fmt.Errorf("chunk signature mismatch, expected %s, got %s", expected, sig)
+		}
+		if n == 0 {
+			break
+		}
+		if line, err = readLine(); err != nil || line != "" {
+			return nil, fmt.Errorf("invalid chunk end %q, %v", line, err)
+		}
+		data = append(data, chunk...)
+	}
+
+	trailer, err := readLine()
+	if err != nil {
+		return nil, err
+	}
+	line, err := readLine()
+	if err != nil {
+		return nil, err
+	}
+	if expected := "x-oss-trailer-signature:" + cs.SignTrailer([]byte(trailer+"\n")); expected != line {
+		return nil, fmt.Errorf("trailer signature mismatch, expected %s, got %s", expected, line)
+	}
+	if line, err = readLine(); err != nil || line != "" {
+		return nil, fmt.Errorf("invalid trailer end %q, %v", line, err)
+	}
+
+	crc := NewCRC64(0)
+	crc.Write(data)
+	if expected := "x-oss-hash-crc64ecma:" + strconv.FormatUint(crc.Sum64(), 10); expected != trailer {
+		return nil, fmt.Errorf("trailer mismatch, expected %s, got %s", expected, trailer)
+	}
+	return data, nil
+}
+
+func TestChunkedSigning(t *testing.T) {
+	var mu sync.Mutex
+	var bodies [][]byte
+	var headers []http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var data []byte
+		var err error
+		if r.Header.Get("x-oss-content-sha256") == signer.StreamingPayloadTrailer {
+			data, err = decodeChunkedPayload(r, "sk")
+		} else {
+			data, err = io.ReadAll(r.Body)
+		}
+		if err != nil {
+			w.WriteHeader(400)
+			io.WriteString(w, err.Error())
+			return
+		}
+		mu.Lock()
+		bodies = append(bodies, data)
+		header := r.Header.Clone()
+		header["Transfer-Encoding"] = r.TransferEncoding
+		headers = append(headers, header)
+		mu.Unlock()
+		crc := NewCRC64(0)
+		crc.Write(data)
+		w.Header().Set("x-oss-request-id", "id-1234")
+		w.Header().Set("ETag", "\"D41D8CD98F00B204E9800998ECF8427E\"")
+		w.Header().Set(HeaderOssCRC64, strconv.FormatUint(crc.Sum64(), 10))
+		w.WriteHeader(200)
+	}))
+	defer server.Close()
+	reset := func() ([][]byte, []http.Header) {
+		mu.Lock()
+		defer mu.Unlock()
+		b, h := bodies, headers
+		bodies, headers = nil, nil
+		return b, h
+	}
+
+	data := make([]byte, 3*defaultChunkedPayloadSize+100)
+	rand.Read(data)
+	stream := func() io.Reader {
+		pr, pw := io.Pipe()
+		go func() {
+			for i := 0; i < len(data); i += 1000 {
+				end := i + 1000
+				if end > len(data) {
+					end = len(data)
+				}
+				pw.Write(data[i:end])
+			}
+			pw.Close()
+		}()
+		return pr
+	}
+
+	cfg := LoadDefaultConfig().
+		WithCredentialsProvider(credentials.NewStaticCredentialsProvider("ak", "sk")).
+		WithRegion("cn-hangzhou").
+		WithEndpoint(server.URL).
+		WithEnableChunkedSigning(true)
+	client := NewClient(cfg)
+
+	// the body of unknown length
+	_, err := client.PutObject(context.TODO(), &PutObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key"), Body: stream()})
+	assert.Nil(t, err)
+	b, h := reset()
+	assert.Len(t, b, 1)
+	assert.Equal(t, data, b[0])
+	assert.Equal(t, signer.StreamingPayloadTrailer, h[0].Get("x-oss-content-sha256"))
+	assert.Equal(t, "x-oss-hash-crc64ecma", h[0].Get("x-oss-trailer"))
+	assert.Equal(t, []string{"chunked"}, h[0].Values("Transfer-Encoding"))
+
+	_, err = client.UploadPart(context.TODO(), &UploadPartRequest{Bucket: Ptr("bucket"), Key: Ptr("key"),
+		UploadId: Ptr("upload-id"), PartNumber: int32(1), Body: stream()})
+	assert.Nil(t, err)
+	b, h = reset()
+	assert.Equal(t, data, b[0])
+	assert.Equal(t, signer.StreamingPayloadTrailer, h[0].Get("x-oss-content-sha256"))
+
+	// the body of known length is signed as before
+	_, err = client.PutObject(context.TODO(), &PutObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key"), Body: bytes.NewReader(data)})
+	assert.Nil(t, err)
+	_, err = client.PutObject(context.TODO(), &PutObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key"),
+		ContentLength: Ptr(int64(len(data))), Body: stream()})
+	assert.Nil(t, err)
+	_, err = client.PutObject(context.TODO(), &PutObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")})
+	assert.Nil(t, err)
+	b, h = reset()
+	assert.Len(t, b, 3)
+	assert.Equal(t, data, b[0])
+	assert.Equal(t, data, b[1])
+	for _, header := range h {
+		assert.Equal(t, "UNSIGNED-PAYLOAD", header.Get("x-oss-content-sha256"))
+		assert.Equal(t, "", header.Get("x-oss-trailer"))
+	}
+
+	// only takes effect for the V4 signature
+	_, err = client.PutObject(context.TODO(), &PutObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key"), Body: stream()},
+		func(o *Options) { o.Signer = &signer.SignerV1{} })
+	assert.Nil(t, err)
+	b, h = reset()
+	assert.Equal(t, data, b[0])
+	assert.Equal(t, "", h[0].Get("x-oss-content-sha256"))
+
+	// disabled by default
+	cfgDisabled := cfg.Copy()
+	client = NewClient(cfgDisabled.WithEnableChunkedSigning(false))
+	_, err = client.PutObject(context.TODO(), &PutObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key"), Body: stream()})
+	assert.Nil(t, err)
+	b, h = reset()
+	assert.Equal(t, data, b[0])
+	assert.Equal(t, "UNSIGNED-PAYLOAD", h[0].Get("x-oss-content-sha256"))
+}
+
+func TestChunkedSigning_Uploader(t *testing.T) {
+	var mu sync.Mutex
+	parts := map[string][]byte{}
+	var streamed int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		w.Header().Set("x-oss-request-id", "id-1234")
+		switch {
+		case r.Method == "POST" && query.Has("uploads"):
+			io.WriteString(w, `<InitiateMultipartUploadResult><Bucket>bucket</Bucket><Key>key</Key><UploadId>upload-id</UploadId></InitiateMultipartUploadResult>`)
+		case r.Method == "PUT" && query.Get("partNumber") != "":
+			if r.Header.Get("x-oss-content-sha256") != signer.StreamingPayloadTrailer {
+				w.WriteHeader(400)
+				return
+			}
+			data, err := decodeChunkedPayload(r, "sk")
+			if err != nil {
+				w.WriteHeader(400)
+				io.WriteString(w, err.Error())
+				return
+			}
+			mu.Lock()
+			parts[query.Get("partNumber")] = data
+			streamed++
+			mu.Unlock()
+			crc := NewCRC64(0)
+			crc.Write(data)
+			w.Header().Set("ETag", "\"etag\"")
+			w.Header().Set(HeaderOssCRC64, strconv.FormatUint(crc.Sum64(), 10))
+		case r.Method == "POST" && query.Get("uploadId") == "upload-id":
+			io.Copy(io.Discard, r.Body)
+			crc := NewCRC64(0)
+			mu.Lock()
+			for i := 1; i <= len(parts); i++ {
+				crc.Write(parts[strconv.Itoa(i)])
+			}
+			mu.Unlock()
+			w.Header().Set(HeaderOssCRC64, strconv.FormatUint(crc.Sum64(), 10))
+			io.WriteString(w, `<CompleteMultipartUploadResult><Bucket>bucket</Bucket><Key>key</Key><ETag>"etag"</ETag></CompleteMultipartUploadResult>`)
+		default:
+			w.WriteHeader(400)
+		}
+	}))
+	defer server.Close()
+
+	cfg := LoadDefaultConfig().
+		WithCredentialsProvider(credentials.NewStaticCredentialsProvider("ak", "sk")).
+		WithRegion("cn-hangzhou").
+		WithEndpoint(server.URL).
+		WithEnableChunkedSigning(true)
+	u := NewUploader(NewClient(cfg), func(uo *UploaderOptions) {
+		uo.PartSize = 100 * 1024
+	})
+
+	for _, size := range []int{250 * 1024, 200 * 1024} {
+		mu.Lock()
+		parts = map[string][]byte{}
+		streamed = 0
+		mu.Unlock()
+
+		data := make([]byte, size)
+		rand.Read(data)
+		pr, pw := io.Pipe()
+		go func() {
+			pw.Write(data)
+			pw.Close()
+		}()
+		var progress int64
+		result, err := u.UploadFrom(context.TODO(), &PutObjectRequest{
+			Bucket: Ptr("bucket"),
+			Key:    Ptr("key"),
+			ProgressFn: func(increment, transferred, total int64) {
+				progress = transferred
+				assert.Equal(t, int64(-1), total)
+			},
+		}, pr)
+		assert.Nil(t, err)
+		assert.Equal(t, "upload-id", ToString(result.UploadId))
+		assert.Equal(t, int64(size), progress)
+
+		// the parts are streamed, there is no empty part at the end
+		count := (size + 100*1024 - 1) / (100 * 1024)
+		mu.Lock()
+		assert.Equal(t, count, streamed)
+		var uploaded []byte
+		for i := 1; i <= count; i++ {
+			uploaded = append(uploaded, parts[strconv.Itoa(i)]...)
+		}
+		mu.Unlock()
+		assert.Equal(t, data, uploaded)
+	}
+}
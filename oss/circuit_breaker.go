@@ -0,0 +1,272 @@
+package oss
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultCircuitBreakerFailureThreshold = 5
+	DefaultCircuitBreakerOpenTimeout      = 30 * time.Second
+	DefaultCircuitBreakerHalfOpenProbes   = 1
+)
+
+type CircuitState int
+
+const (
+	CircuitClosed CircuitState = iota
+	CircuitOpen
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "closed"
+}
+
+type CircuitBreakerOptions struct {
+	// The number of consecutive connection errors or 5xx responses that opens the circuit.
+	FailureThreshold int
+
+	// How long the circuit stays open before the probe requests are let through.
+	OpenTimeout time.Duration
+
+	// The number of concurrent probe requests in the half-open state.
+	// The circuit is closed when all of them succeed.
+	HalfOpenProbes int
+
+	// Called when the state of the circuit of an endpoint host changes.
+	OnStateChange func(host string, from, to CircuitState)
+}
+
+// CircuitBreaker tracks the health of every endpoint host.
+// When a host fails FailureThreshold times in a row, the requests to it fail fast
+// with a CircuitOpenError for OpenTimeout, then some probe requests decide whether to close the circuit again.
+// It can be shared by several clients.
+type CircuitBreaker struct {
+	mu       sync.Mutex
+	options  CircuitBreakerOptions
+	circuits map[string]*circuit
+	now      func() time.Time
+}
+
+type circuit struct {
+	state      CircuitState
+	failures   int
+	openedAt   time.Time
+	probes     int
+	successes  int
+	generation uint64
+}
+
+func NewCircuitBreaker(optFns ...func(*CircuitBreakerOptions)) *CircuitBreaker {
+	options := CircuitBreakerOptions{
+		FailureThreshold: DefaultCircuitBreakerFailureThreshold,
+		OpenTimeout:      DefaultCircuitBreakerOpenTimeout,
+		HalfOpenProbes:   DefaultCircuitBreakerHalfOpenProbes,
+	}
+
+	for _, fn := range optFns {
+		fn(&options)
+	}
+
+	if options.FailureThreshold <= 0 {
+		options.FailureThreshold = DefaultCircuitBreakerFailureThreshold
+	}
+
+	if options.OpenTimeout <= 0 {
+		options.OpenTimeout = DefaultCircuitBreakerOpenTimeout
+	}
+
+	if options.HalfOpenProbes <= 0 {
+		options.HalfOpenProbes = DefaultCircuitBreakerHalfOpenProbes
+	}
+
+	return &CircuitBreaker{
+		options:  options,
+		circuits: map[string]*circuit{},
+		now:      time.Now,
+	}
+}
+
+// State returns the state of the circuit of the endpoint host.
+func (cb *CircuitBreaker) State(host string) CircuitState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if c, ok := cb.circuits[host]; ok {
+		if c.state == CircuitOpen && cb.now().Sub(c.openedAt) >= cb.options.OpenTimeout {
+			return CircuitHalfOpen
+		}
+		return c.state
+	}
+	return CircuitClosed
+}
+
+// Reset closes the circuit of the endpoint host.
+func (cb *CircuitBreaker) Reset(host string) {
+	cb.mu.Lock()
+	c, ok := cb.circuits[host]
+	if !ok {
+		cb.mu.Unlock()
+		return
+	}
+	from := c.state
+	delete(cb.circuits, host)
+	cb.mu.Unlock()
+	if from != CircuitClosed {
+		cb.notify(host, from, CircuitClosed)
+	}
+}
+
+type circuitOutcome int
+
+const (
+	circuitSuccess circuitOutcome = iota
+	circuitFailure
+	// neither success nor failure, e.g. the request is canceled
+	circuitIgnored
+)
+
+// allow returns an error if the request to the host must fail fast,
+// otherwise the done func must be called with the outcome of the request.
+func (cb *CircuitBreaker) allow(host string) (func(circuitOutcome), error) {
+	cb.mu.Lock()
+	c, ok := cb.circuits[host]
+	if !ok {
+		c = &circuit{}
+		cb.circuits[host] = c
+	}
+
+	var changed bool
+	from := c.state
+	if c.state == CircuitOpen {
+		elapsed := cb.now().Sub(c.openedAt)
+		if elapsed < cb.options.OpenTimeout {
+			cb.mu.Unlock()
+			return nil, &CircuitOpenError{Host: host, RetryAfter: cb.options.OpenTimeout - elapsed}
+		}
+		c.state = CircuitHalfOpen
+		c.probes = 0
+		c.successes = 0
+		c.generation++
+		changed = true
+	}
+
+	if c.state == CircuitHalfOpen {
+		if c.probes >= cb.options.HalfOpenProbes {
+			cb.mu.Unlock()
+			if changed {
+				cb.notify(host, from, CircuitHalfOpen)
+			}
+			return nil, &CircuitOpenError{Host: host}
+		}
+		c.probes++
+	}
+	generation := c.generation
+	cb.mu.Unlock()
+
+	if changed {
+		cb.notify(host, from, CircuitHalfOpen)
+	}
+
+	var once sync.Once
+	return func(outcome circuitOutcome) {
+		once.Do(func() { cb.done(host, generation, outcome) })
+	}, nil
+}
+
+func (cb *CircuitBreaker) done(host string, generation uint64, outcome circuitOutcome) {
+	cb.mu.Lock()
+	c, ok := cb.circuits[host]
+	if !ok || c.generation != generation {
+		// the circuit has changed since the request was allowed
+		cb.mu.Unlock()
+		return
+	}
+
+	from := c.state
+	switch c.state {
+	case CircuitClosed:
+		switch outcome {
+		case circuitSuccess:
+			c.failures = 0
+		case circuitFailure:
+			c.failures++
+			if c.failures >= cb.options.FailureThreshold {
+				cb.open(c)
+			}
+		}
+	case CircuitHalfOpen:
+		c.probes--
+		switch outcome {
+		case circuitSuccess:
+			c.successes++
+			if c.successes >= cb.options.HalfOpenProbes {
+				c.state = CircuitClosed
+				c.failures = 0
+				c.generation++
+			}
+		case circuitFailure:
+			cb.open(c)
+		}
+	}
+	to := c.state
+	cb.mu.Unlock()
+
+	if from != to {
+		cb.notify(host, from, to)
+	}
+}
+
+func (cb *CircuitBreaker) open(c *circuit) {
+	c.state = CircuitOpen
+	c.openedAt = cb.now()
+	c.failures = 0
+	c.generation++
+}
+
+func (cb *CircuitBreaker) notify(host string, from, to CircuitState) {
+	if cb.options.OnStateChange != nil {
+		cb.options.OnStateChange(host, from, to)
+	}
+}
+
+// circuitOutcomeOf classifies the result of a http attempt,
+// only the connection errors and the 5xx responses are failures of the endpoint.
+func circuitOutcomeOf(ctx context.Context, err error) circuitOutcome {
+	if err == nil {
+		return circuitSuccess
+	}
+	if ctx.Err() != nil {
+		return circuitIgnored
+	}
+	// a dial timeout matches context.DeadlineExceeded too, the endpoint is unreachable
+	if isConnectionError(ctx, err) {
+		return circuitFailure
+	}
+	if errors.Is(err, context.Canceled) {
+		return circuitIgnored
+	}
+	var serr *ServiceError
+	if errors.As(err, &serr) {
+		if serr.StatusCode >= 500 {
+			return circuitFailure
+		}
+		return circuitSuccess
+	}
+	var uerr *url.Error
+	var nerr net.Error
+	if errors.As(err, &uerr) || errors.As(err, &nerr) {
+		return circuitFailure
+	}
+	return circuitIgnored
+}
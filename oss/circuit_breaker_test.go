@@ -0,0 +1,240 @@
+package oss
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/credentials"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreaker_State(t *testing.T) {
+	var changes []string
+	cb := NewCircuitBreaker(func(o *CircuitBreakerOptions) {
+		o.FailureThreshold = 3
+		o.OpenTimeout = time.Second
+		o.HalfOpenProbes = 2
+		o.OnStateChange = func(host string, from, to CircuitState) {
+			changes = append(changes, host+":"+from.String()+"->"+to.String())
+		}
+	})
+	now := time.Now()
+	cb.now = func() time.Time { return now }
+	host := "bucket.oss-cn-hangzhou.aliyuncs.com"
+
+	// a success resets the consecutive failures
+	for _, outcome := range []circuitOutcome{circuitFailure, circuitFailure, circuitSuccess, circuitFailure, circuitFailure, circuitIgnored} {
+		done, err := cb.allow(host)
+		assert.Nil(t, err)
+		done(outcome)
+	}
+	assert.Equal(t, CircuitClosed, cb.State(host))
+
+	done, err := cb.allow(host)
+	assert.Nil(t, err)
+	done(circuitFailure)
+	assert.Equal(t, CircuitOpen, cb.State(host))
+
+	// fails fast
+	_, err = cb.allow(host)
+	var cerr *CircuitOpenError
+	assert.True(t, errors.As(err, &cerr))
+	assert.Equal(t, host, cerr.Host)
+	assert.Equal(t, time.Second, cerr.RetryAfter)
+
+	// other hosts are not affected
+	done, err = cb.allow("other")
+	assert.Nil(t, err)
+	done(circuitSuccess)
+
+	// half-open, at most 2 probes
+	now = now.Add(time.Second)
+	assert.Equal(t, CircuitHalfOpen, cb.State(host))
+	probe1, err := cb.allow(host)
+	assert.Nil(t, err)
+	probe2, err := cb.allow(host)
+	assert.Nil(t, err)
+	_, err = cb.allow(host)
+	assert.True(t, errors.As(err, &cerr))
+	assert.Equal(t, time.Duration(0), cerr.RetryAfter)
+
+	// a failed probe opens the circuit again
+	probe1(circuitFailure)
+	assert.Equal(t, CircuitOpen, cb.State(host))
+	probe2(circuitSuccess)
+	assert.Equal(t, CircuitOpen, cb.State(host))
+
+	// all probes succeed
+	now = now.Add(time.Second)
+	probe1, err = cb.allow(host)
+	assert.Nil(t, err)
+	probe2, err = cb.allow(host)
+	assert.Nil(t, err)
+	probe1(circuitSuccess)
+	assert.Equal(t, CircuitHalfOpen, cb.State(host))
+	probe2(circuitSuccess)
+	probe2(circuitFailure)
+	assert.Equal(t, CircuitClosed, cb.State(host))
+
+	cb.Reset(host)
+	assert.Equal(t, []string{
+		host + ":closed->open",
+		host + ":open->half-open",
+		host + ":half-open->open",
+		host + ":open->half-open",
+		host + ":half-open->closed",
+	}, changes)
+}
+
+func TestCircuitOutcomeOf(t *testing.T) {
+	assert.Equal(t, circuitSuccess, circuitOutcomeOf(context.TODO(), nil))
+	assert.Equal(t, circuitFailure, circuitOutcomeOf(context.TODO(), &ServiceError{StatusCode: 503}))
+	assert.Equal(t, circuitSuccess, circuitOutcomeOf(context.TODO(), &ServiceError{StatusCode: 404}))
+	assert.Equal(t, circuitFailure, circuitOutcomeOf(context.TODO(), &url.Error{Op: "Get", URL: "http://host", Err: errors.New("connection refused")}))
+	assert.Equal(t, circuitIgnored, circuitOutcomeOf(context.TODO(), &url.Error{Op: "Get", URL: "http://host", Err: context.Canceled}))
+	assert.Equal(t, circuitIgnored, circuitOutcomeOf(context.TODO(), errors.New("sign error")))
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	assert.Equal(t, circuitIgnored, circuitOutcomeOf(ctx, &ServiceError{StatusCode: 503}))
+
+	// the dial timeout
+	_, err := (&net.Dialer{Timeout: time.Nanosecond}).Dial("tcp", "127.0.0.1:1")
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Equal(t, circuitFailure, circuitOutcomeOf(context.TODO(), &url.Error{Op: "Get", URL: "http://host", Err: err}))
+}
+
+func TestCircuitBreaker_DialTimeout(t *testing.T) {
+	var dials int32
+	// the dial blocks until the connect timeout, like a blackholed endpoint
+	dialer := &net.Dialer{
+		Timeout: 50 * time.Millisecond,
+		Control: func(network, address string, c syscall.RawConn) error {
+			atomic.AddInt32(&dials, 1)
+			time.Sleep(100 * time.Millisecond)
+			return nil
+		},
+	}
+	cb := NewCircuitBreaker(func(o *CircuitBreakerOptions) {
+		o.FailureThreshold = 2
+		o.OpenTimeout = time.Hour
+	})
+	cfg := LoadDefaultConfig().
+		WithCredentialsProvider(credentials.NewAnonymousCredentialsProvider()).
+		WithRegion("cn-hangzhou").
+		WithEndpoint("http://127.0.0.1:1").
+		WithRetryMaxAttempts(3).
+		WithHttpClient(&http.Client{Transport: &http.Transport{DialContext: dialer.DialContext}}).
+		WithCircuitBreaker(cb)
+
+	client := NewClient(cfg)
+	_, err := client.HeadObject(context.TODO(), &HeadObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")})
+	var cerr *CircuitOpenError
+	assert.True(t, errors.As(err, &cerr))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&dials))
+	assert.Equal(t, CircuitOpen, cb.State("127.0.0.1:1"))
+}
+
+func TestCircuitBreaker_Client(t *testing.T) {
+	var count int
+	server := testSetupMockServer(t, 503, map[string]string{"x-oss-request-id": "id-1234"},
+		[]byte(`<Error><Code>ServiceUnavailable</Code><Message>error</Message><RequestId>id-1234</RequestId></Error>`),
+		func(t *testing.T, r *http.Request) {
+			count++
+		})
+	defer server.Close()
+
+	cb := NewCircuitBreaker(func(o *CircuitBreakerOptions) {
+		o.FailureThreshold = 2
+		o.OpenTimeout = time.Hour
+	})
+	cfg := LoadDefaultConfig().
+		WithCredentialsProvider(credentials.NewAnonymousCredentialsProvider()).
+		WithRegion("cn-hangzhou").
+		WithEndpoint(server.URL).
+		WithRetryMaxAttempts(3).
+		WithCircuitBreaker(cb)
+
+	client := NewClient(cfg)
+	_, err := client.HeadObject(context.TODO(), &HeadObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")})
+	var cerr *CircuitOpenError
+	assert.True(t, errors.As(err, &cerr))
+	var operr *OperationError
+	assert.True(t, errors.As(err, &operr))
+	assert.Equal(t, 2, count)
+
+	u, _ := url.Parse(server.URL)
+	assert.Equal(t, CircuitOpen, cb.State(u.Host))
+
+	// fails fast
+	_, err = client.HeadObject(context.TODO(), &HeadObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")})
+	assert.True(t, errors.As(err, &cerr))
+	assert.Equal(t, 2, count)
+
+	// the options of the operation
+	count = 0
+	_, err = client.HeadObject(context.TODO(), &HeadObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")}, func(o *Options) {
+		o.CircuitBreaker = NewCircuitBreaker()
+	})
+	assert.False(t, errors.As(err, &cerr))
+	assert.Equal(t, 3, count)
+}
+
+func TestCircuitBreaker_Presign(t *testing.T) {
+	var count int
+	server := testSetupMockServer(t, 503, map[string]string{"x-oss-request-id": "id-1234"},
+		[]byte(`<Error><Code>ServiceUnavailable</Code><Message>error</Message><RequestId>id-1234</RequestId></Error>`),
+		func(t *testing.T, r *http.Request) {
+			count++
+		})
+	defer server.Close()
+
+	cb := NewCircuitBreaker(func(o *CircuitBreakerOptions) {
+		o.FailureThreshold = 1
+		o.OpenTimeout = time.Second
+	})
+	now := time.Now()
+	cb.now = func() time.Time { return now }
+	cfg := LoadDefaultConfig().
+		WithCredentialsProvider(credentials.NewStaticCredentialsProvider("ak", "sk")).
+		WithRegion("cn-hangzhou").
+		WithEndpoint(server.URL).
+		WithRetryMaxAttempts(1).
+		WithCircuitBreaker(cb)
+
+	client := NewClient(cfg)
+	u, _ := url.Parse(server.URL)
+	_, err := client.HeadObject(context.TODO(), &HeadObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")})
+	assert.NotNil(t, err)
+	assert.Equal(t, CircuitOpen, cb.State(u.Host))
+
+	// presigning is not blocked by the open circuit
+	result, err := client.Presign(context.TODO(), &GetObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")})
+	assert.Nil(t, err)
+	assert.NotEmpty(t, result.URL)
+	assert.Equal(t, CircuitOpen, cb.State(u.Host))
+
+	// presigning is not a probe, the circuit stays half-open
+	now = now.Add(time.Second)
+	assert.Equal(t, CircuitHalfOpen, cb.State(u.Host))
+	for i := 0; i < 3; i++ {
+		_, err = client.Presign(context.TODO(), &GetObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")})
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, CircuitHalfOpen, cb.State(u.Host))
+	assert.Equal(t, 1, count)
+
+	// the probe is still sent
+	_, err = client.HeadObject(context.TODO(), &HeadObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")})
+	var cerr *CircuitOpenError
+	assert.False(t, errors.As(err, &cerr))
+	assert.Equal(t, 2, count)
+	assert.Equal(t, CircuitOpen, cb.State(u.Host))
+}
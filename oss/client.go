@@ -7,6 +7,7 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"hash"
 	"io"
@@ -54,12 +55,28 @@ type Options struct {
 	AdditionalHeaders []string
 
 	EndpointProvider EndpointProvider
+
+	Middlewares []Middleware
+
+	Tracer Tracer
+
+	MetricsCollector MetricsCollector
+
+	RequestRateLimiter *RequestRateLimiter
+
+	CircuitBreaker *CircuitBreaker
+
+	EndpointPool *EndpointPool
+
+	HedgingPolicy *HedgingPolicy
 }
 
 func (c Options) Copy() Options {
 	to := c
 	to.ResponseHandlers = make([]func(*http.Response) error, len(c.ResponseHandlers))
 	copy(to.ResponseHandlers, c.ResponseHandlers)
+	to.Middlewares = make([]Middleware, len(c.Middlewares))
+	copy(to.Middlewares, c.Middlewares)
 	return to
 }
 
@@ -69,6 +86,20 @@ func OpReadWriteTimeout(value time.Duration) func(*Options) {
 	}
 }
 
+// OpCredentialsProvider uses the credentials provider for the operation, e.g. the one of a tenant.
+func OpCredentialsProvider(value credentials.CredentialsProvider) func(*Options) {
+	return func(o *Options) {
+		o.CredentialsProvider = value
+	}
+}
+
+// OpSigner uses the signer for the operation.
+func OpSigner(value signer.Signer) func(*Options) {
+	return func(o *Options) {
+		o.Signer = value
+	}
+}
+
 type innerOptions struct {
 	BwTokenBuckets BwTokenBuckets
 
@@ -97,11 +128,21 @@ func NewClient(cfg *Config, optFns ...func(*Options)) *Client {
 		HttpClient:          cfg.HttpClient,
 		FeatureFlags:        FeatureFlagsDefault,
 		AdditionalHeaders:   cfg.AdditionalHeaders,
+		Middlewares:         append([]Middleware(nil), cfg.Middlewares...),
+		Tracer:              cfg.Tracer,
+		MetricsCollector:    cfg.MetricsCollector,
+		RequestRateLimiter:  cfg.RequestRateLimiter,
+		CircuitBreaker:      cfg.CircuitBreaker,
+		EndpointPool:        cfg.EndpointPool,
+		HedgingPolicy:       cfg.HedgingPolicy,
 	}
 	inner := innerOptions{
 		Log:       NewLogger(ToInt(cfg.LogLevel), cfg.LogPrinter),
 		UserAgent: buildUserAgent(cfg),
 	}
+	if cfg.Logger != nil {
+		inner.Log = cfg.Logger
+	}
 
 	resolveEndpoint(cfg, &options)
 	resolveRetryer(cfg, &options)
@@ -115,6 +156,10 @@ func NewClient(cfg *Config, optFns ...func(*Options)) *Client {
 		fn(&options)
 	}
 
+	if options.CredentialsProvider == nil {
+		options.CredentialsProvider = credentials.NewDefaultCredentialsProviderChain()
+	}
+
 	client := &Client{
 		options: options,
 		inner:   inner,
@@ -124,6 +169,11 @@ func NewClient(cfg *Config, optFns ...func(*Options)) *Client {
 }
 
 func resolveEndpoint(cfg *Config, o *Options) {
+	if cfg.EndpointPool != nil {
+		o.Endpoint = cfg.EndpointPool.primary()
+		return
+	}
+
 	disableSSL := ToBool(cfg.DisableSSL)
 	endpoint := ToString(cfg.Endpoint)
 	region := ToString(cfg.Region)
@@ -195,21 +245,21 @@ func resolveHTTPClient(cfg *Config, o *Options, inner *innerOptions) {
 	if cfg.EnabledRedirect != nil {
 		tcfg.EnabledRedirect = cfg.EnabledRedirect
 	}
-	if cfg.UploadBandwidthlimit != nil {
-		value := *cfg.UploadBandwidthlimit * 1024
-		tb := newBwTokenBucket(value)
+	// the token buckets are always installed, so that the limits can be changed at runtime
+	if inner != nil {
+		tx := newBwTokenBucket(ToInt64(cfg.UploadBandwidthlimit) * 1024)
+		tx.setMetricsCollector(cfg.MetricsCollector, "upload")
 		tcfg.PostWrite = append(tcfg.PostWrite, func(n int, _ error) {
-			tb.LimitBandwidth(n)
+			tx.LimitBandwidth(n)
 		})
-		inner.BwTokenBuckets[BwTokenBucketSlotTx] = tb
-	}
-	if cfg.DownloadBandwidthlimit != nil {
-		value := *cfg.DownloadBandwidthlimit * 1024
-		tb := newBwTokenBucket(value)
+		inner.BwTokenBuckets[BwTokenBucketSlotTx] = tx
+
+		rx := newBwTokenBucket(ToInt64(cfg.DownloadBandwidthlimit) * 1024)
+		rx.setMetricsCollector(cfg.MetricsCollector, "download")
 		tcfg.PostRead = append(tcfg.PostRead, func(n int, _ error) {
-			tb.LimitBandwidth(n)
+			rx.LimitBandwidth(n)
 		})
-		inner.BwTokenBuckets[BwTokenBucketSlotRx] = tb
+		inner.BwTokenBuckets[BwTokenBucketSlotRx] = rx
 	}
 	if cfg.BindAddress != nil {
 		tcfg.BindAddr = cfg.BindAddress
@@ -254,6 +304,10 @@ func resolveUrlStyle(cfg *Config, o *Options) {
 }
 
 func resolveFeatureFlags(cfg *Config, o *Options) {
+	if cfg.FeatureFlags != nil {
+		o.FeatureFlags = *cfg.FeatureFlags
+	}
+
 	if ToBool(cfg.DisableDownloadCRC64Check) {
 		o.FeatureFlags = o.FeatureFlags & ^FeatureEnableCRC64CheckDownload
 	}
@@ -261,6 +315,10 @@ func resolveFeatureFlags(cfg *Config, o *Options) {
 	if ToBool(cfg.DisableUploadCRC64Check) {
 		o.FeatureFlags = o.FeatureFlags & ^FeatureEnableCRC64CheckUpload
 	}
+
+	if ToBool(cfg.EnableChunkedSigning) {
+		o.FeatureFlags = o.FeatureFlags | FeatureEnableChunkedSigning
+	}
 }
 
 func resolveCloudBox(cfg *Config, o *Options) {
@@ -305,14 +363,25 @@ func buildUserAgent(cfg *Config) string {
 }
 
 func (c *Client) invokeOperation(ctx context.Context, input *OperationInput, optFns []func(*Options)) (output *OperationOutput, err error) {
+	ctx = ensureCorrelationId(ctx)
+	diag := &Diagnostics{Operation: input.OpName}
+	ctx = withDiagnostics(ctx, diag)
+	defer func(start time.Time) {
+		diag.finish(start)
+		if output != nil {
+			output.OpMetadata.Set(OpMetaKeyDiagnostics, diag)
+		}
+		if e, ok := err.(*OperationError); ok {
+			e.diagnostics = diag
+		}
+	}(time.Now())
 	if c.getLogLevel() >= LogInfo {
-		c.inner.Log.Infof("InvokeOperation Start: input[%p], OpName:%s, Bucket:%s, Key:%s",
-			input, input.OpName,
-			ToString(input.Bucket), ToString(input.Key))
+		start := time.Now()
+		logFields(ctx, c.inner.Log, LogInfo, "InvokeOperation Start", operationLogFields(input)...)
 		defer func() {
-			c.inner.Log.Infof("InvokeOperation End: input[%p], OpName:%s, output:'%v', err:'%v'",
-				input, input.OpName,
-				c.dumpOperationOutput(output), err)
+			fields := append(operationLogFields(input), c.dumpOperationOutput(output, err)...)
+			fields = append(fields, LogField{Key: LogFieldLatency, Value: time.Since(start)})
+			logFields(ctx, c.inner.Log, LogInfo, "InvokeOperation End", fields...)
 		}()
 	}
 
@@ -325,11 +394,29 @@ func (c *Client) invokeOperation(ctx context.Context, input *OperationInput, opt
 
 	applyOperationOpt(&options, &opOpt)
 
-	applyOperationMetadata(input, &options)
-
 	ctx = applyOperationContext(ctx, &options)
 
-	output, err = c.sendRequest(ctx, input, &options)
+	ctx, span := startSpan(ctx, options.Tracer, input.OpName, operationSpanAttributes(input, &options)...)
+	defer func() {
+		setResponseSpanAttributes(span, output, err)
+		endSpan(span, err)
+	}()
+
+	// presigning puts no request on the wire
+	if options.RequestRateLimiter != nil && !isPresignOptions(&options) {
+		if err = options.RequestRateLimiter.Wait(ctx, ToString(input.Bucket), operationClassOf(input)); err != nil {
+			return output, &OperationError{
+				name: input.OpName,
+				err:  err}
+		}
+	}
+
+	handler := decorateInitializeHandler(func(ctx context.Context, input *OperationInput) (*OperationOutput, error) {
+		applyOperationMetadata(input, &options)
+		return c.sendRequest(ctx, input, &options)
+	}, options.Middlewares)
+
+	output, err = handler(ctx, input)
 
 	if err != nil {
 		return output, &OperationError{
@@ -342,11 +429,10 @@ func (c *Client) invokeOperation(ctx context.Context, input *OperationInput, opt
 
 func (c *Client) sendRequest(ctx context.Context, input *OperationInput, opts *Options) (output *OperationOutput, err error) {
 	var request *http.Request
-	var response *http.Response
 	if c.getLogLevel() >= LogInfo {
-		c.inner.Log.Infof("sendRequest Start: input[%p]", input)
+		logFields(ctx, c.inner.Log, LogInfo, fmt.Sprintf("sendRequest Start: input[%p]", input))
 		defer func() {
-			c.inner.Log.Infof("sendRequest End: input[%p], http.Request[%p], http.Response[%p]", input, request, response)
+			logFields(ctx, c.inner.Log, LogInfo, fmt.Sprintf("sendRequest End: input[%p], http.Request[%p], output[%p]", input, request, output))
 		}()
 	}
 
@@ -362,6 +448,15 @@ func (c *Client) sendRequest(ctx context.Context, input *OperationInput, opts *O
 			writers = append(writers, ww)
 		}
 	}
+	sent := &byteCounter{}
+	writers = append(writers, sent)
+	defer func() {
+		diagnosticsFromContext(ctx).BytesSent = sent.Count()
+		spanFromContext(ctx).SetAttributes(Attribute{Key: AttributeBytesSent, Value: sent.Count()})
+		if opts.MetricsCollector != nil {
+			opts.MetricsCollector.AddCounter(MetricBytesSentTotal, float64(sent.Count()), operationMetricLabels(input))
+		}
+	}()
 	// host & path
 	var strUrl string
 	if opts.EndpointProvider != nil {
@@ -417,6 +512,14 @@ func (c *Client) sendRequest(ctx context.Context, input *OperationInput, opts *O
 	}
 	request.Body = TeeReadNopCloser(body, writers...)
 
+	handler := decorateSerializeHandler(func(ctx context.Context, input *OperationInput, request *http.Request) (*OperationOutput, error) {
+		return c.sendSerializedRequest(ctx, input, request, opts)
+	}, opts.Middlewares)
+
+	return handler(ctx, input, request)
+}
+
+func (c *Client) sendSerializedRequest(ctx context.Context, input *OperationInput, request *http.Request, opts *Options) (output *OperationOutput, err error) {
 	//signing context
 	subResource, _ := input.OpMetadata.Get(signer.SubResource).([]string)
 	clockOffset := c.inner.ClockOffset
@@ -432,6 +535,11 @@ func (c *Client) sendRequest(ctx context.Context, input *OperationInput, opts *O
 		AdditionalHeaders: opts.AdditionalHeaders,
 	}
 
+	if useChunkedSigning(input, request, opts) {
+		signingCtx.StreamingPayload = true
+		request.Header.Set(HeaderOssTrailer, strings.ToLower(HeaderOssCRC64))
+	}
+
 	if date := request.Header.Get(HeaderOssDate); date != "" {
 		signingCtx.Time, _ = http.ParseTime(date)
 	} else if signTime, ok := input.OpMetadata.Get(signer.SignTime).(time.Time); ok {
@@ -439,20 +547,47 @@ func (c *Client) sendRequest(ctx context.Context, input *OperationInput, opts *O
 	}
 
 	// send http request
-	response, err = c.sendHttpRequest(ctx, signingCtx, opts)
+	response, err := c.sendHttpRequest(ctx, input, signingCtx, opts)
 
 	if err != nil {
 		return output, err
 	}
 
+	diag := diagnosticsFromContext(ctx)
+	diag.ClockOffset = signingCtx.ClockOffset
+	if response.Body != nil {
+		response.Body = &diagnosticsReadCloser{ReadCloser: response.Body, d: diag}
+	}
+
+	if opts.MetricsCollector != nil && response.Body != nil {
+		response.Body = &metricsReadCloser{
+			ReadCloser: response.Body,
+			mc:         opts.MetricsCollector,
+			labels:     operationMetricLabels(input),
+		}
+	}
+
 	// covert http response into output context
-	output = &OperationOutput{
-		Input:       input,
-		Status:      response.Status,
-		StatusCode:  response.StatusCode,
-		Body:        response.Body,
-		Headers:     response.Header,
-		httpRequest: request,
+	// the body is owned by the output once the innermost handler converts the response
+	delivered := false
+	handler := decorateDeserializeHandler(func(_ context.Context, input *OperationInput, response *http.Response) (*OperationOutput, error) {
+		delivered = true
+		return &OperationOutput{
+			Input:       input,
+			Status:      response.Status,
+			StatusCode:  response.StatusCode,
+			Body:        response.Body,
+			Headers:     response.Header,
+			httpRequest: request,
+		}, nil
+	}, opts.Middlewares)
+
+	output, err = handler(ctx, input, response)
+	if response.Body != nil && (err != nil || !delivered || output == nil || output.Body == nil) {
+		response.Body.Close()
+	}
+	if err != nil {
+		return output, err
 	}
 
 	// save other info by Metadata filed, ex. retry detail info
@@ -468,19 +603,50 @@ func (c *Client) sendRequest(ctx context.Context, input *OperationInput, opts *O
 	return output, err
 }
 
-func (c *Client) sendHttpRequest(ctx context.Context, signingCtx *signer.SigningContext, opts *Options) (response *http.Response, err error) {
+func (c *Client) sendHttpRequest(ctx context.Context, input *OperationInput, signingCtx *signer.SigningContext, opts *Options) (response *http.Response, err error) {
 	request := signingCtx.Request
 	retryer := opts.Retryer
 	maxAttempts := c.retryMaxAttempts(opts)
-	body, _ := request.Body.(*teeReadNopCloser)
+	body, ok := request.Body.(*teeReadNopCloser)
+	if !ok {
+		// the body may be replaced by a serialize middleware
+		var reader io.Reader = http.NoBody
+		if request.Body != nil {
+			reader = request.Body
+		}
+		body = TeeReadNopCloser(reader).(*teeReadNopCloser)
+		request.Body = body
+	}
 	resetTime := signingCtx.Time.IsZero()
 	body.Mark()
+	attempts := 0
+	defer func() {
+		spanFromContext(ctx).SetAttributes(Attribute{Key: AttributeAttempts, Value: attempts})
+	}()
+	var labels MetricLabels
+	if opts.MetricsCollector != nil {
+		labels = operationMetricLabels(input)
+	}
+	diag := diagnosticsFromContext(ctx)
+	// presigning sends nothing, the health of the endpoints is unknown
+	presign := isPresignOptions(opts)
+	var attemptRetryer retry.AttemptRetryer
+	if !presign {
+		attemptRetryer, _ = retryer.(retry.AttemptRetryer)
+	}
+	tracer := opts.Tracer
+	if presign {
+		tracer = nil
+	}
 	for tries := 1; tries <= maxAttempts; tries++ {
+		var attemptDelay time.Duration
 		if tries > 1 {
-			delay, err := retryer.RetryDelay(tries, err)
-			if err != nil {
+			delay, derr := retryer.RetryDelay(tries, err)
+			if derr != nil {
+				err = &RetryStoppedError{Err: derr, LastErr: err}
 				break
 			}
+			attemptDelay = delay
 
 			if err = sleepWithContext(ctx, delay); err != nil {
 				err = &CanceledError{Err: err}
@@ -495,10 +661,85 @@ func (c *Client) sendHttpRequest(ctx context.Context, signingCtx *signer.Signing
 				signingCtx.Time = time.Time{}
 			}
 
-			c.inner.Log.Infof("Attempt retry, request[%p], tries:%v, retry delay:%v", request, tries, delay)
+			logFields(ctx, c.inner.Log, LogInfo, "Attempt retry",
+				append(operationLogFields(input),
+					LogField{Key: LogFieldAttempt, Value: tries},
+					LogField{Key: LogFieldDelay, Value: delay})...)
+			if opts.MetricsCollector != nil {
+				opts.MetricsCollector.AddCounter(MetricRetriesTotal, 1, labels)
+			}
+		}
+
+		attempt := retry.Attempt{OpName: input.OpName, Number: tries}
+		if attemptRetryer != nil {
+			if err = attemptRetryer.BeforeAttempt(ctx, attempt); err != nil {
+				err = &CanceledError{Err: err}
+				break
+			}
 		}
 
-		if response, err = c.sendHttpRequestOnce(ctx, signingCtx, opts); err == nil {
+		var endpoint *url.URL
+		if opts.EndpointPool != nil && opts.EndpointProvider == nil {
+			endpoint = opts.EndpointPool.pick(func(u *url.URL) bool {
+				return opts.CircuitBreaker == nil || opts.CircuitBreaker.State(endpointHost(input, opts, u)) != CircuitOpen
+			})
+			if host := endpointHost(input, opts, endpoint); host != request.URL.Host {
+				if tries > 1 {
+					logFields(ctx, c.inner.Log, LogWarn, "Endpoint failover",
+						append(operationLogFields(input), LogField{Key: LogFieldHost, Value: host})...)
+				}
+				setRequestEndpoint(request, host, endpoint)
+			}
+		}
+
+		var circuitDone func(circuitOutcome)
+		if opts.CircuitBreaker != nil && !presign {
+			if circuitDone, err = opts.CircuitBreaker.allow(request.URL.Host); err != nil {
+				logFields(ctx, c.inner.Log, LogWarn, "Circuit open",
+					append(operationLogFields(input), LogField{Key: LogFieldHost, Value: request.URL.Host})...)
+				break
+			}
+		}
+
+		attempts = tries
+		attemptCtx, span := startSpan(ctx, tracer, "HTTP "+request.Method,
+			Attribute{Key: AttributeAttempt, Value: tries},
+			Attribute{Key: AttributeHttpMethod, Value: request.Method},
+			Attribute{Key: AttributeServerAddr, Value: request.URL.Host},
+		)
+		start := time.Now()
+		diag.startAttempt(tries, attemptDelay, request.URL.Host)
+		response, err = c.sendHttpRequestOnce(attemptCtx, input, signingCtx, opts)
+		diag.endAttempt(response, err)
+		if c.getLogLevel() >= LogDebug {
+			fields := append(operationLogFields(input), LogField{Key: LogFieldAttempt, Value: tries})
+			fields = append(fields, responseLogFields(response, err)...)
+			fields = append(fields, LogField{Key: LogFieldLatency, Value: time.Since(start)})
+			logFields(ctx, c.inner.Log, LogDebug, "Attempt End", fields...)
+		}
+		if opts.MetricsCollector != nil && !presign {
+			var statusCode int
+			if response != nil {
+				statusCode = response.StatusCode
+			}
+			recordHttpAttempt(opts.MetricsCollector, labels, statusCode, err, time.Since(start))
+		}
+		setHttpResponseSpanAttributes(span, response, err)
+		endSpan(span, err)
+		if attemptRetryer != nil {
+			attemptRetryer.AfterAttempt(attempt, err)
+		}
+		if circuitDone != nil {
+			circuitDone(circuitOutcomeOf(ctx, err))
+		}
+		if endpoint != nil && !presign {
+			if isConnectionError(ctx, err) {
+				opts.EndpointPool.markFailure(endpoint)
+			} else if _, ok := err.(*ServiceError); err == nil || ok {
+				opts.EndpointPool.markSuccess(endpoint)
+			}
+		}
+		if err == nil {
 			break
 		}
 
@@ -513,39 +754,56 @@ func (c *Client) sendHttpRequest(ctx context.Context, signingCtx *signer.Signing
 			break
 		}
 
-		if !retryer.IsErrorRetryable(err) {
+		if attemptRetryer != nil {
+			if !attemptRetryer.IsAttemptRetryable(attempt, err) {
+				break
+			}
+		} else if !retryer.IsErrorRetryable(err) {
 			break
 		}
 	}
 	return response, err
 }
 
-func (c *Client) sendHttpRequestOnce(ctx context.Context, signingCtx *signer.SigningContext, opts *Options) (
+func (c *Client) sendHttpRequestOnce(ctx context.Context, input *OperationInput, signingCtx *signer.SigningContext, opts *Options) (
 	response *http.Response, err error,
 ) {
 	if c.getLogLevel() > LogInfo {
-		c.inner.Log.Infof("sendHttpRequestOnce Start, http.Request[%p]", signingCtx.Request)
+		logFields(ctx, c.inner.Log, LogInfo, fmt.Sprintf("sendHttpRequestOnce Start, http.Request[%p]", signingCtx.Request))
 		defer func() {
-			c.inner.Log.Infof("sendHttpRequestOnce End, http.Request[%p], response[%p], err:%v", signingCtx.Request, response, err)
+			logFields(ctx, c.inner.Log, LogInfo, fmt.Sprintf("sendHttpRequestOnce End, http.Request[%p], response[%p]", signingCtx.Request, response),
+				LogField{Key: LogFieldError, Value: err})
 		}()
 	}
 
-	if _, anonymous := opts.CredentialsProvider.(*credentials.AnonymousCredentialsProvider); !anonymous {
-		cred, err := opts.CredentialsProvider.GetCredentials(ctx)
-		if err != nil {
-			return response, err
-		}
+	sign := decorateSignHandler(func(ctx context.Context, signingCtx *signer.SigningContext) error {
+		return c.signRequest(ctx, signingCtx, opts)
+	}, opts.Middlewares)
 
-		signingCtx.Credentials = &cred
-		if err = c.options.Signer.Sign(ctx, signingCtx); err != nil {
-			return response, err
+	if err = sign(ctx, signingCtx); err != nil {
+		return response, err
+	}
+
+	if signingCtx.ChunkSigner != nil {
+		// the body is encoded again with the new signatures on retry
+		body := signingCtx.Request.Body
+		if r, ok := body.(*chunkedPayloadReader); ok {
+			body = r.body
 		}
-		c.inner.Log.Debugf("sendHttpRequestOnce::Sign request[%p], StringToSign:%s", signingCtx.Request, signingCtx.StringToSign)
+		signingCtx.Request.Body = newChunkedPayloadReader(body, signingCtx.ChunkSigner, defaultChunkedPayloadSize)
+		signingCtx.Request.ContentLength = -1
 	}
 
 	c.logHttpPRequet(signingCtx.Request)
 
-	if response, err = opts.HttpClient.Do(signingCtx.Request); err != nil {
+	send := decorateSendHandler(func(_ context.Context, request *http.Request) (*http.Response, error) {
+		if opts.HedgingPolicy != nil && !isPresignOptions(opts) && opts.HedgingPolicy.applies(input, request) {
+			return opts.HedgingPolicy.do(request, opts.HttpClient.Do)
+		}
+		return opts.HttpClient.Do(request)
+	}, opts.Middlewares)
+
+	if response, err = send(ctx, signingCtx.Request); err != nil {
 		return response, err
 	}
 
@@ -560,6 +818,45 @@ func (c *Client) sendHttpRequestOnce(ctx context.Context, signingCtx *signer.Sig
 	return response, err
 }
 
+// useChunkedSigning returns whether the body of unknown length is signed chunk by chunk.
+func useChunkedSigning(input *OperationInput, request *http.Request, opts *Options) bool {
+	if opts.FeatureFlags&FeatureEnableChunkedSigning == 0 || input.Body == nil {
+		return false
+	}
+	if input.OpName != "PutObject" && input.OpName != "UploadPart" {
+		return false
+	}
+	if opts.AuthMethod != nil && *opts.AuthMethod == AuthMethodQuery {
+		return false
+	}
+	if _, ok := opts.Signer.(*signer.SignerV4); !ok {
+		return false
+	}
+	if _, anonymous := opts.CredentialsProvider.(*credentials.AnonymousCredentialsProvider); anonymous {
+		return false
+	}
+	return request.Header.Get(HTTPHeaderContentLength) == "" && GetReaderLen(input.Body) < 0
+}
+
+func (c *Client) signRequest(ctx context.Context, signingCtx *signer.SigningContext, opts *Options) error {
+	if _, anonymous := opts.CredentialsProvider.(*credentials.AnonymousCredentialsProvider); anonymous {
+		return nil
+	}
+
+	cred, err := opts.CredentialsProvider.GetCredentials(ctx)
+	if err != nil {
+		return err
+	}
+
+	signingCtx.Credentials = &cred
+	if err = opts.Signer.Sign(ctx, signingCtx); err != nil {
+		return err
+	}
+	logFields(ctx, c.inner.Log, LogDebug, fmt.Sprintf("sendHttpRequestOnce::Sign request[%p]", signingCtx.Request),
+		LogField{Key: LogFieldStringToSign, Value: signingCtx.StringToSign})
+	return nil
+}
+
 func (c *Client) postSendHttpRequestOnce(signingCtx *signer.SigningContext, _ *http.Response, err error) {
 	if err != nil {
 		switch e := err.(type) {
@@ -568,8 +865,14 @@ func (c *Client) postSendHttpRequestOnce(signingCtx *signer.SigningContext, _ *h
 				e.Code == "RequestTimeTooSkewed" &&
 				!e.Timestamp.IsZero() {
 				signingCtx.ClockOffset = e.Timestamp.Sub(signingCtx.Time)
-				c.inner.Log.Warnf("Got RequestTimeTooSkewed error, correct clock request[%p], ClockOffset:%v, Server Time:%v, Client time:%v",
-					signingCtx.Request, signingCtx.ClockOffset, e.Timestamp, signingCtx.Time)
+				logFields(signingCtx.Request.Context(), c.inner.Log, LogWarn,
+					fmt.Sprintf("Got RequestTimeTooSkewed error, correct clock request[%p], ClockOffset:%v, Server Time:%v, Client time:%v",
+						signingCtx.Request, signingCtx.ClockOffset, e.Timestamp, signingCtx.Time))
+			}
+			if e.Code == "SignatureDoesNotMatch" {
+				e.SignatureDiagnostics = newSignatureDiagnostics(signingCtx, e)
+				logFields(signingCtx.Request.Context(), c.inner.Log, LogWarn,
+					fmt.Sprintf("Got SignatureDoesNotMatch error, request[%p], %v", signingCtx.Request, e.SignatureDiagnostics))
 			}
 		}
 	}
@@ -749,6 +1052,49 @@ func applyOperationOpt(c *Options, op *Options) {
 		c.AuthMethod = op.AuthMethod
 	}
 
+	if op.CredentialsProvider != nil {
+		c.CredentialsProvider = op.CredentialsProvider
+	}
+
+	if op.Signer != nil {
+		c.Signer = op.Signer
+	}
+
+	if op.AdditionalHeaders != nil {
+		c.AdditionalHeaders = op.AdditionalHeaders
+	}
+
+	if op.Tracer != nil {
+		c.Tracer = op.Tracer
+	}
+
+	if op.MetricsCollector != nil {
+		c.MetricsCollector = op.MetricsCollector
+	}
+
+	if op.RequestRateLimiter != nil {
+		c.RequestRateLimiter = op.RequestRateLimiter
+	}
+
+	if op.CircuitBreaker != nil {
+		c.CircuitBreaker = op.CircuitBreaker
+	}
+
+	if op.HedgingPolicy != nil {
+		c.HedgingPolicy = op.HedgingPolicy
+	}
+
+	if op.EndpointPool != nil {
+		c.EndpointPool = op.EndpointPool
+		if op.Endpoint == nil {
+			c.Endpoint = op.EndpointPool.primary()
+		}
+	}
+
+	if len(op.Middlewares) > 0 {
+		c.Middlewares = append(c.Middlewares, op.Middlewares...)
+	}
+
 	//response handler
 	handlers := []func(*http.Response) error{
 		serviceErrorResponseHandler,
@@ -1494,6 +1840,30 @@ func (c *Client) toClientError(err error, code string, output *OperationOutput)
 		Err: err}
 }
 
+// SetUploadBandwidthlimit changes the upload bandwidth limit at runtime, in KiB/s. 0 means no limit.
+// It only works with the http client created by the sdk.
+func (c *Client) SetUploadBandwidthlimit(value int64) error {
+	return c.setBandwidthlimit(BwTokenBucketSlotTx, value)
+}
+
+// SetDownloadBandwidthlimit changes the download bandwidth limit at runtime, in KiB/s. 0 means no limit.
+// It only works with the http client created by the sdk.
+func (c *Client) SetDownloadBandwidthlimit(value int64) error {
+	return c.setBandwidthlimit(BwTokenBucketSlotRx, value)
+}
+
+func (c *Client) setBandwidthlimit(slot int, value int64) error {
+	tb := c.inner.BwTokenBuckets[slot]
+	if tb == nil {
+		return fmt.Errorf("bandwidth limit is not supported with a custom http client")
+	}
+	if value < 0 {
+		return NewErrParamInvalid("value")
+	}
+	tb.SetBandwidth(value * 1024)
+	return nil
+}
+
 func (c *Client) hasFeature(flag FeatureFlagsType) bool {
 	return (c.options.FeatureFlags & flag) > 0
 }
@@ -1514,67 +1884,69 @@ func (c *Client) retryMaxAttempts(opts *Options) int {
 	return retry.DefaultMaxAttempts
 }
 
-func (c *Client) dumpOperationOutput(output *OperationOutput) string {
+func (c *Client) dumpOperationOutput(output *OperationOutput, err error) []LogField {
+	if err != nil {
+		return responseLogFields(nil, err)
+	}
 	if output == nil {
-		return ""
+		return nil
+	}
+	return []LogField{
+		{Key: LogFieldStatus, Value: output.StatusCode},
+		{Key: LogFieldRequestId, Value: output.Headers.Get(HeaderOssRequestID)},
 	}
-	return fmt.Sprintf("http.Request[%p] Status:%v, StatusCode%v, RequestId:%v",
-		output.httpRequest, output.Status, output.StatusCode,
-		output.Headers.Get(HeaderOssRequestID),
-	)
+}
+
+func operationLogFields(input *OperationInput) []LogField {
+	return []LogField{
+		{Key: LogFieldOperation, Value: input.OpName},
+		{Key: LogFieldBucket, Value: ToString(input.Bucket)},
+		{Key: LogFieldKey, Value: ToString(input.Key)},
+	}
+}
+
+func responseLogFields(response *http.Response, err error) []LogField {
+	var fields []LogField
+	var serr *ServiceError
+	if errors.As(err, &serr) {
+		fields = append(fields,
+			LogField{Key: LogFieldStatus, Value: serr.StatusCode},
+			LogField{Key: LogFieldRequestId, Value: serr.RequestID})
+	} else if response != nil {
+		fields = append(fields,
+			LogField{Key: LogFieldStatus, Value: response.StatusCode},
+			LogField{Key: LogFieldRequestId, Value: response.Header.Get(HeaderOssRequestID)})
+	}
+	if err != nil {
+		fields = append(fields, LogField{Key: LogFieldError, Value: err})
+	}
+	return fields
 }
 
 // LoggerHTTPReq Print the header information of the http request
 func (c *Client) logHttpPRequet(request *http.Request) {
-	if c.getLogLevel() < LogDebug {
+	if c.getLogLevel() < LogDebug || request == nil {
 		return
 	}
-	var logBuffer bytes.Buffer
-	logBuffer.WriteString(fmt.Sprintf("http.request[%p]", request))
-	if request != nil {
-		logBuffer.WriteString(fmt.Sprintf("Method:%s\t", request.Method))
-		logBuffer.WriteString(fmt.Sprintf("Host:%s\t", request.URL.Host))
-		logBuffer.WriteString(fmt.Sprintf("Path:%s\t", request.URL.Path))
-		logBuffer.WriteString(fmt.Sprintf("Query:%s\t", request.URL.RawQuery))
-		logBuffer.WriteString(fmt.Sprintf("Header info:"))
-
-		for k, v := range request.Header {
-			var valueBuffer bytes.Buffer
-			for j := 0; j < len(v); j++ {
-				if j > 0 {
-					valueBuffer.WriteString(" ")
-				}
-				valueBuffer.WriteString(v[j])
-			}
-			logBuffer.WriteString(fmt.Sprintf("\t%s:%s", k, valueBuffer.String()))
-		}
-	}
-
-	c.inner.Log.Debugf("%s", logBuffer.String())
+	logFields(request.Context(), c.inner.Log, LogDebug, fmt.Sprintf("http.request[%p]", request),
+		LogField{Key: LogFieldMethod, Value: request.Method},
+		LogField{Key: LogFieldHost, Value: request.URL.Host},
+		LogField{Key: LogFieldPath, Value: request.URL.Path},
+		LogField{Key: LogFieldQuery, Value: redactQuery(request.URL.RawQuery)},
+		LogField{Key: LogFieldHeaders, Value: redactHeader(request.Header)},
+	)
 }
 
 // LoggerHTTPResp Print Response to http request
 func (c *Client) logHttpResponse(request *http.Request, response *http.Response) {
-	if c.getLogLevel() < LogDebug {
+	if c.getLogLevel() < LogDebug || response == nil {
 		return
 	}
-	var logBuffer bytes.Buffer
-	logBuffer.WriteString(fmt.Sprintf("http.request[%p]|http.response[%p]", request, response))
-	if response != nil {
-		logBuffer.WriteString(fmt.Sprintf("StatusCode:%d\t", response.StatusCode))
-		logBuffer.WriteString(fmt.Sprintf("Header info:"))
-		for k, v := range response.Header {
-			var valueBuffer bytes.Buffer
-			for j := 0; j < len(v); j++ {
-				if j > 0 {
-					valueBuffer.WriteString(" ")
-				}
-				valueBuffer.WriteString(v[j])
-			}
-			logBuffer.WriteString(fmt.Sprintf("\t%s:%s", k, valueBuffer.String()))
-		}
-	}
-	c.inner.Log.Debugf("%s", logBuffer.String())
+	logFields(request.Context(), c.inner.Log, LogDebug, fmt.Sprintf("http.request[%p]|http.response[%p]", request, response),
+		LogField{Key: LogFieldStatus, Value: response.StatusCode},
+		LogField{Key: LogFieldRequestId, Value: response.Header.Get(HeaderOssRequestID)},
+		LogField{Key: LogFieldHeaders, Value: redactHeader(response.Header)},
+	)
 }
 
 func (c *Client) getLogLevel() int {
@@ -4,12 +4,19 @@ import (
 	"testing"
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"net/http/httptest"
+	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/credentials"
 	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/retry"
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/signer"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -1988,3 +1995,290 @@ func TestMockReturnsXmlError(t *testing.T) {
 }
 
 
+
+func TestInvokeOperation_AdaptiveRetryer(t *testing.T) {
+	var count int32
+	server := testSetupMockServer(t, 503, map[string]string{"x-oss-request-id": "id-1234", "Retry-After": "1"},
+		[]byte(`<Error><Code>ServiceUnavailable</Code><Message>error</Message><RequestId>id-1234</RequestId></Error>`),
+		func(t *testing.T, r *http.Request) {
+			count++
+		})
+	defer server.Close()
+
+	cfg := LoadDefaultConfig().
+		WithCredentialsProvider(credentials.NewAnonymousCredentialsProvider()).
+		WithRegion("cn-hangzhou").
+		WithEndpoint(server.URL).
+		WithRetryer(retry.NewAdaptive(func(o *retry.AdaptiveOptions) {
+			o.MaxBackoff = 100 * time.Millisecond
+			// the min send rate after throttling slows the test down
+			o.DisableRateLimit = true
+		}))
+
+	client := NewClient(cfg)
+
+	// the hint is capped by MaxBackoff
+	start := time.Now()
+	_, err := client.GetObject(context.TODO(), &GetObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")})
+	var serr *ServiceError
+	assert.True(t, errors.As(err, &serr))
+	assert.Equal(t, time.Second, serr.RetryAfter())
+	assert.Equal(t, int32(3), count)
+	assert.True(t, time.Since(start) >= 200*time.Millisecond)
+
+	// 503 is not a throttling response by itself, so a non-idempotent operation is not retried
+	count = 0
+	_, err = client.AppendObject(context.TODO(), &AppendObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key"), Position: Ptr(int64(0))})
+	assert.NotNil(t, err)
+	assert.Equal(t, int32(1), count)
+
+	// SlowDown is a throttling response, so a non-idempotent operation is retried too
+	serverSlowDown := testSetupMockServer(t, 503, map[string]string{"x-oss-request-id": "id-1234"},
+		[]byte(`<Error><Code>SlowDown</Code><Message>error</Message><RequestId>id-1234</RequestId></Error>`),
+		func(t *testing.T, r *http.Request) {
+			count++
+		})
+	defer serverSlowDown.Close()
+
+	count = 0
+	_, err = NewClient(cfg.WithEndpoint(serverSlowDown.URL)).AppendObject(context.TODO(), &AppendObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key"), Position: Ptr(int64(0))})
+	assert.NotNil(t, err)
+	assert.Equal(t, int32(3), count)
+
+	server500 := testSetupMockServer(t, 500, map[string]string{"x-oss-request-id": "id-1234"},
+		[]byte(`<Error><Code>InternalError</Code><Message>error</Message><RequestId>id-1234</RequestId></Error>`),
+		func(t *testing.T, r *http.Request) {
+			count++
+		})
+	defer server500.Close()
+
+	client = NewClient(cfg.WithEndpoint(server500.URL))
+	count = 0
+	_, err = client.AppendObject(context.TODO(), &AppendObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key"), Position: Ptr(int64(0))})
+	assert.NotNil(t, err)
+	assert.Equal(t, int32(1), count)
+
+	count = 0
+	_, err = client.CompleteMultipartUpload(context.TODO(), &CompleteMultipartUploadRequest{Bucket: Ptr("bucket"), Key: Ptr("key"), UploadId: Ptr("id")})
+	assert.NotNil(t, err)
+	assert.Equal(t, int32(1), count)
+
+	count = 0
+	_, err = client.GetObject(context.TODO(), &GetObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")})
+	assert.NotNil(t, err)
+	assert.Equal(t, int32(3), count)
+}
+
+func TestInvokeOperation_RetryQuotaExceeded(t *testing.T) {
+	var count int32
+	server := testSetupMockServer(t, 500, map[string]string{"x-oss-request-id": "id-1234"},
+		[]byte(`<Error><Code>InternalError</Code><Message>error</Message><RequestId>id-1234</RequestId></Error>`),
+		func(t *testing.T, r *http.Request) {
+			count++
+		})
+	defer server.Close()
+
+	cfg := LoadDefaultConfig().
+		WithCredentialsProvider(credentials.NewAnonymousCredentialsProvider()).
+		WithRegion("cn-hangzhou").
+		WithEndpoint(server.URL).
+		WithRetryer(retry.NewAdaptive(func(o *retry.AdaptiveOptions) {
+			o.MaxBackoff = 10 * time.Millisecond
+			o.RetryQuota = 5
+		}))
+
+	client := NewClient(cfg)
+
+	// the quota is enough for one retry
+	_, err := client.GetObject(context.TODO(), &GetObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")})
+	assert.Equal(t, int32(2), count)
+	assert.True(t, errors.Is(err, retry.ErrRetryQuotaExceeded))
+	var serr *ServiceError
+	assert.True(t, errors.As(err, &serr))
+	assert.Equal(t, "InternalError", serr.Code)
+	var operr *OperationError
+	assert.True(t, errors.As(err, &operr))
+	assert.Contains(t, err.Error(), "retry quota exceeded")
+	assert.Contains(t, err.Error(), "InternalError")
+
+	// no retry at all
+	count = 0
+	_, err = client.GetObject(context.TODO(), &GetObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")})
+	assert.Equal(t, int32(1), count)
+	assert.True(t, errors.Is(err, retry.ErrRetryQuotaExceeded))
+	assert.True(t, errors.As(err, &serr))
+}
+
+type countingAttemptRetryer struct {
+	*retry.Adaptive
+	before, after int32
+}
+
+func (r *countingAttemptRetryer) BeforeAttempt(ctx context.Context, attempt retry.Attempt) error {
+	atomic.AddInt32(&r.before, 1)
+	return r.Adaptive.BeforeAttempt(ctx, attempt)
+}
+
+func (r *countingAttemptRetryer) AfterAttempt(attempt retry.Attempt, err error) {
+	atomic.AddInt32(&r.after, 1)
+	r.Adaptive.AfterAttempt(attempt, err)
+}
+
+func TestInvokeOperation_AdaptiveRetryerPresign(t *testing.T) {
+	server := testSetupMockServer(t, 200, map[string]string{"x-oss-request-id": "id-1234"}, nil,
+		func(t *testing.T, r *http.Request) {})
+	defer server.Close()
+
+	retryer := &countingAttemptRetryer{Adaptive: retry.NewAdaptive()}
+	cfg := LoadDefaultConfig().
+		WithCredentialsProvider(credentials.NewStaticCredentialsProvider("ak", "sk")).
+		WithRegion("cn-hangzhou").
+		WithEndpoint(server.URL).
+		WithRetryer(retryer)
+
+	client := NewClient(cfg)
+
+	// presigning sends nothing, the send rate is not updated
+	for i := 0; i < 3; i++ {
+		_, err := client.Presign(context.TODO(), &GetObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")})
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, int32(0), atomic.LoadInt32(&retryer.before))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&retryer.after))
+
+	_, err := client.HeadObject(context.TODO(), &HeadObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&retryer.before))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&retryer.after))
+}
+
+func TestServiceError_RetryAfter(t *testing.T) {
+	err := &ServiceError{Headers: http.Header{}}
+	assert.Equal(t, time.Duration(0), err.RetryAfter())
+	err.Headers.Set("Retry-After", "3")
+	assert.Equal(t, 3*time.Second, err.RetryAfter())
+	err.Headers.Set("Retry-After", "-1")
+	assert.Equal(t, time.Duration(0), err.RetryAfter())
+	err.Headers.Set("Retry-After", time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+	assert.True(t, err.RetryAfter() > 50*time.Second)
+	err.Headers.Set("Retry-After", "abc")
+	assert.Equal(t, time.Duration(0), err.RetryAfter())
+	assert.Equal(t, time.Duration(0), (&ServiceError{}).RetryAfter())
+}
+
+func TestInvokeOperation_CredentialsOverride(t *testing.T) {
+	var mu sync.Mutex
+	auths := map[string][]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		auth := r.Header.Get("Authorization")
+		if auth == "" {
+			auth = r.URL.Query().Get("x-oss-credential") + r.URL.Query().Get("OSSAccessKeyId")
+		}
+		mu.Lock()
+		auths[r.Method] = append(auths[r.Method], auth)
+		mu.Unlock()
+		w.Header().Set("x-oss-request-id", "id-1234")
+		w.Header().Set("ETag", "\"D41D8CD98F00B204E9800998ECF8427E\"")
+		w.Header().Set("Last-Modified", "Fri, 24 Feb 2012 06:07:48 GMT")
+		switch {
+		case r.Method == "HEAD":
+			w.Header().Set("Content-Length", "10")
+			w.WriteHeader(200)
+		case r.Method == "GET":
+			w.Header().Set("Content-Length", "10")
+			w.WriteHeader(200)
+			io.WriteString(w, "helloworld")
+		case r.Header.Get("x-oss-copy-source") != "":
+			w.WriteHeader(200)
+			io.WriteString(w, `<CopyObjectResult><ETag>"D41D8CD98F00B204E9800998ECF8427E"</ETag><LastModified>2012-02-24T06:07:48.000Z</LastModified></CopyObjectResult>`)
+		default:
+			w.WriteHeader(200)
+		}
+	}))
+	defer server.Close()
+	reset := func() map[string][]string {
+		mu.Lock()
+		defer mu.Unlock()
+		old := auths
+		auths = map[string][]string{}
+		return old
+	}
+
+	cfg := LoadDefaultConfig().
+		WithCredentialsProvider(credentials.NewStaticCredentialsProvider("ak", "sk")).
+		WithRegion("cn-hangzhou").
+		WithEndpoint(server.URL)
+	client := NewClient(cfg)
+	tenant := OpCredentialsProvider(credentials.NewStaticCredentialsProvider("tenant-ak", "tenant-sk", "tenant-token"))
+
+	_, err := client.PutObject(context.TODO(), &PutObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")})
+	assert.Nil(t, err)
+	_, err = client.PutObject(context.TODO(), &PutObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")}, tenant)
+	assert.Nil(t, err)
+	_, err = client.PutObject(context.TODO(), &PutObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")}, tenant, OpSigner(&signer.SignerV1{}))
+	assert.Nil(t, err)
+	puts := reset()["PUT"]
+	assert.Len(t, puts, 3)
+	assert.Contains(t, puts[0], "OSS4-HMAC-SHA256 Credential=ak/")
+	assert.Contains(t, puts[1], "OSS4-HMAC-SHA256 Credential=tenant-ak/")
+	assert.True(t, strings.HasPrefix(puts[2], "OSS tenant-ak:"))
+
+	// the tenants share the client
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := fmt.Sprintf("tenant-%d", i)
+			_, err := client.HeadObject(context.TODO(), &HeadObjectRequest{Bucket: Ptr("bucket"), Key: Ptr(id)},
+				OpCredentialsProvider(credentials.NewStaticCredentialsProvider(id, "sk")))
+			assert.Nil(t, err)
+		}(i)
+	}
+	wg.Wait()
+	heads := reset()["HEAD"]
+	assert.Len(t, heads, 10)
+	for i := 0; i < 10; i++ {
+		found := false
+		for _, h := range heads {
+			found = found || strings.Contains(h, fmt.Sprintf("Credential=tenant-%d/", i))
+		}
+		assert.True(t, found)
+	}
+
+	// presign
+	result, err := client.Presign(context.TODO(), &GetObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")},
+		func(o *PresignOptions) { o.ClientOptions = []func(*Options){tenant} })
+	assert.Nil(t, err)
+	assert.Contains(t, result.URL, "x-oss-credential=tenant-ak")
+	assert.Contains(t, result.URL, "x-oss-security-token=tenant-token")
+
+	result, err = client.Presign(context.TODO(), &GetObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")},
+		func(o *PresignOptions) { o.ClientOptions = []func(*Options){tenant, OpSigner(&signer.SignerV1{})} })
+	assert.Nil(t, err)
+	assert.Contains(t, result.URL, "OSSAccessKeyId=tenant-ak")
+	assert.NotContains(t, result.URL, "x-oss-credential")
+	assert.Equal(t, 0, len(reset()))
+
+	// the transfer managers
+	clientOptions := func(o *[]func(*Options)) { *o = []func(*Options){tenant} }
+	_, err = NewUploader(client, func(o *UploaderOptions) { clientOptions(&o.ClientOptions) }).
+		UploadFrom(context.TODO(), &PutObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")}, strings.NewReader("helloworld"))
+	assert.Nil(t, err)
+	_, err = NewDownloader(client, func(o *DownloaderOptions) { clientOptions(&o.ClientOptions) }).
+		DownloadFile(context.TODO(), &GetObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")}, filepath.Join(t.TempDir(), "file"))
+	assert.Nil(t, err)
+	_, err = NewCopier(client, func(o *CopierOptions) { clientOptions(&o.ClientOptions) }).
+		Copy(context.TODO(), &CopyObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key"), SourceKey: Ptr("src")})
+	assert.Nil(t, err)
+	all := reset()
+	assert.True(t, len(all["PUT"]) >= 2)
+	assert.True(t, len(all["GET"]) >= 1)
+	assert.True(t, len(all["HEAD"]) >= 2)
+	for _, list := range all {
+		for _, auth := range list {
+			assert.Contains(t, auth, "Credential=tenant-ak/")
+		}
+	}
+}
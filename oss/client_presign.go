@@ -16,6 +16,9 @@ type PresignOptions struct {
 
 	// Expiration sets the expiration time for the generated presign url.
 	Expiration time.Time
+
+	// ClientOptions are applied to the presign operation, e.g. the CredentialsProvider and the Signer of a tenant.
+	ClientOptions []func(*Options)
 }
 
 type PresignResult struct {
@@ -36,6 +39,12 @@ func (c *nopHttpClient) Do(*http.Request) (*http.Response, error) {
 	}, nil
 }
 
+// isPresignOptions reports whether the operation only signs the request, nothing is sent.
+func isPresignOptions(opts *Options) bool {
+	_, ok := opts.HttpClient.(*nopHttpClient)
+	return ok
+}
+
 var (
 	defaultNopHttpClient  = &nopHttpClient{}
 	defaultPresignOptions = []func(*Options){
@@ -68,13 +77,22 @@ func (c *Client) Presign(ctx context.Context, request any, optFns ...func(*Presi
 	} else if options.Expires > 0 {
 		input.OpMetadata.Set(signer.SignTime, time.Now().Add(options.Expires))
 	}
-	output, err := c.invokeOperation(ctx, &input, defaultPresignOptions)
+	// the presign options can not be overridden
+	opOpts := append(append([]func(*Options){}, options.ClientOptions...), defaultPresignOptions...)
+	output, err := c.invokeOperation(ctx, &input, opOpts)
 	if err != nil {
 		return nil, err
 	}
 
+	presignOpts := c.options.Copy()
+	opOpt := Options{}
+	for _, fn := range options.ClientOptions {
+		fn(&opOpt)
+	}
+	applyOperationOpt(&presignOpts, &opOpt)
+
 	result := &PresignResult{}
-	err = c.unmarshalPresignOutput(result, output)
+	err = c.unmarshalPresignOutput(result, output, &presignOpts)
 	return result, err
 }
 
@@ -137,11 +155,12 @@ func (c *Client) marshalPresignInput(request any, input *OperationInput) error {
 	return c.marshalInput(request, input)
 }
 
-func (c *Client) unmarshalPresignOutput(result *PresignResult, output *OperationOutput) error {
-	if chk, ok := c.options.Signer.(interface{ IsSignedHeader([]string, string) bool }); ok {
+func (c *Client) unmarshalPresignOutput(result *PresignResult, output *OperationOutput, opts *Options) error {
+	opSigner := opts.Signer
+	if chk, ok := opSigner.(interface{ IsSignedHeader([]string, string) bool }); ok {
 		header := map[string]string{}
 		for k, v := range output.httpRequest.Header {
-			if chk.IsSignedHeader(c.options.AdditionalHeaders, k) {
+			if chk.IsSignedHeader(opts.AdditionalHeaders, k) {
 				header[k] = v[0]
 			}
 		}
@@ -154,7 +173,7 @@ func (c *Client) unmarshalPresignOutput(result *PresignResult, output *Operation
 	if signTime, ok := output.OpMetadata.Get(signer.SignTime).(time.Time); ok {
 		result.Expiration = signTime
 	}
-	_, ok := c.options.Signer.(*signer.SignerV4)
+	_, ok := opSigner.(*signer.SignerV4)
 	if ok {
 		if !result.Expiration.IsZero() && (result.Expiration.After(time.Now().Add(7 * 24 * time.Hour))) {
 			return fmt.Errorf("expires should be not greater than 604800(seven days)")
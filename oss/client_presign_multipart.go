@@ -0,0 +1,205 @@
+package oss
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/credentials"
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/signer"
+)
+
+type PresignPart struct {
+	// The number of the part, from 1 to 10000.
+	PartNumber int32
+
+	// The size of the part, the uploader must send the same Content-Length.
+	// It is signed only with the V4 signature.
+	ContentLength *int64
+
+	// The base64-encoded MD5 of the part, the uploader must send the same Content-MD5.
+	ContentMD5 *string
+}
+
+type PresignMultipartUploadRequest struct {
+	// The request to initiate the multipart upload, e.g. the bucket, the key and the metadata of the object.
+	InitiateRequest *InitiateMultipartUploadRequest
+
+	// The number of the parts, the part numbers are from 1 to PartCount. It is ignored if Parts is set.
+	PartCount int
+
+	// The parts with the constraints.
+	Parts []PresignPart
+}
+
+type PresignPartResult struct {
+	PartNumber int32
+
+	PresignResult
+}
+
+type PresignMultipartUploadResult struct {
+	// The name of the bucket.
+	Bucket *string
+
+	// The name of the object.
+	Key *string
+
+	// The upload ID of the multipart upload.
+	UploadId *string
+
+	// The presigned UploadPart requests in the order of the parts.
+	Parts []PresignPartResult
+
+	// The presigned CompleteMultipartUpload request, the part list is sent in the body.
+	CompleteMultipartUpload *PresignResult
+
+	// The presigned AbortMultipartUpload request.
+	AbortMultipartUpload *PresignResult
+
+	// The expiration time of the presigned requests.
+	Expiration time.Time
+}
+
+// PresignMultipartUpload initiates a multipart upload, and presigns the UploadPart requests of the parts,
+// the CompleteMultipartUpload and the AbortMultipartUpload requests, so that the parts can be uploaded
+// without the credentials, e.g. by a browser. The credentials are resolved once for all the requests.
+func (c *Client) PresignMultipartUpload(ctx context.Context, request *PresignMultipartUploadRequest, optFns ...func(*PresignOptions)) (*PresignMultipartUploadResult, error) {
+	if request == nil {
+		return nil, NewErrParamNull("request")
+	}
+	if request.InitiateRequest == nil {
+		return nil, NewErrParamNull("request.InitiateRequest")
+	}
+
+	parts := request.Parts
+	if len(parts) == 0 {
+		if request.PartCount <= 0 || request.PartCount > int(MaxUploadParts) {
+			return nil, NewErrParamInvalid("request.PartCount")
+		}
+		for i := 1; i <= request.PartCount; i++ {
+			parts = append(parts, PresignPart{PartNumber: int32(i)})
+		}
+	}
+	for _, part := range parts {
+		if part.PartNumber < 1 || part.PartNumber > MaxUploadParts {
+			return nil, NewErrParamInvalid("request.Parts.PartNumber")
+		}
+	}
+
+	options := PresignOptions{}
+	for _, fn := range optFns {
+		fn(&options)
+	}
+
+	opts := c.options.Copy()
+	opOpt := Options{}
+	for _, fn := range options.ClientOptions {
+		fn(&opOpt)
+	}
+	applyOperationOpt(&opts, &opOpt)
+
+	// resolve the credentials once
+	if opts.CredentialsProvider == nil {
+		return nil, NewErrParamNull("CredentialsProvider")
+	}
+	cred, err := opts.CredentialsProvider.GetCredentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+	clientOptions := append(append([]func(*Options){}, options.ClientOptions...),
+		OpCredentialsProvider(credentials.NewStaticCredentialsProvider(cred.AccessKeyID, cred.AccessKeySecret, cred.SecurityToken)))
+
+	// all the requests expire at the same time
+	expiration := options.Expiration
+	if expiration.IsZero() {
+		expires := options.Expires
+		if expires <= 0 {
+			expires = DefaultPresignExpires
+		}
+		expiration = time.Now().Add(expires)
+	}
+	if _, ok := opts.Signer.(*signer.SignerV4); ok && expiration.After(time.Now().Add(7*24*time.Hour)) {
+		return nil, fmt.Errorf("expires should be not greater than 604800(seven days)")
+	}
+	presignFns := []func(*PresignOptions){
+		PresignExpiration(expiration),
+		func(o *PresignOptions) { o.ClientOptions = clientOptions },
+	}
+
+	initResult, err := c.InitiateMultipartUpload(ctx, request.InitiateRequest, clientOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PresignMultipartUploadResult{
+		Bucket:     request.InitiateRequest.Bucket,
+		Key:        request.InitiateRequest.Key,
+		UploadId:   initResult.UploadId,
+		Parts:      make([]PresignPartResult, 0, len(parts)),
+		Expiration: expiration,
+	}
+
+	if err = c.presignMultipartUploadRequests(ctx, result, parts, opts.AdditionalHeaders, clientOptions, presignFns); err != nil {
+		// the upload is not handed out, do not leave it behind
+		_, _ = c.AbortMultipartUpload(ctx, &AbortMultipartUploadRequest{
+			Bucket:   result.Bucket,
+			Key:      result.Key,
+			UploadId: result.UploadId,
+		}, clientOptions...)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// presignMultipartUploadRequests presigns the UploadPart requests of the parts, the CompleteMultipartUpload
+// and the AbortMultipartUpload requests of the initiated upload.
+func (c *Client) presignMultipartUploadRequests(ctx context.Context, result *PresignMultipartUploadResult, parts []PresignPart,
+	additionalHeaders []string, clientOptions []func(*Options), presignFns []func(*PresignOptions)) (err error) {
+	for _, part := range parts {
+		fns := append([]func(*PresignOptions){}, presignFns...)
+		if part.ContentLength != nil {
+			// the Content-Length is not signed by default
+			headers := append(append([]string{}, additionalHeaders...), HTTPHeaderContentLength)
+			fns = append(fns, func(o *PresignOptions) {
+				o.ClientOptions = append(append([]func(*Options){}, clientOptions...), func(o *Options) {
+					o.AdditionalHeaders = headers
+				})
+			})
+		}
+		presignResult, err := c.Presign(ctx, &UploadPartRequest{
+			Bucket:        result.Bucket,
+			Key:           result.Key,
+			UploadId:      result.UploadId,
+			PartNumber:    part.PartNumber,
+			ContentLength: part.ContentLength,
+			ContentMD5:    part.ContentMD5,
+		}, fns...)
+		if err != nil {
+			return err
+		}
+		result.Parts = append(result.Parts, PresignPartResult{
+			PartNumber:    part.PartNumber,
+			PresignResult: *presignResult,
+		})
+	}
+
+	if result.CompleteMultipartUpload, err = c.Presign(ctx, &CompleteMultipartUploadRequest{
+		Bucket:   result.Bucket,
+		Key:      result.Key,
+		UploadId: result.UploadId,
+	}, presignFns...); err != nil {
+		return err
+	}
+
+	if result.AbortMultipartUpload, err = c.Presign(ctx, &AbortMultipartUploadRequest{
+		Bucket:   result.Bucket,
+		Key:      result.Key,
+		UploadId: result.UploadId,
+	}, presignFns...); err != nil {
+		return err
+	}
+
+	return nil
+}
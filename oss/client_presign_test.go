@@ -3,12 +3,17 @@ package oss
 import (
 	"context"
 	"fmt"
+	"io"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/credentials"
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/signer"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -519,3 +524,190 @@ func TestPresignWithError(t *testing.T) {
 	assert.NotNil(t, err)
 	assert.Contains(t, err.Error(), "expires should be not greater than 604800(seven days)")
 }
+
+func TestPresignMultipartUpload(t *testing.T) {
+	var initiates int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		if r.Method != "POST" || !r.URL.Query().Has("uploads") {
+			w.WriteHeader(400)
+			return
+		}
+		atomic.AddInt32(&initiates, 1)
+		w.Header().Set("x-oss-request-id", "id-1234")
+		w.Header().Set("Content-Type", "application/xml")
+		w.WriteHeader(200)
+		io.WriteString(w, `<?xml version="1.0" encoding="UTF-8"?>
+<InitiateMultipartUploadResult>
+  <Bucket>bucket</Bucket>
+  <Key>key</Key>
+  <UploadId>upload-id</UploadId>
+</InitiateMultipartUploadResult>`)
+	}))
+	defer server.Close()
+
+	var calls int32
+	provider := credentials.CredentialsProviderFunc(func(ctx context.Context) (credentials.Credentials, error) {
+		atomic.AddInt32(&calls, 1)
+		return credentials.Credentials{AccessKeyID: "ak", AccessKeySecret: "sk", SecurityToken: "token"}, nil
+	})
+	cfg := LoadDefaultConfig().
+		WithCredentialsProvider(provider).
+		WithRegion("cn-hangzhou").
+		WithEndpoint(server.URL)
+	client := NewClient(cfg)
+
+	// V4
+	expiration := time.Now().Add(time.Hour)
+	result, err := client.PresignMultipartUpload(context.TODO(), &PresignMultipartUploadRequest{
+		InitiateRequest: &InitiateMultipartUploadRequest{Bucket: Ptr("bucket"), Key: Ptr("key")},
+		PartCount:       3,
+	}, PresignExpiration(expiration))
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&initiates))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	assert.Equal(t, "upload-id", ToString(result.UploadId))
+	assert.Equal(t, "bucket", ToString(result.Bucket))
+	assert.Equal(t, "key", ToString(result.Key))
+	assert.Equal(t, expiration, result.Expiration)
+	assert.Len(t, result.Parts, 3)
+	for i, part := range result.Parts {
+		assert.Equal(t, int32(i+1), part.PartNumber)
+		assert.Equal(t, "PUT", part.Method)
+		assert.Equal(t, expiration, part.Expiration)
+		assert.Contains(t, part.URL, fmt.Sprintf("partNumber=%d", i+1))
+		assert.Contains(t, part.URL, "uploadId=upload-id")
+		assert.Contains(t, part.URL, "x-oss-security-token=token")
+		assert.Contains(t, part.URL, "x-oss-signature=")
+		assert.Empty(t, part.SignedHeaders)
+	}
+	assert.Equal(t, "POST", result.CompleteMultipartUpload.Method)
+	assert.Contains(t, result.CompleteMultipartUpload.URL, "uploadId=upload-id")
+	assert.Contains(t, result.CompleteMultipartUpload.URL, "x-oss-signature=")
+	assert.Equal(t, expiration, result.CompleteMultipartUpload.Expiration)
+	assert.Equal(t, "DELETE", result.AbortMultipartUpload.Method)
+	assert.Contains(t, result.AbortMultipartUpload.URL, "uploadId=upload-id")
+	assert.Equal(t, expiration, result.AbortMultipartUpload.Expiration)
+
+	// the parts with the constraints
+	result, err = client.PresignMultipartUpload(context.TODO(), &PresignMultipartUploadRequest{
+		InitiateRequest: &InitiateMultipartUploadRequest{Bucket: Ptr("bucket"), Key: Ptr("key")},
+		Parts: []PresignPart{
+			{PartNumber: 1, ContentLength: Ptr(int64(100 * 1024)), ContentMD5: Ptr("1B2M2Y8AsgTpgAmY7PhCfg==")},
+			{PartNumber: 2, ContentLength: Ptr(int64(1024))},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	assert.Len(t, result.Parts, 2)
+	assert.Equal(t, "102400", result.Parts[0].SignedHeaders["Content-Length"])
+	assert.Equal(t, "1B2M2Y8AsgTpgAmY7PhCfg==", result.Parts[0].SignedHeaders["Content-Md5"])
+	assert.Contains(t, result.Parts[0].URL, "x-oss-additional-headers=content-length")
+	assert.Equal(t, "1024", result.Parts[1].SignedHeaders["Content-Length"])
+	assert.Empty(t, result.Parts[1].SignedHeaders["Content-Md5"])
+	assert.NotContains(t, result.CompleteMultipartUpload.URL, "x-oss-additional-headers")
+	assert.True(t, result.Expiration.Sub(time.Now()) > DefaultPresignExpires-time.Minute)
+	assert.True(t, result.Expiration.Sub(time.Now()) <= DefaultPresignExpires)
+
+	// V1, the Content-Length is not signed
+	cfgV1 := cfg.Copy()
+	client = NewClient(cfgV1.WithSignatureVersion(SignatureVersionV1))
+	result, err = client.PresignMultipartUpload(context.TODO(), &PresignMultipartUploadRequest{
+		InitiateRequest: &InitiateMultipartUploadRequest{Bucket: Ptr("bucket"), Key: Ptr("key")},
+		Parts: []PresignPart{
+			{PartNumber: 1, ContentLength: Ptr(int64(1024)), ContentMD5: Ptr("1B2M2Y8AsgTpgAmY7PhCfg==")},
+		},
+	}, PresignExpires(time.Hour))
+	assert.Nil(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	assert.Contains(t, result.Parts[0].URL, "OSSAccessKeyId=ak")
+	assert.True(t, strings.Contains(result.Parts[0].URL, "security-token=token"))
+	assert.Equal(t, map[string]string{"Content-Md5": "1B2M2Y8AsgTpgAmY7PhCfg=="}, result.Parts[0].SignedHeaders)
+
+	// invalid arguments
+	_, err = client.PresignMultipartUpload(context.TODO(), nil)
+	assert.Contains(t, err.Error(), "null field, request")
+	_, err = client.PresignMultipartUpload(context.TODO(), &PresignMultipartUploadRequest{PartCount: 1})
+	assert.Contains(t, err.Error(), "null field, request.InitiateRequest")
+	_, err = client.PresignMultipartUpload(context.TODO(), &PresignMultipartUploadRequest{
+		InitiateRequest: &InitiateMultipartUploadRequest{Bucket: Ptr("bucket"), Key: Ptr("key")},
+	})
+	assert.Contains(t, err.Error(), "invalid field, request.PartCount")
+	_, err = client.PresignMultipartUpload(context.TODO(), &PresignMultipartUploadRequest{
+		InitiateRequest: &InitiateMultipartUploadRequest{Bucket: Ptr("bucket"), Key: Ptr("key")},
+		Parts:           []PresignPart{{PartNumber: 10001}},
+	})
+	assert.Contains(t, err.Error(), "invalid field, request.Parts.PartNumber")
+	client = NewClient(cfg)
+	_, err = client.PresignMultipartUpload(context.TODO(), &PresignMultipartUploadRequest{
+		InitiateRequest: &InitiateMultipartUploadRequest{Bucket: Ptr("bucket"), Key: Ptr("key")},
+		PartCount:       1,
+	}, PresignExpires(8*24*time.Hour))
+	assert.Contains(t, err.Error(), "expires should be not greater than 604800(seven days)")
+	assert.Equal(t, int32(3), atomic.LoadInt32(&initiates))
+}
+
+type presignFailingSigner struct {
+	signer.SignerV4
+}
+
+func (s *presignFailingSigner) Sign(ctx context.Context, signingCtx *signer.SigningContext) error {
+	if signingCtx.AuthMethodQuery && signingCtx.Request.URL.Query().Get("partNumber") == "2" {
+		return fmt.Errorf("presign error")
+	}
+	return s.SignerV4.Sign(ctx, signingCtx)
+}
+
+func TestPresignMultipartUpload_AbortOnError(t *testing.T) {
+	var initiates, aborts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Header().Set("x-oss-request-id", "id-1234")
+		switch {
+		case r.Method == "POST" && r.URL.Query().Has("uploads"):
+			atomic.AddInt32(&initiates, 1)
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(200)
+			io.WriteString(w, `<?xml version="1.0" encoding="UTF-8"?>
+<InitiateMultipartUploadResult>
+  <Bucket>bucket</Bucket>
+  <Key>key</Key>
+  <UploadId>upload-id</UploadId>
+</InitiateMultipartUploadResult>`)
+		case r.Method == "DELETE" && r.URL.Query().Get("uploadId") == "upload-id":
+			atomic.AddInt32(&aborts, 1)
+			w.WriteHeader(204)
+		default:
+			w.WriteHeader(400)
+		}
+	}))
+	defer server.Close()
+
+	cfg := LoadDefaultConfig().
+		WithCredentialsProvider(credentials.NewStaticCredentialsProvider("ak", "sk")).
+		WithRegion("cn-hangzhou").
+		WithEndpoint(server.URL)
+	client := NewClient(cfg)
+
+	// the initiated upload is aborted if a presign fails
+	result, err := client.PresignMultipartUpload(context.TODO(), &PresignMultipartUploadRequest{
+		InitiateRequest: &InitiateMultipartUploadRequest{Bucket: Ptr("bucket"), Key: Ptr("key")},
+		PartCount:       3,
+	}, func(o *PresignOptions) {
+		o.ClientOptions = append(o.ClientOptions, func(o *Options) { o.Signer = &presignFailingSigner{} })
+	})
+	assert.Nil(t, result)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "presign error")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&initiates))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&aborts))
+
+	// not aborted on success
+	_, err = client.PresignMultipartUpload(context.TODO(), &PresignMultipartUploadRequest{
+		InitiateRequest: &InitiateMultipartUploadRequest{Bucket: Ptr("bucket"), Key: Ptr("key")},
+		PartCount:       3,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&initiates))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&aborts))
+}
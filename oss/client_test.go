@@ -2,12 +2,14 @@ package oss
 
 import (
 	"bytes"
+	"context"
 	"encoding/xml"
 	"io"
 	"net/http"
 	"testing"
 	"time"
 
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/credentials"
 	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/retry"
 	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/signer"
 	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/transport"
@@ -935,6 +937,13 @@ func TestHasFeature(t *testing.T) {
 	assert.True(t, c.hasFeature(FeatureAutoDetectMimeType))
 	assert.True(t, c.hasFeature(FeatureEnableCRC64CheckUpload))
 	assert.True(t, c.hasFeature(FeatureEnableCRC64CheckDownload))
+	assert.False(t, c.hasFeature(FeatureEnableChunkedSigning))
+
+	// Enable FeatureEnableChunkedSigning
+	cfg.WithEnableChunkedSigning(true)
+	c = NewClient(cfg)
+	assert.True(t, c.hasFeature(FeatureEnableCRC64CheckUpload))
+	assert.True(t, c.hasFeature(FeatureEnableChunkedSigning))
 }
 
 func TestFeatureCorrectClockSkew(t *testing.T) {
@@ -1180,6 +1189,27 @@ func TestSinger(t *testing.T) {
 	assert.True(t, ok)
 }
 
+func TestDefaultCredentialsProvider(t *testing.T) {
+	cfg := NewConfig()
+	c := NewClient(cfg)
+	_, ok := c.options.CredentialsProvider.(*credentials.CredentialsProviderChain)
+	assert.True(t, ok)
+
+	cfg = NewConfig().WithCredentialsProvider(credentials.NewAnonymousCredentialsProvider())
+	c = NewClient(cfg)
+	_, ok = c.options.CredentialsProvider.(*credentials.AnonymousCredentialsProvider)
+	assert.True(t, ok)
+
+	t.Setenv("OSS_ACCESS_KEY_ID", "ak")
+	t.Setenv("OSS_ACCESS_KEY_SECRET", "sk")
+	t.Setenv("OSS_SESSION_TOKEN", "")
+	c = NewClient(NewConfig(), func(o *Options) { o.CredentialsProvider = nil })
+	cred, err := c.options.CredentialsProvider.GetCredentials(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, "ak", cred.AccessKeyID)
+	assert.Equal(t, "sk", cred.AccessKeySecret)
+}
+
 func TestRetryMaxAttempts(t *testing.T) {
 	cfg := NewConfig()
 	c := NewClient(cfg)
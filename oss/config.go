@@ -33,6 +33,7 @@ type Config struct {
 	HttpClient HTTPClient
 
 	// The credentials provider to use when signing requests.
+	// If not set, the default credentials provider chain is used, see credentials.NewDefaultCredentialsProviderChain.
 	CredentialsProvider credentials.CredentialsProvider
 
 	// Allows you to enable the client to use path-style addressing, i.e., https://oss-cn-hangzhou.aliyuncs.com/bucket/key.
@@ -108,6 +109,14 @@ type Config struct {
 	// Set this to `true` to disable this feature.
 	DisableDownloadCRC64Check *bool
 
+	// Sign the body of unknown length chunk by chunk, see FeatureEnableChunkedSigning.
+	// It only takes effect for the V4 signature in the header.
+	EnableChunkedSigning *bool
+
+	// The feature flags of the client, FeatureFlagsDefault if not set.
+	// DisableUploadCRC64Check, DisableDownloadCRC64Check and EnableChunkedSigning are applied on top of them.
+	FeatureFlags *FeatureFlagsType
+
 	// Additional signable headers.
 	AdditionalHeaders []string
 
@@ -125,6 +134,30 @@ type Config struct {
 
 	// Local address to bind to for outgoing connections.
 	BindAddress net.IP
+
+	// The middlewares applied to every operation of the client.
+	Middlewares []Middleware
+
+	// The tracer used to create a span for every operation and every http attempt.
+	Tracer Tracer
+
+	// The collector that receives the request, retry, bandwidth and transfer metrics.
+	MetricsCollector MetricsCollector
+
+	// The logger used instead of the one built from LogLevel and LogPrinter.
+	Logger Logger
+
+	// The limiter of the operations per second, it can be shared by several clients.
+	RequestRateLimiter *RequestRateLimiter
+
+	// The circuit breaker of the endpoint hosts, it can be shared by several clients.
+	CircuitBreaker *CircuitBreaker
+
+	// The endpoints with ordered failover, the primary one is used instead of Endpoint.
+	EndpointPool *EndpointPool
+
+	// The policy of the hedged read requests, no hedging if not set.
+	HedgingPolicy *HedgingPolicy
 }
 
 func NewConfig() *Config {
@@ -136,7 +169,13 @@ func (c Config) Copy() Config {
 	return cp
 }
 
+// LoadDefaultConfig loads the config from the profile files and the OSS_* environment variables, see LoadConfig.
+// If the profile files are invalid, only OSS_SDK_LOG_LEVEL is loaded, call LoadConfig to get the error.
 func LoadDefaultConfig() *Config {
+	if config, err := LoadConfig(); err == nil {
+		return config
+	}
+
 	config := &Config{}
 
 	// load from env
@@ -280,6 +319,16 @@ func (c *Config) WithDisableDownloadCRC64Check(value bool) *Config {
 	return c
 }
 
+func (c *Config) WithEnableChunkedSigning(value bool) *Config {
+	c.EnableChunkedSigning = Ptr(value)
+	return c
+}
+
+func (c *Config) WithFeatureFlags(value FeatureFlagsType) *Config {
+	c.FeatureFlags = Ptr(value)
+	return c
+}
+
 func (c *Config) WithAdditionalHeaders(value []string) *Config {
 	c.AdditionalHeaders = value
 	return c
@@ -309,3 +358,43 @@ func (c *Config) WithBindAddress(value net.IP) *Config {
 	c.BindAddress = value
 	return c
 }
+
+func (c *Config) WithMiddlewares(middlewares ...Middleware) *Config {
+	c.Middlewares = append(c.Middlewares, middlewares...)
+	return c
+}
+
+func (c *Config) WithTracer(tracer Tracer) *Config {
+	c.Tracer = tracer
+	return c
+}
+
+func (c *Config) WithMetricsCollector(collector MetricsCollector) *Config {
+	c.MetricsCollector = collector
+	return c
+}
+
+func (c *Config) WithLogger(logger Logger) *Config {
+	c.Logger = logger
+	return c
+}
+
+func (c *Config) WithRequestRateLimiter(limiter *RequestRateLimiter) *Config {
+	c.RequestRateLimiter = limiter
+	return c
+}
+
+func (c *Config) WithCircuitBreaker(cb *CircuitBreaker) *Config {
+	c.CircuitBreaker = cb
+	return c
+}
+
+func (c *Config) WithEndpointPool(pool *EndpointPool) *Config {
+	c.EndpointPool = pool
+	return c
+}
+
+func (c *Config) WithHedgingPolicy(policy *HedgingPolicy) *Config {
+	c.HedgingPolicy = policy
+	return c
+}
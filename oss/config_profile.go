@@ -0,0 +1,459 @@
+package oss
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/credentials"
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/retry"
+)
+
+const (
+	// The name of the profile, "default" if not set.
+	EnvProfile = "OSS_PROFILE"
+
+	// The path of the config file, ~/.alibabacloud/oss/config if not set.
+	EnvConfigFile = "OSS_CONFIG_FILE"
+
+	// The path of the credentials file, ~/.alibabacloud/oss/credentials if not set.
+	EnvCredentialsFile = "OSS_SHARED_CREDENTIALS_FILE"
+
+	DefaultProfileName = "default"
+)
+
+// LoadOptions specifies where LoadConfig reads the settings from.
+type LoadOptions struct {
+	// The name of the profile, OSS_PROFILE is used if not set.
+	Profile string
+
+	// The path of the config file, OSS_CONFIG_FILE is used if not set.
+	ConfigFile string
+
+	// The path of the credentials file, OSS_SHARED_CREDENTIALS_FILE is used if not set.
+	CredentialsFile string
+
+	// Do not read the OSS_* environment variables, except the ones that locate the files.
+	DisableEnv bool
+}
+
+// profileSetting is a setting that can be set in the profile files and by an environment variable.
+type profileSetting struct {
+	key string
+	env string
+	set func(cfg *Config, value string) error
+}
+
+var profileSettings = []profileSetting{
+	{"region", "OSS_REGION", func(c *Config, v string) error { c.Region = Ptr(v); return nil }},
+	{"endpoint", "OSS_ENDPOINT", func(c *Config, v string) error { c.Endpoint = Ptr(v); return nil }},
+	{"retry_max_attempts", "OSS_RETRY_MAX_ATTEMPTS", setProfileInt(func(c *Config, v int) { c.RetryMaxAttempts = Ptr(v) })},
+	{"connect_timeout", "OSS_CONNECT_TIMEOUT", setProfileDuration(func(c *Config, v time.Duration) { c.ConnectTimeout = Ptr(v) })},
+	{"readwrite_timeout", "OSS_READWRITE_TIMEOUT", setProfileDuration(func(c *Config, v time.Duration) { c.ReadWriteTimeout = Ptr(v) })},
+	{"max_connections", "OSS_MAX_CONNECTIONS", setProfileInt(func(c *Config, v int) { c.MaxConnections = Ptr(v) })},
+	{"signature_version", "OSS_SIGNATURE_VERSION", func(c *Config, v string) error {
+		switch strings.ToLower(v) {
+		case "v1", "1":
+			c.SignatureVersion = Ptr(SignatureVersionV1)
+		case "v4", "4":
+			c.SignatureVersion = Ptr(SignatureVersionV4)
+		default:
+			return fmt.Errorf("invalid signature version %q", v)
+		}
+		return nil
+	}},
+	{"use_path_style", "OSS_USE_PATH_STYLE", setProfileBool(func(c *Config, v bool) { c.UsePathStyle = Ptr(v) })},
+	{"use_cname", "OSS_USE_CNAME", setProfileBool(func(c *Config, v bool) { c.UseCName = Ptr(v) })},
+	{"use_internal_endpoint", "OSS_USE_INTERNAL_ENDPOINT", setProfileBool(func(c *Config, v bool) { c.UseInternalEndpoint = Ptr(v) })},
+	{"use_dualstack_endpoint", "OSS_USE_DUALSTACK_ENDPOINT", setProfileBool(func(c *Config, v bool) { c.UseDualStackEndpoint = Ptr(v) })},
+	{"use_accelerate_endpoint", "OSS_USE_ACCELERATE_ENDPOINT", setProfileBool(func(c *Config, v bool) { c.UseAccelerateEndpoint = Ptr(v) })},
+	{"disable_ssl", "OSS_DISABLE_SSL", setProfileBool(func(c *Config, v bool) { c.DisableSSL = Ptr(v) })},
+	{"insecure_skip_verify", "OSS_INSECURE_SKIP_VERIFY", setProfileBool(func(c *Config, v bool) { c.InsecureSkipVerify = Ptr(v) })},
+	{"enabled_redirect", "OSS_ENABLED_REDIRECT", setProfileBool(func(c *Config, v bool) { c.EnabledRedirect = Ptr(v) })},
+	{"disable_upload_crc64_check", "OSS_DISABLE_UPLOAD_CRC64_CHECK", setProfileBool(func(c *Config, v bool) { c.DisableUploadCRC64Check = Ptr(v) })},
+	{"disable_download_crc64_check", "OSS_DISABLE_DOWNLOAD_CRC64_CHECK", setProfileBool(func(c *Config, v bool) { c.DisableDownloadCRC64Check = Ptr(v) })},
+	{"proxy_host", "OSS_PROXY_HOST", func(c *Config, v string) error { c.ProxyHost = Ptr(v); return nil }},
+	{"user_agent", "OSS_USER_AGENT", func(c *Config, v string) error { c.UserAgent = Ptr(v); return nil }},
+	{"cloudbox_id", "OSS_CLOUDBOX_ID", func(c *Config, v string) error { c.CloudBoxId = Ptr(v); return nil }},
+	{"log_level", "OSS_SDK_LOG_LEVEL", func(c *Config, v string) error {
+		if level := ToLogLevel(v); level > LogOff {
+			c.LogLevel = Ptr(level)
+		}
+		return nil
+	}},
+	{"feature_flags", "OSS_FEATURE_FLAGS", func(c *Config, v string) error {
+		flags, err := parseProfileFeatureFlags(v)
+		if err != nil {
+			return err
+		}
+		c.FeatureFlags = Ptr(flags)
+		return nil
+	}},
+}
+
+var profileFeatureFlags = map[string]FeatureFlagsType{
+	"correct_clock_skew":          FeatureCorrectClockSkew,
+	"enable_md5":                  FeatureEnableMD5,
+	"auto_detect_mime_type":       FeatureAutoDetectMimeType,
+	"enable_crc64_check_upload":   FeatureEnableCRC64CheckUpload,
+	"enable_crc64_check_download": FeatureEnableCRC64CheckDownload,
+	"enable_chunked_signing":      FeatureEnableChunkedSigning,
+}
+
+// parseProfileFeatureFlags parses a comma-separated list of the flags, e.g. "enable_md5,-auto_detect_mime_type".
+// A flag is enabled, or disabled with the "-" prefix, on top of FeatureFlagsDefault.
+func parseProfileFeatureFlags(v string) (FeatureFlagsType, error) {
+	flags := FeatureFlagsDefault
+	for _, name := range strings.Split(v, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		disable := strings.HasPrefix(name, "-")
+		name = strings.TrimLeft(name, "+-")
+		flag, ok := profileFeatureFlags[name]
+		if !ok {
+			return 0, fmt.Errorf("invalid feature flag %q", name)
+		}
+		if disable {
+			flags &^= flag
+		} else {
+			flags |= flag
+		}
+	}
+	return flags, nil
+}
+
+// profileRetrySetting is a setting of the retryer, the retryer is built only if any of them is set.
+type profileRetrySetting struct {
+	key string
+	env string
+	set func(o *profileRetryOptions, value string) error
+}
+
+type profileRetryOptions struct {
+	mode string
+	fns  []func(*retry.AdaptiveOptions)
+}
+
+var profileRetrySettings = []profileRetrySetting{
+	{"retry_mode", "OSS_RETRY_MODE", func(o *profileRetryOptions, v string) error {
+		switch mode := strings.ToLower(v); mode {
+		case "standard", "adaptive":
+			o.mode = mode
+		default:
+			return fmt.Errorf("invalid retry mode %q", v)
+		}
+		return nil
+	}},
+	{"retry_max_backoff", "OSS_RETRY_MAX_BACKOFF", setProfileRetryDuration(func(o *retry.AdaptiveOptions, v time.Duration) { o.MaxBackoff = v })},
+	{"retry_base_delay", "OSS_RETRY_BASE_DELAY", setProfileRetryDuration(func(o *retry.AdaptiveOptions, v time.Duration) { o.BaseDelay = v })},
+	{"retry_quota", "OSS_RETRY_QUOTA", setProfileRetryInt(func(o *retry.AdaptiveOptions, v int) { o.RetryQuota = v })},
+	{"retry_cost", "OSS_RETRY_COST", setProfileRetryInt(func(o *retry.AdaptiveOptions, v int) { o.RetryCost = v })},
+	{"retry_timeout_cost", "OSS_RETRY_TIMEOUT_COST", setProfileRetryInt(func(o *retry.AdaptiveOptions, v int) { o.RetryTimeoutCost = v })},
+	{"retry_no_retry_increment", "OSS_RETRY_NO_RETRY_INCREMENT", setProfileRetryInt(func(o *retry.AdaptiveOptions, v int) { o.NoRetryIncrement = v })},
+	{"retry_non_idempotent_operations", "OSS_RETRY_NON_IDEMPOTENT_OPERATIONS", func(o *profileRetryOptions, v string) error {
+		var ops []string
+		for _, op := range strings.Split(v, ",") {
+			if op = strings.TrimSpace(op); op != "" {
+				ops = append(ops, op)
+			}
+		}
+		o.fns = append(o.fns, func(ao *retry.AdaptiveOptions) { ao.NonIdempotentOperations = ops })
+		return nil
+	}},
+	{"retry_disable_rate_limit", "OSS_RETRY_DISABLE_RATE_LIMIT", func(o *profileRetryOptions, v string) error {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+		o.fns = append(o.fns, func(ao *retry.AdaptiveOptions) { ao.DisableRateLimit = b })
+		return nil
+	}},
+}
+
+func setProfileRetryInt(fn func(*retry.AdaptiveOptions, int)) func(*profileRetryOptions, string) error {
+	return func(o *profileRetryOptions, v string) error {
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			return err
+		}
+		o.fns = append(o.fns, func(ao *retry.AdaptiveOptions) { fn(ao, i) })
+		return nil
+	}
+}
+
+func setProfileRetryDuration(fn func(*retry.AdaptiveOptions, time.Duration)) func(*profileRetryOptions, string) error {
+	return func(o *profileRetryOptions, v string) error {
+		d, err := parseProfileDuration(v)
+		if err != nil {
+			return err
+		}
+		o.fns = append(o.fns, func(ao *retry.AdaptiveOptions) { fn(ao, d) })
+		return nil
+	}
+}
+
+// retryer returns the retryer of the mode, standard if not set, or nil if no retry setting is set.
+// The adaptive settings are ignored by the standard retryer.
+func (o *profileRetryOptions) retryer(maxAttempts *int) retry.Retryer {
+	if o.mode == "" && len(o.fns) == 0 {
+		return nil
+	}
+	apply := func(ao *retry.AdaptiveOptions) {
+		if maxAttempts != nil {
+			ao.MaxAttempts = *maxAttempts
+		}
+		for _, fn := range o.fns {
+			fn(ao)
+		}
+	}
+	if o.mode == "adaptive" {
+		return retry.NewAdaptive(apply)
+	}
+	return retry.NewStandard(func(ro *retry.RetryOptions) {
+		ao := retry.AdaptiveOptions{RetryOptions: *ro}
+		apply(&ao)
+		*ro = ao.RetryOptions
+	})
+}
+
+func setProfileBool(fn func(*Config, bool)) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+		fn(c, b)
+		return nil
+	}
+}
+
+func setProfileInt(fn func(*Config, int)) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			return err
+		}
+		fn(c, i)
+		return nil
+	}
+}
+
+func setProfileDuration(fn func(*Config, time.Duration)) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		d, err := parseProfileDuration(v)
+		if err != nil {
+			return err
+		}
+		fn(c, d)
+		return nil
+	}
+}
+
+// parseProfileDuration accepts the seconds, or a duration string such as 1m30s.
+func parseProfileDuration(v string) (time.Duration, error) {
+	if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Duration(secs) * time.Second, nil
+	}
+	return time.ParseDuration(v)
+}
+
+// LoadConfig loads the config from the profile files and the OSS_* environment variables.
+//
+// The precedence, from the highest to the lowest, is:
+//  1. the values set by the Config.With* functions after loading
+//  2. the OSS_* environment variables
+//  3. the credentials file, only for the credentials
+//  4. the config file
+//  5. the defaults of the client
+//
+// The files are in the INI or the TOML format, a profile is a section named [name], [profile name] or [profile.name].
+// A missing file is ignored, and so is a missing profile unless it is set explicitly.
+func LoadConfig(optFns ...func(*LoadOptions)) (*Config, error) {
+	options := LoadOptions{}
+	for _, fn := range optFns {
+		fn(&options)
+	}
+
+	profile := options.Profile
+	explicit := profile != ""
+	if profile == "" {
+		profile = os.Getenv(EnvProfile)
+		explicit = profile != ""
+	}
+	if profile == "" {
+		profile = DefaultProfileName
+	}
+
+	configFile := firstNonEmpty(options.ConfigFile, os.Getenv(EnvConfigFile), defaultProfileFilePath("config"))
+	credentialsFile := firstNonEmpty(options.CredentialsFile, os.Getenv(EnvCredentialsFile), defaultProfileFilePath("credentials"))
+
+	values := map[string]string{}
+	found := false
+	for _, file := range []string{configFile, credentialsFile} {
+		if file == "" {
+			continue
+		}
+		sections, err := parseProfileFile(file)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		if section, ok := sections[profile]; ok {
+			found = true
+			for k, v := range section {
+				values[k] = v
+			}
+		}
+	}
+
+	if explicit && !found {
+		return nil, fmt.Errorf("profile %q is not found in %s or %s", profile, configFile, credentialsFile)
+	}
+
+	lookup := func(key, env string) string {
+		if !options.DisableEnv {
+			if ev := os.Getenv(env); ev != "" {
+				return ev
+			}
+		}
+		return values[key]
+	}
+
+	cfg := NewConfig()
+	for _, s := range profileSettings {
+		v := lookup(s.key, s.env)
+		if v == "" {
+			continue
+		}
+		if err := s.set(cfg, v); err != nil {
+			return nil, fmt.Errorf("invalid %s in profile %q, %v", s.key, profile, err)
+		}
+	}
+
+	retryOptions := &profileRetryOptions{}
+	for _, s := range profileRetrySettings {
+		v := lookup(s.key, s.env)
+		if v == "" {
+			continue
+		}
+		if err := s.set(retryOptions, v); err != nil {
+			return nil, fmt.Errorf("invalid %s in profile %q, %v", s.key, profile, err)
+		}
+	}
+	cfg.Retryer = retryOptions.retryer(cfg.RetryMaxAttempts)
+
+	cfg.CredentialsProvider = resolveProfileCredentials(values, options.DisableEnv)
+
+	return cfg, nil
+}
+
+// resolveProfileCredentials returns the first credentials source that is configured:
+// the OSS_ACCESS_KEY_ID and OSS_ACCESS_KEY_SECRET environment variables,
+// the access_key_id and access_key_secret of the profile, the credential_process of the profile,
+// or the ecs_ram_role of the profile.
+func resolveProfileCredentials(values map[string]string, disableEnv bool) credentials.CredentialsProvider {
+	if !disableEnv && os.Getenv("OSS_ACCESS_KEY_ID") != "" && os.Getenv("OSS_ACCESS_KEY_SECRET") != "" {
+		return credentials.NewEnvironmentVariableCredentialsProvider()
+	}
+
+	if id, secret := values["access_key_id"], values["access_key_secret"]; id != "" && secret != "" {
+		return credentials.NewStaticCredentialsProvider(id, secret, values["security_token"])
+	}
+
+	if command := values["credential_process"]; command != "" {
+		return credentials.NewProcessCredentialsProvider(command)
+	}
+
+	if role, ok := values["ecs_ram_role"]; ok {
+		return credentials.NewEcsRoleCredentialsProvider(credentials.EcsRamRole(role))
+	}
+
+	return nil
+}
+
+func defaultProfileFilePath(name string) string {
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		return ""
+	}
+	return filepath.Join(home, ".alibabacloud", "oss", name)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// parseProfileFile parses an INI or a simple TOML file into the sections.
+// The keys are lower-cased, the quotes around the values are removed.
+func parseProfileFile(path string) (map[string]map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sections := map[string]map[string]string{}
+	var section map[string]string
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+
+		if line[0] == '[' {
+			end := strings.IndexByte(line, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("%s:%d, invalid section %q", path, lineNum, line)
+			}
+			name := strings.TrimSpace(line[1:end])
+			if strings.HasPrefix(name, "profile ") || strings.HasPrefix(name, "profile.") {
+				name = strings.TrimSpace(name[len("profile "):])
+			}
+			name = strings.Trim(name, `"'`)
+			if _, ok := sections[name]; !ok {
+				sections[name] = map[string]string{}
+			}
+			section = sections[name]
+			continue
+		}
+
+		i := strings.IndexByte(line, '=')
+		if i < 0 {
+			return nil, fmt.Errorf("%s:%d, invalid line %q", path, lineNum, line)
+		}
+		if section == nil {
+			// the keys before the first section are ignored
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(line[:i]))
+		section[key] = parseProfileValue(strings.TrimSpace(line[i+1:]))
+	}
+	return sections, scanner.Err()
+}
+
+func parseProfileValue(v string) string {
+	if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') {
+		if end := strings.IndexByte(v[1:], v[0]); end >= 0 {
+			return v[1 : end+1]
+		}
+	}
+	// inline comment
+	if i := strings.Index(v, " #"); i >= 0 {
+		v = strings.TrimSpace(v[:i])
+	}
+	return v
+}
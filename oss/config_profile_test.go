@@ -0,0 +1,299 @@
+package oss
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/credentials"
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/retry"
+	"github.com/stretchr/testify/assert"
+)
+
+func testClearProfileEnv(t *testing.T) {
+	t.Setenv(EnvProfile, "")
+	t.Setenv(EnvConfigFile, "")
+	t.Setenv(EnvCredentialsFile, "")
+	t.Setenv("OSS_ACCESS_KEY_ID", "")
+	t.Setenv("OSS_ACCESS_KEY_SECRET", "")
+	for _, s := range profileSettings {
+		t.Setenv(s.env, "")
+	}
+	for _, s := range profileRetrySettings {
+		t.Setenv(s.env, "")
+	}
+}
+
+func testWriteFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	assert.Nil(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestParseProfileFile(t *testing.T) {
+	path := testWriteFile(t, "config", `
+# comment
+ignored = value
+
+[default]
+region = cn-hangzhou
+endpoint = "oss-cn-hangzhou.aliyuncs.com" # the public endpoint
+; comment
+Use_Path_Style = true
+
+[profile dev]
+region = 'cn-shanghai'
+
+[profile.test]
+region = cn-beijing
+`)
+	sections, err := parseProfileFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{
+		"region":         "cn-hangzhou",
+		"endpoint":       "oss-cn-hangzhou.aliyuncs.com",
+		"use_path_style": "true",
+	}, sections["default"])
+	assert.Equal(t, "cn-shanghai", sections["dev"]["region"])
+	assert.Equal(t, "cn-beijing", sections["test"]["region"])
+
+	_, err = parseProfileFile(testWriteFile(t, "bad", "[default\nregion = cn-hangzhou"))
+	assert.NotNil(t, err)
+	_, err = parseProfileFile(testWriteFile(t, "bad", "[default]\nregion"))
+	assert.NotNil(t, err)
+}
+
+func TestLoadConfig_Profile(t *testing.T) {
+	testClearProfileEnv(t)
+	configFile := testWriteFile(t, "config", `
+[default]
+region = cn-hangzhou
+connect_timeout = 5
+readwrite_timeout = 1m
+retry_max_attempts = 5
+signature_version = v1
+use_internal_endpoint = true
+disable_upload_crc64_check = true
+access_key_id = config-ak
+access_key_secret = config-sk
+
+[profile dev]
+region = cn-shanghai
+endpoint = oss-cn-shanghai.aliyuncs.com
+credential_process = echo
+`)
+	credentialsFile := testWriteFile(t, "credentials", `
+[default]
+access_key_id = ak
+access_key_secret = sk
+security_token = token
+`)
+	t.Setenv(EnvConfigFile, configFile)
+	t.Setenv(EnvCredentialsFile, credentialsFile)
+
+	cfg, err := LoadConfig()
+	assert.Nil(t, err)
+	assert.Equal(t, "cn-hangzhou", ToString(cfg.Region))
+	assert.Nil(t, cfg.Endpoint)
+	assert.Equal(t, 5*time.Second, *cfg.ConnectTimeout)
+	assert.Equal(t, time.Minute, *cfg.ReadWriteTimeout)
+	assert.Equal(t, 5, ToInt(cfg.RetryMaxAttempts))
+	assert.Equal(t, SignatureVersionV1, *cfg.SignatureVersion)
+	assert.True(t, ToBool(cfg.UseInternalEndpoint))
+	assert.True(t, ToBool(cfg.DisableUploadCRC64Check))
+	assert.Nil(t, cfg.DisableDownloadCRC64Check)
+
+	// the credentials file takes precedence
+	cred, err := cfg.CredentialsProvider.GetCredentials(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, "ak", cred.AccessKeyID)
+	assert.Equal(t, "sk", cred.AccessKeySecret)
+	assert.Equal(t, "token", cred.SecurityToken)
+
+	// env vars take precedence
+	t.Setenv("OSS_REGION", "cn-beijing")
+	t.Setenv("OSS_SIGNATURE_VERSION", "v4")
+	t.Setenv("OSS_ACCESS_KEY_ID", "env-ak")
+	t.Setenv("OSS_ACCESS_KEY_SECRET", "env-sk")
+	cfg, err = LoadConfig()
+	assert.Nil(t, err)
+	assert.Equal(t, "cn-beijing", ToString(cfg.Region))
+	assert.Equal(t, SignatureVersionV4, *cfg.SignatureVersion)
+	cred, err = cfg.CredentialsProvider.GetCredentials(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, "env-ak", cred.AccessKeyID)
+
+	cfg, err = LoadConfig(func(o *LoadOptions) { o.DisableEnv = true })
+	assert.Nil(t, err)
+	assert.Equal(t, "cn-hangzhou", ToString(cfg.Region))
+
+	// the profile selected by the env var
+	t.Setenv("OSS_REGION", "")
+	t.Setenv("OSS_SIGNATURE_VERSION", "")
+	t.Setenv("OSS_ACCESS_KEY_ID", "")
+	t.Setenv(EnvProfile, "dev")
+	cfg, err = LoadConfig()
+	assert.Nil(t, err)
+	assert.Equal(t, "cn-shanghai", ToString(cfg.Region))
+	assert.Equal(t, "oss-cn-shanghai.aliyuncs.com", ToString(cfg.Endpoint))
+	assert.Nil(t, cfg.ConnectTimeout)
+	_, ok := cfg.CredentialsProvider.(*credentials.ProcessCredentialsProvider)
+	assert.True(t, ok)
+
+	// the options take precedence
+	cfg, err = LoadConfig(func(o *LoadOptions) { o.Profile = "default" })
+	assert.Nil(t, err)
+	assert.Equal(t, "cn-hangzhou", ToString(cfg.Region))
+
+	// an explicit profile must exist
+	_, err = LoadConfig(func(o *LoadOptions) { o.Profile = "unknown" })
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "unknown")
+}
+
+func TestLoadConfig_Invalid(t *testing.T) {
+	testClearProfileEnv(t)
+	t.Setenv(EnvConfigFile, testWriteFile(t, "config", "[default]\nconnect_timeout = abc\n"))
+	t.Setenv(EnvCredentialsFile, filepath.Join(t.TempDir(), "not-exist"))
+	t.Setenv("OSS_SDK_LOG_LEVEL", "info")
+
+	_, err := LoadConfig()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "connect_timeout")
+
+	// falls back to the env vars
+	cfg := LoadDefaultConfig()
+	assert.Nil(t, cfg.ConnectTimeout)
+	assert.Equal(t, LogInfo, ToInt(cfg.LogLevel))
+
+	t.Setenv(EnvConfigFile, testWriteFile(t, "config", "[default]\nsignature_version = v2\n"))
+	_, err = LoadConfig()
+	assert.NotNil(t, err)
+
+	// no files, no profile
+	t.Setenv(EnvConfigFile, filepath.Join(t.TempDir(), "not-exist"))
+	t.Setenv("OSS_USE_PATH_STYLE", "true")
+	t.Setenv("OSS_ENDPOINT", "http://127.0.0.1:8080")
+	cfg, err = LoadConfig()
+	assert.Nil(t, err)
+	assert.True(t, ToBool(cfg.UsePathStyle))
+	assert.Equal(t, "http://127.0.0.1:8080", ToString(cfg.Endpoint))
+	assert.Nil(t, cfg.CredentialsProvider)
+}
+
+func TestLoadConfig_EcsRamRole(t *testing.T) {
+	testClearProfileEnv(t)
+	t.Setenv(EnvConfigFile, testWriteFile(t, "config", "[default]\necs_ram_role = role\n"))
+	t.Setenv(EnvCredentialsFile, filepath.Join(t.TempDir(), "not-exist"))
+	cfg, err := LoadConfig()
+	assert.Nil(t, err)
+	assert.NotNil(t, cfg.CredentialsProvider)
+}
+
+func TestLoadConfig_Retry(t *testing.T) {
+	testClearProfileEnv(t)
+	t.Setenv(EnvCredentialsFile, filepath.Join(t.TempDir(), "not-exist"))
+	t.Setenv(EnvConfigFile, testWriteFile(t, "config", `
+[default]
+retry_max_attempts = 5
+retry_mode = adaptive
+retry_max_backoff = 30s
+retry_base_delay = 100ms
+retry_quota = 0
+retry_disable_rate_limit = true
+retry_non_idempotent_operations = AppendObject, PutObject
+
+[profile standard]
+retry_base_delay = 1
+`))
+
+	cfg, err := LoadConfig()
+	assert.Nil(t, err)
+	r, ok := cfg.Retryer.(*retry.Adaptive)
+	assert.True(t, ok)
+	assert.Equal(t, 5, r.MaxAttempts())
+	// no quota for the retries
+	_, err = r.RetryDelay(2, errors.New("error"))
+	assert.Equal(t, retry.ErrRetryQuotaExceeded, err)
+	// the rate limit is disabled
+	for i := 0; i < 10; i++ {
+		r.AfterAttempt(retry.Attempt{OpName: "GetObject", Number: 1}, &ServiceError{StatusCode: 503, Code: "SlowDown"})
+		assert.Nil(t, r.BeforeAttempt(context.TODO(), retry.Attempt{OpName: "GetObject", Number: 1}))
+	}
+	serr := &ServiceError{StatusCode: 500}
+	assert.True(t, r.IsAttemptRetryable(retry.Attempt{OpName: "CompleteMultipartUpload"}, serr))
+	assert.False(t, r.IsAttemptRetryable(retry.Attempt{OpName: "PutObject"}, serr))
+
+	// the env vars take precedence
+	t.Setenv("OSS_RETRY_MODE", "standard")
+	t.Setenv("OSS_RETRY_MAX_ATTEMPTS", "2")
+	cfg, err = LoadConfig()
+	assert.Nil(t, err)
+	s, ok := cfg.Retryer.(*retry.Standard)
+	assert.True(t, ok)
+	assert.Equal(t, 2, s.MaxAttempts())
+	t.Setenv("OSS_RETRY_MODE", "")
+	t.Setenv("OSS_RETRY_MAX_ATTEMPTS", "")
+
+	// standard by default
+	cfg, err = LoadConfig(func(o *LoadOptions) { o.Profile = "standard" })
+	assert.Nil(t, err)
+	s, ok = cfg.Retryer.(*retry.Standard)
+	assert.True(t, ok)
+	assert.Equal(t, retry.DefaultMaxAttempts, s.MaxAttempts())
+
+	// no retry setting, the retryer of the client is used
+	t.Setenv(EnvConfigFile, testWriteFile(t, "config", "[default]\nretry_max_attempts = 4\n"))
+	cfg, err = LoadConfig()
+	assert.Nil(t, err)
+	assert.Nil(t, cfg.Retryer)
+	assert.Equal(t, 4, ToInt(cfg.RetryMaxAttempts))
+
+	t.Setenv("OSS_RETRY_MODE", "unknown")
+	_, err = LoadConfig()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "retry_mode")
+	t.Setenv("OSS_RETRY_MODE", "")
+
+	t.Setenv("OSS_RETRY_QUOTA", "abc")
+	_, err = LoadConfig()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "retry_quota")
+}
+
+func TestLoadConfig_FeatureFlags(t *testing.T) {
+	testClearProfileEnv(t)
+	t.Setenv(EnvCredentialsFile, filepath.Join(t.TempDir(), "not-exist"))
+	t.Setenv(EnvConfigFile, testWriteFile(t, "config", `
+[default]
+region = cn-hangzhou
+feature_flags = enable_md5, -auto_detect_mime_type
+disable_download_crc64_check = true
+`))
+
+	cfg, err := LoadConfig()
+	assert.Nil(t, err)
+	assert.Equal(t, (FeatureFlagsDefault|FeatureEnableMD5)&^FeatureAutoDetectMimeType, *cfg.FeatureFlags)
+	client := NewClient(cfg)
+	assert.Equal(t, FeatureCorrectClockSkew|FeatureEnableMD5|FeatureEnableCRC64CheckUpload, client.options.FeatureFlags)
+
+	t.Setenv("OSS_FEATURE_FLAGS", "-correct_clock_skew")
+	cfg, err = LoadConfig()
+	assert.Nil(t, err)
+	assert.Equal(t, FeatureFlagsDefault&^FeatureCorrectClockSkew, *cfg.FeatureFlags)
+
+	t.Setenv("OSS_FEATURE_FLAGS", "+enable_md5,unknown")
+	_, err = LoadConfig()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "feature_flags")
+
+	// the defaults of the client
+	t.Setenv("OSS_FEATURE_FLAGS", "")
+	cfg, err = LoadConfig(func(o *LoadOptions) { o.DisableEnv = true })
+	assert.Nil(t, err)
+	cfg.FeatureFlags = nil
+	assert.Equal(t, FeatureFlagsDefault&^FeatureEnableCRC64CheckDownload, NewClient(cfg).options.FeatureFlags)
+}
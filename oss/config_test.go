@@ -42,6 +42,7 @@ func TestConfigDefault(t *testing.T) {
 
 	assert.Nil(t, config.DisableUploadCRC64Check)
 	assert.Nil(t, config.DisableDownloadCRC64Check)
+	assert.Nil(t, config.EnableChunkedSigning)
 
 	assert.Nil(t, config.AdditionalHeaders)
 	assert.Nil(t, config.UserAgent)
@@ -131,6 +132,9 @@ func TestConfigDefault(t *testing.T) {
 	config.WithDisableDownloadCRC64Check(true)
 	assert.Equal(t, true, *config.DisableDownloadCRC64Check)
 
+	config.WithEnableChunkedSigning(true)
+	assert.Equal(t, true, *config.EnableChunkedSigning)
+
 	config.WithAdditionalHeaders([]string{"content-length"})
 	assert.NotNil(t, config.AdditionalHeaders)
 	assert.Len(t, config.AdditionalHeaders, 1)
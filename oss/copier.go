@@ -72,6 +72,8 @@ type Copier struct {
 	options      CopierOptions
 	client       CopyAPIClient
 	featureFlags FeatureFlagsType
+	tracer       Tracer
+	metrics      MetricsCollector
 }
 
 // NewCopier creates a new Copier instance to copy objects.
@@ -95,6 +97,8 @@ func NewCopier(api CopyAPIClient, optFns ...func(*CopierOptions)) *Copier {
 	c := &Copier{
 		client:  api,
 		options: options,
+		tracer:  getTracer(api),
+		metrics: getMetricsCollector(api),
 	}
 
 	//Get Client Feature
@@ -136,7 +140,11 @@ func (m *CopyError) Unwrap() error {
 	return m.Err
 }
 
-func (c *Copier) Copy(ctx context.Context, request *CopyObjectRequest, optFns ...func(*CopierOptions)) (*CopyResult, error) {
+func (c *Copier) Copy(ctx context.Context, request *CopyObjectRequest, optFns ...func(*CopierOptions)) (result *CopyResult, err error) {
+	ctx = ensureCorrelationId(ctx)
+	ctx, span := startSpan(ctx, c.tracer, "Copier.Copy", copySpanAttributes(request)...)
+	defer func() { endSpan(span, err) }()
+
 	// Copier wrapper
 	delegate, err := c.newDelegate(ctx, request, optFns...)
 	if err != nil {
@@ -411,6 +419,7 @@ func (d *copierDelegate) multiCopy() (*CopyResult, error) {
 				break
 			}
 			if getErrFn() == nil {
+				done := trackPartInFlight(d.base.metrics, "copy", d.request.Bucket)
 				upResult, err := d.base.client.UploadPartCopy(
 					d.context,
 					&UploadPartCopyRequest{
@@ -424,6 +433,7 @@ func (d *copierDelegate) multiCopy() (*CopyResult, error) {
 						Range:           Ptr(data.sourceRange),
 						RequestPayer:    d.request.RequestPayer,
 					}, mpcClientOptions...)
+				done()
 				//fmt.Printf("UploadPart result: %#v, %#v\n", upResult, err)
 				if err == nil {
 					mu.Lock()
@@ -0,0 +1,180 @@
+package credentials
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The sources of the default credentials provider chain.
+const (
+	CredentialsSourceEnvironment = "environment"
+	CredentialsSourceProcess     = "process"
+	CredentialsSourceEcsRamRole  = "ecs_ram_role"
+)
+
+const (
+	// The command of the process source.
+	EnvCredentialProcess = "OSS_CREDENTIAL_PROCESS"
+
+	// Skip the ecs_ram_role source if true, e.g. outside of ECS.
+	EnvEcsMetadataDisabled = "ALIBABA_CLOUD_ECS_METADATA_DISABLED"
+)
+
+// The chain returns the last failure during the backoff instead of trying all the sources again.
+const defaultChainFailureBackoff = 5 * time.Second
+
+var defaultCredentialsSources = []string{
+	CredentialsSourceEnvironment,
+	CredentialsSourceProcess,
+	CredentialsSourceEcsRamRole,
+}
+
+type DefaultCredentialsProviderChainOptions struct {
+	// The sources in the order they are tried, environment, process and ecs_ram_role if not set.
+	Sources []string
+
+	// The command of the process source, OSS_CREDENTIAL_PROCESS is used if not set.
+	ProcessCommand string
+
+	// The options of the ecs_ram_role source.
+	// The chain uses 1 second timeout and no retry by default, so that it fails fast outside of ECS.
+	EcsRoleOptions []func(*EcsRoleCredentialsProviderOptions)
+
+	// The duration the last failure is returned without trying the sources again, 5 seconds if not set.
+	FailureBackoff time.Duration
+}
+
+// CredentialsProviderChain tries the providers in order, and uses the first one that returns the credentials from then on.
+type CredentialsProviderChain struct {
+	names     []string
+	providers []func() (CredentialsProvider, error)
+
+	mu       sync.Mutex
+	source   string
+	provider CredentialsProvider
+
+	backoff time.Duration
+	err     error
+	retryAt time.Time
+}
+
+// NewDefaultCredentialsProviderChain returns a chain of the environment variable, the process
+// and the ECS RAM role credentials providers.
+func NewDefaultCredentialsProviderChain(optFns ...func(*DefaultCredentialsProviderChainOptions)) CredentialsProvider {
+	options := DefaultCredentialsProviderChainOptions{
+		Sources:        defaultCredentialsSources,
+		FailureBackoff: defaultChainFailureBackoff,
+	}
+	for _, fn := range optFns {
+		fn(&options)
+	}
+
+	chain := &CredentialsProviderChain{backoff: options.FailureBackoff}
+	for _, source := range options.Sources {
+		var provider func() (CredentialsProvider, error)
+		switch source {
+		case CredentialsSourceEnvironment:
+			provider = func() (CredentialsProvider, error) {
+				return NewEnvironmentVariableCredentialsProvider(), nil
+			}
+		case CredentialsSourceProcess:
+			processCommand := options.ProcessCommand
+			provider = func() (CredentialsProvider, error) {
+				command := processCommand
+				if command == "" {
+					command = os.Getenv(EnvCredentialProcess)
+				}
+				if command == "" {
+					return nil, fmt.Errorf("%s is not set", EnvCredentialProcess)
+				}
+				return NewProcessCredentialsProvider(command), nil
+			}
+		case CredentialsSourceEcsRamRole:
+			ecsOptFns := append([]func(*EcsRoleCredentialsProviderOptions){
+				func(o *EcsRoleCredentialsProviderOptions) {
+					o.Timeout = time.Second
+					o.Retries = 1
+				},
+			}, options.EcsRoleOptions...)
+			provider = func() (CredentialsProvider, error) {
+				if disabled, _ := strconv.ParseBool(os.Getenv(EnvEcsMetadataDisabled)); disabled {
+					return nil, fmt.Errorf("disabled by %s", EnvEcsMetadataDisabled)
+				}
+				return NewEcsRoleCredentialsProvider(ecsOptFns...), nil
+			}
+		default:
+			provider = func() (CredentialsProvider, error) {
+				return nil, fmt.Errorf("unknown source")
+			}
+		}
+		chain.names = append(chain.names, source)
+		chain.providers = append(chain.providers, provider)
+	}
+	return chain
+}
+
+// Source returns the name of the source that provides the credentials, or empty if none succeeded yet.
+func (c *CredentialsProviderChain) Source() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.source
+}
+
+func (c *CredentialsProviderChain) GetCredentials(ctx context.Context) (Credentials, error) {
+	c.mu.Lock()
+	provider, source := c.provider, c.source
+	c.mu.Unlock()
+
+	if provider != nil {
+		cred, err := provider.GetCredentials(ctx)
+		if err != nil {
+			return cred, fmt.Errorf("%s: %w", source, err)
+		}
+		return cred, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.provider != nil {
+		return c.provider.GetCredentials(ctx)
+	}
+	if c.err != nil && time.Now().Before(c.retryAt) {
+		return Credentials{}, c.err
+	}
+
+	var reasons []string
+	for i, fn := range c.providers {
+		provider, err := fn()
+		if err == nil {
+			var cred Credentials
+			if cred, err = provider.GetCredentials(ctx); err == nil && !cred.HasKeys() {
+				err = fmt.Errorf("access key id or access key secret is empty")
+			}
+			if err == nil {
+				c.provider, c.source = provider, c.names[i]
+				return cred, nil
+			}
+		}
+		reasons = append(reasons, fmt.Sprintf("%s: %v", c.names[i], err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	var err error
+	if len(reasons) == 0 {
+		err = fmt.Errorf("no valid credentials source in the chain")
+	} else {
+		err = fmt.Errorf("no valid credentials found in the chain, %s", strings.Join(reasons, "; "))
+	}
+	// a canceled call says nothing about the sources
+	if ctx.Err() == nil {
+		c.err, c.retryAt = err, time.Now().Add(c.backoff)
+	}
+	return Credentials{}, err
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"runtime"
 	"sync/atomic"
@@ -841,3 +842,709 @@ func newStubEcsRoleCredentialsProvider(optFns ...func(*stubEcsRoleCredentialsPro
 	}))
 	return provider
 }
+
+func testSetupStsMockServer(t *testing.T, expiration time.Time, calls *int32, lastForm *atomic.Value) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		assert.Equal(t, "POST", r.Method)
+		assert.Nil(t, r.ParseForm())
+		form := r.PostForm
+		lastForm.Store(form)
+		signature := form.Get("Signature")
+		form.Del("Signature")
+		if form.Get("AccessKeyId") != "ak" || signature != stsSignature("POST", form, "sk") {
+			w.WriteHeader(400)
+			fmt.Fprint(w, `{"RequestId":"id-1234","Code":"SignatureDoesNotMatch","Message":"Specified signature is not matched with our calculation."}`)
+			return
+		}
+		if form.Get("Action") != "AssumeRole" || form.Get("RoleArn") != "acs:ram::123456:role/test" {
+			w.WriteHeader(404)
+			fmt.Fprint(w, `{"RequestId":"id-1234","Code":"EntityNotExist.Role","Message":"The role not exists."}`)
+			return
+		}
+		fmt.Fprintf(w, `{"RequestId":"id-1234","Credentials":{"AccessKeyId":"STS.ak","AccessKeySecret":"STS.sk","SecurityToken":"STS.token","Expiration":"%s"}}`,
+			expiration.UTC().Format("2006-01-02T15:04:05Z"))
+	}))
+}
+
+func TestAssumeRoleCredentialsProvider(t *testing.T) {
+	var calls int32
+	var lastForm atomic.Value
+	expiration := time.Now().Add(time.Hour)
+	server := testSetupStsMockServer(t, expiration, &calls, &lastForm)
+	defer server.Close()
+
+	provider := NewAssumeRoleCredentialsProviderWithoutRefresh(NewStaticCredentialsProvider("ak", "sk", "token"), "acs:ram::123456:role/test",
+		func(o *AssumeRoleCredentialsProviderOptions) {
+			o.Endpoint = server.URL
+			o.RoleSessionName = "session"
+			o.ExternalId = "external-id"
+			o.Policy = `{"Version":"1","Statement":[{"Effect":"Allow","Action":"oss:GetObject","Resource":"*"}]}`
+			o.DurationSeconds = 900
+		})
+	cred, err := provider.GetCredentials(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, "STS.ak", cred.AccessKeyID)
+	assert.Equal(t, "STS.sk", cred.AccessKeySecret)
+	assert.Equal(t, "STS.token", cred.SecurityToken)
+	assert.Equal(t, expiration.UTC().Format("2006-01-02T15:04:05Z"), cred.Expires.Format("2006-01-02T15:04:05Z"))
+	form := lastForm.Load().(url.Values)
+	assert.Equal(t, "session", form.Get("RoleSessionName"))
+	assert.Equal(t, "external-id", form.Get("ExternalId"))
+	assert.Contains(t, form.Get("Policy"), "oss:GetObject")
+	assert.Equal(t, "900", form.Get("DurationSeconds"))
+	assert.Equal(t, "token", form.Get("SecurityToken"))
+	assert.Equal(t, "JSON", form.Get("Format"))
+
+	// default options
+	p := NewAssumeRoleCredentialsProviderWithoutRefresh(NewStaticCredentialsProvider("ak", "sk"), "acs:ram::123456:role/test").(*assumeRoleCredentialsProvider)
+	assert.Equal(t, "https://sts.aliyuncs.com", p.client.endpoint)
+	assert.Equal(t, 3600, p.durationSeconds)
+	assert.Contains(t, p.roleSessionName, "oss-go-sdk-v2-")
+
+	// with refresh
+	atomic.StoreInt32(&calls, 0)
+	provider = NewAssumeRoleCredentialsProvider(NewStaticCredentialsProvider("ak", "sk"), "acs:ram::123456:role/test",
+		func(o *AssumeRoleCredentialsProviderOptions) {
+			o.Endpoint = server.URL
+		})
+	_, ok := provider.(*CredentialsFetcherProvider)
+	assert.True(t, ok)
+	for i := 0; i < 3; i++ {
+		cred, err = provider.GetCredentials(context.TODO())
+		assert.Nil(t, err)
+		assert.Equal(t, "STS.ak", cred.AccessKeyID)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// the sts errors
+	provider = NewAssumeRoleCredentialsProviderWithoutRefresh(NewStaticCredentialsProvider("ak", "invalid"), "acs:ram::123456:role/test",
+		func(o *AssumeRoleCredentialsProviderOptions) {
+			o.Endpoint = server.URL
+		})
+	_, err = provider.GetCredentials(context.TODO())
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "StatusCode:400")
+	assert.Contains(t, err.Error(), "SignatureDoesNotMatch")
+	assert.Contains(t, err.Error(), "id-1234")
+
+	provider = NewAssumeRoleCredentialsProviderWithoutRefresh(NewStaticCredentialsProvider("ak", "sk"), "acs:ram::123456:role/unknown",
+		func(o *AssumeRoleCredentialsProviderOptions) {
+			o.Endpoint = server.URL
+		})
+	_, err = provider.GetCredentials(context.TODO())
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "EntityNotExist.Role")
+
+	// the invalid inputs
+	provider = NewAssumeRoleCredentialsProviderWithoutRefresh(NewAnonymousCredentialsProvider(), "acs:ram::123456:role/test")
+	_, err = provider.GetCredentials(context.TODO())
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "base credentials are empty")
+
+	provider = NewAssumeRoleCredentialsProviderWithoutRefresh(NewStaticCredentialsProvider("ak", "sk"), "")
+	_, err = provider.GetCredentials(context.TODO())
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "role arn")
+
+	provider = NewAssumeRoleCredentialsProviderWithoutRefresh(nil, "acs:ram::123456:role/test")
+	_, err = provider.GetCredentials(context.TODO())
+	assert.NotNil(t, err)
+}
+
+func TestStsSignature(t *testing.T) {
+	// the example of the RPC style signature
+	query := url.Values{}
+	query.Set("Action", "DescribeRegions")
+	query.Set("Format", "XML")
+	query.Set("Version", "2014-05-26")
+	query.Set("AccessKeyId", "testid")
+	query.Set("SignatureMethod", "HMAC-SHA1")
+	query.Set("SignatureVersion", "1.0")
+	query.Set("SignatureNonce", "3ee8c1b8-83d3-44af-a94f-4e0ad82fd6cf")
+	query.Set("Timestamp", "2016-02-23T12:46:24Z")
+	assert.Equal(t, "OLeaidS1JvxuMvnyHOwuJ+uX5qY=", stsSignature("GET", query, "testsecret"))
+	assert.Equal(t, "a%20b%2A~%2F%3D", stsPercentEncode("a b*~/="))
+}
+
+func TestOIDCCredentialsProvider(t *testing.T) {
+	var calls int32
+	var tokens []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		assert.Nil(t, r.ParseForm())
+		assert.Equal(t, "AssumeRoleWithOIDC", r.PostForm.Get("Action"))
+		assert.Equal(t, "", r.PostForm.Get("Signature"))
+		assert.Equal(t, "", r.PostForm.Get("AccessKeyId"))
+		if r.PostForm.Get("RoleArn") != "acs:ram::123456:role/test" ||
+			r.PostForm.Get("OIDCProviderArn") != "acs:ram::123456:oidc-provider/ack-rrsa" {
+			w.WriteHeader(400)
+			fmt.Fprint(w, `{"RequestId":"id-1234","Code":"InvalidParameter.OIDCProviderArn","Message":"invalid"}`)
+			return
+		}
+		tokens = append(tokens, r.PostForm.Get("OIDCToken"))
+		assert.Equal(t, "session", r.PostForm.Get("RoleSessionName"))
+		fmt.Fprintf(w, `{"RequestId":"id-1234","Credentials":{"AccessKeyId":"STS.ak","AccessKeySecret":"STS.sk","SecurityToken":"STS.token","Expiration":"%s"}}`,
+			time.Now().Add(time.Hour).UTC().Format("2006-01-02T15:04:05Z"))
+	}))
+	defer server.Close()
+
+	tokenFile := t.TempDir() + "/token"
+	assert.Nil(t, os.WriteFile(tokenFile, []byte("token-1\n"), 0600))
+
+	t.Setenv(EnvRoleArn, "acs:ram::123456:role/test")
+	t.Setenv(EnvOIDCProviderArn, "acs:ram::123456:oidc-provider/ack-rrsa")
+	t.Setenv(EnvOIDCTokenFile, tokenFile)
+	t.Setenv(EnvRoleSessionName, "session")
+
+	provider := NewRRSACredentialsProvider(func(o *OIDCCredentialsProviderOptions) {
+		o.Endpoint = server.URL
+	})
+	_, ok := provider.(*CredentialsFetcherProvider)
+	assert.True(t, ok)
+	cred, err := provider.GetCredentials(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, "STS.ak", cred.AccessKeyID)
+	assert.Equal(t, "STS.sk", cred.AccessKeySecret)
+	assert.Equal(t, "STS.token", cred.SecurityToken)
+	assert.NotNil(t, cred.Expires)
+	_, err = provider.GetCredentials(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// the rotated token is read on each call
+	p := NewOIDCCredentialsProviderWithoutRefresh(oidcOptionsFromEnv, func(o *OIDCCredentialsProviderOptions) {
+		o.Endpoint = server.URL
+	})
+	_, err = p.GetCredentials(context.TODO())
+	assert.Nil(t, err)
+	assert.Nil(t, os.WriteFile(tokenFile, []byte("token-2"), 0600))
+	_, err = p.GetCredentials(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"token-1", "token-1", "token-2"}, tokens)
+
+	// errors
+	p = NewOIDCCredentialsProviderWithoutRefresh(oidcOptionsFromEnv, func(o *OIDCCredentialsProviderOptions) {
+		o.Endpoint = server.URL
+		o.OIDCProviderArn = "acs:ram::123456:oidc-provider/unknown"
+	})
+	_, err = p.GetCredentials(context.TODO())
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "InvalidParameter.OIDCProviderArn")
+
+	p = NewOIDCCredentialsProviderWithoutRefresh(oidcOptionsFromEnv, func(o *OIDCCredentialsProviderOptions) {
+		o.OIDCTokenFile = tokenFile + ".not-exist"
+	})
+	_, err = p.GetCredentials(context.TODO())
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "failed to read oidc token file")
+
+	t.Setenv(EnvOIDCProviderArn, "")
+	_, err = NewRRSACredentialsProvider().GetCredentials(context.TODO())
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "oidc provider arn")
+}
+
+func TestDefaultCredentialsProviderChain(t *testing.T) {
+	t.Setenv("OSS_ACCESS_KEY_ID", "")
+	t.Setenv("OSS_ACCESS_KEY_SECRET", "")
+	t.Setenv(EnvCredentialProcess, "")
+	t.Setenv(EnvEcsMetadataDisabled, "true")
+
+	provider := NewDefaultCredentialsProviderChain()
+	chain, ok := provider.(*CredentialsProviderChain)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"environment", "process", "ecs_ram_role"}, chain.names)
+
+	// all the sources are skipped
+	_, err := provider.GetCredentials(context.TODO())
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "environment: access key id or access key secret is empty")
+	assert.Contains(t, err.Error(), "process: OSS_CREDENTIAL_PROCESS is not set")
+	assert.Contains(t, err.Error(), "ecs_ram_role: disabled by ALIBABA_CLOUD_ECS_METADATA_DISABLED")
+	assert.Equal(t, "", chain.Source())
+
+	// the failure is cached during the backoff
+	cmd := `echo {"AccessKeyId":"process-ak","AccessKeySecret":"process-sk"}`
+	if runtime.GOOS != "windows" {
+		cmd = `echo '{"AccessKeyId":"process-ak","AccessKeySecret":"process-sk"}'`
+	}
+	t.Setenv(EnvCredentialProcess, cmd)
+	_, err1 := provider.GetCredentials(context.TODO())
+	assert.Equal(t, err, err1)
+	assert.Equal(t, "", chain.Source())
+
+	// the process source
+	chain.retryAt = time.Time{}
+	cred, err := provider.GetCredentials(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, "process-ak", cred.AccessKeyID)
+	assert.Equal(t, "process", chain.Source())
+
+	// the successful source is cached
+	t.Setenv("OSS_ACCESS_KEY_ID", "env-ak")
+	t.Setenv("OSS_ACCESS_KEY_SECRET", "env-sk")
+	cred, err = provider.GetCredentials(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, "process-ak", cred.AccessKeyID)
+
+	provider = NewDefaultCredentialsProviderChain()
+	cred, err = provider.GetCredentials(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, "env-ak", cred.AccessKeyID)
+	assert.Equal(t, "environment", provider.(*CredentialsProviderChain).Source())
+
+	// the order
+	provider = NewDefaultCredentialsProviderChain(func(o *DefaultCredentialsProviderChainOptions) {
+		o.Sources = []string{CredentialsSourceProcess, CredentialsSourceEnvironment}
+	})
+	cred, err = provider.GetCredentials(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, "process-ak", cred.AccessKeyID)
+
+	provider = NewDefaultCredentialsProviderChain(func(o *DefaultCredentialsProviderChainOptions) {
+		o.Sources = []string{"unknown", CredentialsSourceProcess}
+		o.ProcessCommand = "exit 1"
+	})
+	_, err = provider.GetCredentials(context.TODO())
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "unknown: unknown source")
+	assert.Contains(t, err.Error(), "process: error in credential_process")
+}
+
+func TestDefaultCredentialsProviderChain_FailureBackoff(t *testing.T) {
+	t.Setenv("OSS_ACCESS_KEY_ID", "")
+	t.Setenv("OSS_ACCESS_KEY_SECRET", "")
+
+	provider := NewDefaultCredentialsProviderChain(func(o *DefaultCredentialsProviderChainOptions) {
+		o.Sources = []string{CredentialsSourceEnvironment}
+		o.FailureBackoff = 100 * time.Millisecond
+	})
+	_, err := provider.GetCredentials(context.TODO())
+	assert.NotNil(t, err)
+
+	t.Setenv("OSS_ACCESS_KEY_ID", "env-ak")
+	t.Setenv("OSS_ACCESS_KEY_SECRET", "env-sk")
+	_, err1 := provider.GetCredentials(context.TODO())
+	assert.Equal(t, err, err1)
+
+	time.Sleep(150 * time.Millisecond)
+	cred, err := provider.GetCredentials(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, "env-ak", cred.AccessKeyID)
+
+	// the failure of a canceled call is not cached
+	t.Setenv("OSS_ACCESS_KEY_ID", "")
+	provider = NewDefaultCredentialsProviderChain(func(o *DefaultCredentialsProviderChainOptions) {
+		o.Sources = []string{CredentialsSourceEnvironment}
+	})
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	_, err = provider.GetCredentials(ctx)
+	assert.NotNil(t, err)
+
+	t.Setenv("OSS_ACCESS_KEY_ID", "env-ak")
+	cred, err = provider.GetCredentials(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, "env-ak", cred.AccessKeyID)
+}
+
+func TestEcsRoleCredentialsProvider_Hardened(t *testing.T) {
+	var tokenCalls, credCalls, failures int32
+	var hardened = NewAtomicBool(true)
+	expiration := time.Now().Add(time.Hour).UTC().Format("2006-01-02T15:04:05Z")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" && r.URL.Path == "/latest/api/token" {
+			if !hardened.Load() {
+				w.WriteHeader(404)
+				return
+			}
+			atomic.AddInt32(&tokenCalls, 1)
+			assert.Equal(t, "21600", r.Header.Get("X-aliyun-ecs-metadata-token-ttl-seconds"))
+			fmt.Fprint(w, "metadata-token")
+			return
+		}
+		if hardened.Load() && r.Header.Get("X-aliyun-ecs-metadata-token") != "metadata-token" {
+			w.WriteHeader(401)
+			return
+		}
+		if atomic.LoadInt32(&failures) > 0 {
+			atomic.AddInt32(&failures, -1)
+			w.WriteHeader(503)
+			return
+		}
+		switch r.URL.Path {
+		case "/latest/meta-data/ram/security-credentials/":
+			fmt.Fprint(w, "EcsRamRoleTest")
+		case "/latest/meta-data/ram/security-credentials/EcsRamRoleTest":
+			atomic.AddInt32(&credCalls, 1)
+			fmt.Fprintf(w, `{"AccessKeyId": "accessKeyId","AccessKeySecret": "accessKeySecret","SecurityToken": "securityToken","Expiration": "%s","Code" : "Success"}`, expiration)
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer server.Close()
+
+	provider := NewEcsRoleCredentialsProviderWithoutRefresh(func(o *EcsRoleCredentialsProviderOptions) {
+		o.Endpoint = server.URL
+	})
+	p := provider.(*ecsRoleCredentialsProvider)
+	assert.Equal(t, server.URL+"/latest/meta-data/ram/security-credentials/", p.ramCredUrl)
+	cred, err := provider.GetCredentials(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, "accessKeyId", cred.AccessKeyID)
+	assert.Equal(t, "securityToken", cred.SecurityToken)
+	assert.NotNil(t, cred.Expires)
+
+	// the token and the http client are reused
+	httpClient := p.httpClient
+	_, err = provider.GetCredentials(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&tokenCalls))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&credCalls))
+	assert.True(t, httpClient == p.httpClient)
+
+	// the 5xx responses are retried with backoff
+	atomic.StoreInt32(&failures, 2)
+	start := time.Now()
+	_, err = provider.GetCredentials(context.TODO())
+	assert.Nil(t, err)
+	assert.True(t, time.Since(start) >= defaultEcsBaseBackoff*3)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&credCalls))
+
+	// the last good credentials are served during the outage
+	atomic.StoreInt32(&failures, 100)
+	cred, err = provider.GetCredentials(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, "accessKeyId", cred.AccessKeyID)
+	p.lastCreds.Expires = ptr(time.Now().Add(-time.Second))
+	_, err = provider.GetCredentials(context.TODO())
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "StatusCode:503")
+	atomic.StoreInt32(&failures, 0)
+
+	// falls back to the normal mode
+	hardened.Store(false)
+	provider = NewEcsRoleCredentialsProviderWithoutRefresh(EcsRamRole("EcsRamRoleTest"), func(o *EcsRoleCredentialsProviderOptions) {
+		o.Endpoint = server.URL
+	})
+	cred, err = provider.GetCredentials(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, "accessKeyId", cred.AccessKeyID)
+
+	// the hardened mode is disabled
+	hardened.Store(true)
+	provider = NewEcsRoleCredentialsProviderWithoutRefresh(EcsRamRole("EcsRamRoleTest"), func(o *EcsRoleCredentialsProviderOptions) {
+		o.Endpoint = server.URL
+		o.DisableMetadataToken = true
+	})
+	_, err = provider.GetCredentials(context.TODO())
+	assert.NotNil(t, err)
+
+	assert.Equal(t, defaultEcsBaseBackoff, ecsBackoff(0))
+	assert.Equal(t, 2*defaultEcsBaseBackoff, ecsBackoff(1))
+	assert.Equal(t, defaultEcsMaxBackoff, ecsBackoff(10))
+	assert.Equal(t, defaultEcsMaxBackoff, ecsBackoff(100))
+}
+
+func TestEcsRoleCredentialsProvider_TokenNetworkError(t *testing.T) {
+	var tokenCalls int32
+	expiration := time.Now().Add(time.Hour).UTC().Format("2006-01-02T15:04:05Z")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" {
+			// drops the connection, e.g. the hop limit of a container
+			atomic.AddInt32(&tokenCalls, 1)
+			conn, _, err := w.(http.Hijacker).Hijack()
+			assert.Nil(t, err)
+			conn.Close()
+			return
+		}
+		assert.Equal(t, "", r.Header.Get("X-aliyun-ecs-metadata-token"))
+		switch r.URL.Path {
+		case "/latest/meta-data/ram/security-credentials/":
+			fmt.Fprint(w, "EcsRamRoleTest")
+		case "/latest/meta-data/ram/security-credentials/EcsRamRoleTest":
+			fmt.Fprintf(w, `{"AccessKeyId": "accessKeyId","AccessKeySecret": "accessKeySecret","SecurityToken": "securityToken","Expiration": "%s","Code" : "Success"}`, expiration)
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer server.Close()
+
+	// falls back to the normal mode
+	provider := NewEcsRoleCredentialsProviderWithoutRefresh(func(o *EcsRoleCredentialsProviderOptions) {
+		o.Endpoint = server.URL
+	})
+	cred, err := provider.GetCredentials(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, "accessKeyId", cred.AccessKeyID)
+	assert.Equal(t, "securityToken", cred.SecurityToken)
+
+	// the token request is sent once, and the failure is kept
+	for i := 0; i < 3; i++ {
+		_, err = provider.GetCredentials(context.TODO())
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&tokenCalls))
+
+	// tried again after the backoff
+	p := provider.(*ecsRoleCredentialsProvider)
+	p.tokenFailedUntil = time.Now().Add(-time.Second)
+	_, err = provider.GetCredentials(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&tokenCalls))
+}
+
+func TestEcsRoleCredentialsProvider_TokenTimeout(t *testing.T) {
+	var tokenCalls int32
+	expiration := time.Now().Add(time.Hour).UTC().Format("2006-01-02T15:04:05Z")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" {
+			// the request is dropped silently
+			atomic.AddInt32(&tokenCalls, 1)
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+		switch r.URL.Path {
+		case "/latest/meta-data/ram/security-credentials/EcsRamRoleTest":
+			fmt.Fprintf(w, `{"AccessKeyId": "accessKeyId","AccessKeySecret": "accessKeySecret","SecurityToken": "securityToken","Expiration": "%s","Code" : "Success"}`, expiration)
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer server.Close()
+
+	provider := NewEcsRoleCredentialsProviderWithoutRefresh(EcsRamRole("EcsRamRoleTest"), func(o *EcsRoleCredentialsProviderOptions) {
+		o.Endpoint = server.URL
+	})
+
+	// not retried, and waits for the token timeout only
+	start := time.Now()
+	cred, err := provider.GetCredentials(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, "accessKeyId", cred.AccessKeyID)
+	elapsed := time.Since(start)
+	assert.True(t, elapsed >= defaultEcsMetadataTokenTimeout)
+	assert.True(t, elapsed < 2*defaultEcsMetadataTokenTimeout, "elapsed %v", elapsed)
+
+	start = time.Now()
+	_, err = provider.GetCredentials(context.TODO())
+	assert.Nil(t, err)
+	assert.True(t, time.Since(start) < defaultEcsMetadataTokenTimeout)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&tokenCalls))
+
+	// a canceled fetch does not keep the failure
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	provider = NewEcsRoleCredentialsProviderWithoutRefresh(EcsRamRole("EcsRamRoleTest"), func(o *EcsRoleCredentialsProviderOptions) {
+		o.Endpoint = server.URL
+	})
+	_, err = provider.GetCredentials(ctx)
+	assert.NotNil(t, err)
+	assert.True(t, provider.(*ecsRoleCredentialsProvider).tokenFailedUntil.IsZero())
+}
+
+func TestUriCredentialsProvider(t *testing.T) {
+	var calls int32
+	expiration := time.Now().Add(time.Hour).UTC().Format("2006-01-02T15:04:05Z")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		switch r.URL.Path {
+		case "/sts":
+			if r.Header.Get("Authorization") != "Bearer token-1" {
+				w.WriteHeader(403)
+				fmt.Fprint(w, "access denied")
+				return
+			}
+			fmt.Fprintf(w, `{"AccessKeyId":"ak","AccessKeySecret":"sk","SecurityToken":"token","Expiration":"%s"}`, expiration)
+		case "/ak":
+			fmt.Fprint(w, `{"AccessKeyId":"ak","AccessKeySecret":"sk"}`)
+		case "/empty":
+			fmt.Fprint(w, `{"AccessKeyId":"ak"}`)
+		default:
+			fmt.Fprint(w, `invalid`)
+		}
+	}))
+	defer server.Close()
+
+	tokenFile := t.TempDir() + "/token"
+	assert.Nil(t, os.WriteFile(tokenFile, []byte("token-1\n"), 0600))
+
+	provider := NewUriCredentialsProvider(server.URL+"/sts", func(o *UriCredentialsProviderOptions) {
+		o.AuthorizationTokenFile = tokenFile
+	})
+	_, ok := provider.(*CredentialsFetcherProvider)
+	assert.True(t, ok)
+	for i := 0; i < 2; i++ {
+		cred, err := provider.GetCredentials(context.TODO())
+		assert.Nil(t, err)
+		assert.Equal(t, "ak", cred.AccessKeyID)
+		assert.Equal(t, "sk", cred.AccessKeySecret)
+		assert.Equal(t, "token", cred.SecurityToken)
+		assert.Equal(t, expiration, cred.Expires.Format("2006-01-02T15:04:05Z"))
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// the rotated token
+	assert.Nil(t, os.WriteFile(tokenFile, []byte("token-2"), 0600))
+	p := NewUriCredentialsProviderWithoutRefresh(server.URL+"/sts", func(o *UriCredentialsProviderOptions) {
+		o.AuthorizationTokenFile = tokenFile
+	})
+	_, err := p.GetCredentials(context.TODO())
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "StatusCode:403")
+	assert.Contains(t, err.Error(), "access denied")
+
+	p = NewUriCredentialsProviderWithoutRefresh(server.URL + "/ak")
+	cred, err := p.GetCredentials(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, "ak", cred.AccessKeyID)
+	assert.Nil(t, cred.Expires)
+
+	p = NewUriCredentialsProviderWithoutRefresh(server.URL + "/empty")
+	_, err = p.GetCredentials(context.TODO())
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "missing AccessKeyId or AccessKeySecret")
+
+	p = NewUriCredentialsProviderWithoutRefresh(server.URL + "/invalid")
+	_, err = p.GetCredentials(context.TODO())
+	assert.NotNil(t, err)
+
+	p = NewUriCredentialsProviderWithoutRefresh(server.URL+"/sts", func(o *UriCredentialsProviderOptions) {
+		o.AuthorizationTokenFile = tokenFile + ".not-exist"
+	})
+	_, err = p.GetCredentials(context.TODO())
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "authorization token file")
+
+	_, err = NewUriCredentialsProviderWithoutRefresh("").GetCredentials(context.TODO())
+	assert.NotNil(t, err)
+}
+
+func TestCredentialsFetcherProvider_BackgroundRefresh(t *testing.T) {
+	var fetches, successes, failures, expired int32
+	fail := NewAtomicBool(false)
+	fetcher := CredentialsFetcherFunc(func(ctx context.Context) (Credentials, error) {
+		atomic.AddInt32(&fetches, 1)
+		if fail.Load() {
+			return Credentials{}, fmt.Errorf("fetch error")
+		}
+		return Credentials{
+			AccessKeyID:     "ak",
+			AccessKeySecret: "sk",
+			Expires:         ptr(time.Now().Add(time.Second)),
+		}, nil
+	})
+
+	provider := NewCredentialsFetcherProvider(fetcher, func(o *CredentialsFetcherOptions) {
+		o.BackgroundRefresh = true
+		o.ExpiredFactor = 0.5
+		o.RefreshDuration = 200 * time.Millisecond
+		o.OnRefreshSuccess = func(creds Credentials) {
+			assert.Equal(t, "ak", creds.AccessKeyID)
+			atomic.AddInt32(&successes, 1)
+		}
+		o.OnRefreshFailure = func(err error) {
+			assert.Contains(t, err.Error(), "fetch error")
+			atomic.AddInt32(&failures, 1)
+		}
+		o.OnExpired = func(creds Credentials) {
+			assert.True(t, creds.Expired())
+			atomic.AddInt32(&expired, 1)
+		}
+	})
+	p := provider.(*CredentialsFetcherProvider)
+
+	// fetched at once
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetches))
+	cred, err := provider.GetCredentials(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, "ak", cred.AccessKeyID)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetches))
+
+	// refreshed ahead of the expired factor, the requests never wait
+	time.Sleep(1500 * time.Millisecond)
+	n := atomic.LoadInt32(&fetches)
+	assert.True(t, n >= 3, "fetches %v", n)
+	cred, err = provider.GetCredentials(context.TODO())
+	assert.Nil(t, err)
+	assert.False(t, cred.Expired())
+	assert.Equal(t, atomic.LoadInt32(&successes), atomic.LoadInt32(&fetches))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&failures))
+
+	// the failures are reported, and the expiry once
+	fail.Store(true)
+	time.Sleep(2 * time.Second)
+	assert.True(t, atomic.LoadInt32(&failures) >= 2)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&expired))
+	_, err = provider.GetCredentials(context.TODO())
+	assert.NotNil(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&expired))
+
+	// stopped by Close
+	fail.Store(false)
+	assert.Nil(t, p.Close())
+	n = atomic.LoadInt32(&fetches)
+	time.Sleep(500 * time.Millisecond)
+	assert.Equal(t, n, atomic.LoadInt32(&fetches))
+
+	// stopped by the context
+	atomic.StoreInt32(&fetches, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	provider = NewCredentialsFetcherProvider(fetcher, func(o *CredentialsFetcherOptions) {
+		o.BackgroundRefresh = true
+		o.RefreshContext = ctx
+		o.ExpiredFactor = 0.5
+	})
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	<-provider.(*CredentialsFetcherProvider).done
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetches))
+	assert.Nil(t, provider.(*CredentialsFetcherProvider).Close())
+
+	// no background refresh by default
+	atomic.StoreInt32(&fetches, 0)
+	provider = NewCredentialsFetcherProvider(fetcher)
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&fetches))
+	assert.Nil(t, provider.(*CredentialsFetcherProvider).Close())
+}
+
+func TestCredentialsFetcherProvider_BackgroundRefreshNoRequestFetch(t *testing.T) {
+	type backgroundKey struct{}
+	var background, request int32
+	fetcher := CredentialsFetcherFunc(func(ctx context.Context) (Credentials, error) {
+		if ctx.Value(backgroundKey{}) != nil {
+			atomic.AddInt32(&background, 1)
+		} else {
+			atomic.AddInt32(&request, 1)
+		}
+		return Credentials{
+			AccessKeyID:     "ak",
+			AccessKeySecret: "sk",
+			Expires:         ptr(time.Now().Add(time.Second)),
+		}, nil
+	})
+
+	provider := NewCredentialsFetcherProvider(fetcher, func(o *CredentialsFetcherOptions) {
+		o.BackgroundRefresh = true
+		o.RefreshDuration = 100 * time.Millisecond
+		o.RefreshContext = context.WithValue(context.Background(), backgroundKey{}, true)
+	})
+	defer provider.(*CredentialsFetcherProvider).Close()
+
+	// the credentials about to expire are refreshed in the background only
+	time.Sleep(50 * time.Millisecond)
+	for i := 0; i < 200; i++ {
+		cred, err := provider.GetCredentials(context.TODO())
+		assert.Nil(t, err)
+		assert.False(t, cred.Expired())
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, int32(0), atomic.LoadInt32(&request))
+	assert.True(t, atomic.LoadInt32(&background) >= 5, "fetches %v", atomic.LoadInt32(&background))
+}
@@ -9,17 +9,43 @@ import (
 	"net/http"
 	"net/url"
 	"path"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
-const ecs_ram_cred_url = "http://100.100.100.200/latest/meta-data/ram/security-credentials/"
+const (
+	ecs_metadata_endpoint = "http://100.100.100.200"
+	ecs_ram_cred_url      = ecs_metadata_endpoint + "/latest/meta-data/ram/security-credentials/"
+	ecs_token_path        = "/latest/api/token"
+
+	ecsMetadataTokenHeader    = "X-aliyun-ecs-metadata-token"
+	ecsMetadataTokenTTLHeader = "X-aliyun-ecs-metadata-token-ttl-seconds"
+
+	defaultEcsMetadataTokenTTL = 6 * time.Hour
+	// the token request is sent once with a short timeout, and a failure is not retried for a while
+	defaultEcsMetadataTokenTimeout = time.Second
+	defaultEcsMetadataTokenBackoff = 5 * time.Minute
+	defaultEcsBaseBackoff          = 200 * time.Millisecond
+	defaultEcsMaxBackoff           = 3 * time.Second
+)
 
 type ecsRoleCredentialsProvider struct {
 	ramCredUrl string
 	ramRole    string
 	timeout    time.Duration
 	retries    int
+
+	// the hardened mode is used if tokenTTL > 0
+	tokenTTL   time.Duration
+	httpClient *http.Client
+
+	mu               sync.Mutex
+	token            string
+	tokenExpiry      time.Time
+	tokenFailedUntil time.Time
+	lastCreds        *Credentials
 }
 
 type ecsRoleCredentials struct {
@@ -31,25 +57,63 @@ type ecsRoleCredentials struct {
 	Code            string    `json:"Code,omitempty"`
 }
 
-func (p *ecsRoleCredentialsProvider) httpGet(ctx context.Context, url string) (*http.Response, error) {
-	c := &http.Client{
-		Timeout: p.timeout,
+// client returns the http client shared by the requests, so the connections are reused.
+func (p *ecsRoleCredentialsProvider) client() *http.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.httpClient == nil {
+		p.httpClient = &http.Client{
+			Timeout: p.timeout,
+		}
+	}
+	return p.httpClient
+}
+
+// ecsBackoff returns the delay before the next attempt, it grows exponentially.
+func ecsBackoff(attempt int) time.Duration {
+	delay := defaultEcsBaseBackoff << uint(attempt)
+	if delay <= 0 || delay > defaultEcsMaxBackoff {
+		delay = defaultEcsMaxBackoff
+	}
+	return delay
+}
+
+// do sends the request with the retries, the network errors and the 5xx responses are retried.
+func (p *ecsRoleCredentialsProvider) do(ctx context.Context, method, url string, header http.Header) (*http.Response, error) {
+	c := p.client()
+	retries := p.retries
+	if retries < 1 {
+		retries = 1
 	}
 	var resp *http.Response
 	var err error
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-	for i := 0; i < p.retries; i++ {
-		resp, err = c.Do(req)
-		if err != nil {
+	for i := 0; i < retries; i++ {
+		if i > 0 {
 			select {
 			case <-ctx.Done():
 				return nil, ctx.Err()
-			default:
+			case <-time.After(ecsBackoff(i - 1)):
+			}
+		}
+		var req *http.Request
+		req, err = http.NewRequestWithContext(ctx, method, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		resp, err = c.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, err
 			}
-			time.Sleep(500 * time.Millisecond)
+			continue
+		}
+		if resp.StatusCode >= 500 {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			err = fmt.Errorf("metadata service returns StatusCode:%v", resp.StatusCode)
 			continue
 		}
 		return resp, nil
@@ -57,15 +121,90 @@ func (p *ecsRoleCredentialsProvider) httpGet(ctx context.Context, url string) (*
 	return nil, err
 }
 
+// metadataToken returns the token of the hardened mode, or empty if the mode is disabled or the token can not be fetched,
+// e.g. the service does not support it or the PUT request is dropped, then the normal mode is used.
+// A failure is kept for defaultEcsMetadataTokenBackoff, so the fetches do not wait for the token request each time.
+func (p *ecsRoleCredentialsProvider) metadataToken(ctx context.Context) string {
+	if p.tokenTTL <= 0 {
+		return ""
+	}
+
+	p.mu.Lock()
+	if p.token != "" && time.Now().Before(p.tokenExpiry) {
+		token := p.token
+		p.mu.Unlock()
+		return token
+	}
+	if time.Now().Before(p.tokenFailedUntil) {
+		p.mu.Unlock()
+		return ""
+	}
+	p.mu.Unlock()
+
+	token, err := p.requestToken(ctx)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		// the caller gives up, it says nothing about the service
+		if ctx.Err() == nil {
+			p.tokenFailedUntil = time.Now().Add(defaultEcsMetadataTokenBackoff)
+		}
+		return ""
+	}
+	p.token = token
+	// refresh the token a little earlier than it expires
+	p.tokenExpiry = time.Now().Add(p.tokenTTL * 9 / 10)
+	return p.token
+}
+
+// requestToken sends the token request once, with a short timeout.
+func (p *ecsRoleCredentialsProvider) requestToken(ctx context.Context) (string, error) {
+	u, err := url.Parse(p.ramCredUrl)
+	if err != nil {
+		return "", err
+	}
+	u.Path = ecs_token_path
+	ctx, cancel := context.WithTimeout(ctx, defaultEcsMetadataTokenTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "PUT", u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set(ecsMetadataTokenTTLHeader, strconv.FormatInt(int64(p.tokenTTL/time.Second), 10))
+	resp, err := p.client().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK || len(body) == 0 {
+		return "", fmt.Errorf("failed to fetch metadata token, resp.StatusCode:%v", resp.StatusCode)
+	}
+	return string(body), nil
+}
+
+func (p *ecsRoleCredentialsProvider) httpGet(ctx context.Context, url string) (*http.Response, error) {
+	token := p.metadataToken(ctx)
+	header := http.Header{}
+	if token != "" {
+		header.Set(ecsMetadataTokenHeader, token)
+	}
+	return p.do(ctx, "GET", url, header)
+}
+
 func (p *ecsRoleCredentialsProvider) getRoleFromMetaData(ctx context.Context) (string, error) {
 	resp, err := p.httpGet(ctx, p.ramCredUrl)
 	if err != nil {
 		return "", err
 	}
+	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
 		return "", fmt.Errorf("failed to fetch ecs role name, resp.StatusCode:%v", resp.StatusCode)
 	}
-	defer resp.Body.Close()
 	roleName, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return "", err
@@ -77,13 +216,13 @@ func (p *ecsRoleCredentialsProvider) getRoleFromMetaData(ctx context.Context) (s
 	return string(roleName), nil
 }
 
-func (p *ecsRoleCredentialsProvider) getCredentialsFromMetaData(ctx context.Context) (ecsRoleCredentials, error) {
+func (p *ecsRoleCredentialsProvider) getCredentialsFromMetaData(ctx context.Context, ramRole string) (ecsRoleCredentials, error) {
 	var ecsCred ecsRoleCredentials
 	u, err := url.Parse(p.ramCredUrl)
 	if err != nil {
 		return ecsCred, err
 	}
-	u.Path = path.Join(u.Path, p.ramRole)
+	u.Path = path.Join(u.Path, ramRole)
 	resp, err := p.httpGet(ctx, u.String())
 	if err != nil {
 		return ecsCred, err
@@ -93,6 +232,9 @@ func (p *ecsRoleCredentialsProvider) getCredentialsFromMetaData(ctx context.Cont
 	if err != nil {
 		return ecsCred, err
 	}
+	if resp.StatusCode != http.StatusOK {
+		return ecsCred, fmt.Errorf("failed to fetch credentials, resp.StatusCode:%v, response body is '%s'", resp.StatusCode, string(body))
+	}
 	err = json.Unmarshal(body, &ecsCred)
 	if err != nil {
 		return ecsCred, err
@@ -109,15 +251,21 @@ func (p *ecsRoleCredentialsProvider) getCredentialsFromMetaData(ctx context.Cont
 	return ecsCred, nil
 }
 
-func (p *ecsRoleCredentialsProvider) GetCredentials(ctx context.Context) (cred Credentials, err error) {
-	if len(p.ramRole) == 0 {
+func (p *ecsRoleCredentialsProvider) fetch(ctx context.Context) (cred Credentials, err error) {
+	p.mu.Lock()
+	ramRole := p.ramRole
+	p.mu.Unlock()
+	if len(ramRole) == 0 {
 		name, err := p.getRoleFromMetaData(ctx)
 		if err != nil {
 			return cred, err
 		}
+		p.mu.Lock()
 		p.ramRole = name
+		p.mu.Unlock()
+		ramRole = name
 	}
-	ecsCred, err := p.getCredentialsFromMetaData(ctx)
+	ecsCred, err := p.getCredentialsFromMetaData(ctx, ramRole)
 	if err != nil {
 		return cred, err
 	}
@@ -127,29 +275,81 @@ func (p *ecsRoleCredentialsProvider) GetCredentials(ctx context.Context) (cred C
 	if !ecsCred.Expiration.IsZero() {
 		cred.Expires = &ecsCred.Expiration
 	}
+
+	p.mu.Lock()
+	p.lastCreds = &cred
+	p.mu.Unlock()
 	return cred, nil
 }
 
+// GetCredentials fetches the credentials from the metadata service. If the service is not available,
+// the last good credentials are returned until they expire.
+func (p *ecsRoleCredentialsProvider) GetCredentials(ctx context.Context) (Credentials, error) {
+	cred, err := p.fetch(ctx)
+	if err == nil || ctx.Err() != nil {
+		return cred, err
+	}
+
+	p.mu.Lock()
+	last := p.lastCreds
+	p.mu.Unlock()
+	if last != nil && !last.Expired() {
+		return *last, nil
+	}
+	return cred, err
+}
+
 type EcsRoleCredentialsProviderOptions struct {
 	RamRole string
 	Timeout time.Duration
 	Retries int
+
+	// The endpoint of the metadata service, http://100.100.100.200 if not set.
+	Endpoint string
+
+	// Do not use the hardened mode, i.e. get the metadata without a token.
+	DisableMetadataToken bool
+
+	// The validity period of the metadata token, 6 hours if not set.
+	MetadataTokenTTL time.Duration
+
+	// The http client to access the metadata service, an http client with the Timeout if not set.
+	HttpClient *http.Client
 }
 
 func NewEcsRoleCredentialsProviderWithoutRefresh(optFns ...func(*EcsRoleCredentialsProviderOptions)) CredentialsProvider {
+	return newEcsRoleCredentialsProvider(optFns...)
+}
+
+func newEcsRoleCredentialsProvider(optFns ...func(*EcsRoleCredentialsProviderOptions)) *ecsRoleCredentialsProvider {
 	options := EcsRoleCredentialsProviderOptions{
-		RamRole: "",
-		Timeout: time.Second * 10,
-		Retries: 3,
+		RamRole:          "",
+		Timeout:          time.Second * 10,
+		Retries:          3,
+		MetadataTokenTTL: defaultEcsMetadataTokenTTL,
 	}
 	for _, fn := range optFns {
 		fn(&options)
 	}
+	ramCredUrl := ecs_ram_cred_url
+	if options.Endpoint != "" {
+		endpoint := options.Endpoint
+		if !strings.Contains(endpoint, "://") {
+			endpoint = "http://" + endpoint
+		}
+		ramCredUrl = strings.TrimSuffix(endpoint, "/") + "/latest/meta-data/ram/security-credentials/"
+	}
+	tokenTTL := options.MetadataTokenTTL
+	if options.DisableMetadataToken {
+		tokenTTL = 0
+	}
 	return &ecsRoleCredentialsProvider{
-		ramCredUrl: ecs_ram_cred_url,
+		ramCredUrl: ramCredUrl,
 		ramRole:    options.RamRole,
 		timeout:    options.Timeout,
 		retries:    options.Retries,
+		tokenTTL:   tokenTTL,
+		httpClient: options.HttpClient,
 	}
 }
 
@@ -160,9 +360,10 @@ func EcsRamRole(ramRole string) func(*EcsRoleCredentialsProviderOptions) {
 }
 
 func NewEcsRoleCredentialsProvider(optFns ...func(*EcsRoleCredentialsProviderOptions)) CredentialsProvider {
-	p := NewEcsRoleCredentialsProviderWithoutRefresh(optFns...)
+	p := newEcsRoleCredentialsProvider(optFns...)
+	// the fetcher provider keeps the current credentials if the refresh fails
 	provider := NewCredentialsFetcherProvider(CredentialsFetcherFunc(func(ctx context.Context) (Credentials, error) {
-		return p.GetCredentials(ctx)
+		return p.fetch(ctx)
 	}))
 	return provider
 }
@@ -3,6 +3,7 @@ package credentials
 import (
 	"context"
 	"fmt"
+	"math/rand"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -14,12 +15,35 @@ var (
 
 	// backoff of refresh time
 	defaultRefreshDuration = 120 * time.Second
+
+	// jitter of the background refresh time
+	defaultRefreshJitter = 0.1
 )
 
 // CredentialsFetcherOptions are the options
 type CredentialsFetcherOptions struct {
 	ExpiredFactor   float64
 	RefreshDuration time.Duration
+
+	// Refresh the credentials in a background goroutine, when ExpiredFactor of their lifetime is left minus a random jitter,
+	// so the requests do not wait for the fetch. The requests only fetch the credentials that have expired.
+	// A failed refresh is retried after RefreshDuration. The goroutine is stopped by Close or by RefreshContext.
+	BackgroundRefresh bool
+
+	// The ratio of the random jitter to the wait time of the background refresh, 0.1 if not set.
+	RefreshJitter float64
+
+	// The context of the background refresh, context.Background if not set.
+	RefreshContext context.Context
+
+	// Called after the credentials are fetched.
+	OnRefreshSuccess func(creds Credentials)
+
+	// Called after the fetch fails.
+	OnRefreshFailure func(err error)
+
+	// Called once when the credentials expire before they are refreshed.
+	OnExpired func(creds Credentials)
 }
 
 type CredentialsFetcher interface {
@@ -45,42 +69,166 @@ type CredentialsFetcherProvider struct {
 
 	expiredFactor   float64
 	refreshDuration time.Duration
+	refreshJitter   float64
+
+	onRefreshSuccess func(creds Credentials)
+	onRefreshFailure func(err error)
+	onExpired        func(creds Credentials)
+
+	cancel context.CancelFunc
+	done   chan struct{}
 }
 
 type fetcherCredentials struct {
 	Creds        Credentials
 	ExpiryWindow time.Duration
+
+	expiredNotified int32
 }
 
 func NewCredentialsFetcherProvider(fetcher CredentialsFetcher, optFns ...func(*CredentialsFetcherOptions)) CredentialsProvider {
 	options := CredentialsFetcherOptions{
 		ExpiredFactor:   defaultExpiredFactor,
 		RefreshDuration: defaultRefreshDuration,
+		RefreshJitter:   defaultRefreshJitter,
 	}
 
 	for _, fn := range optFns {
 		fn(&options)
 	}
 
-	return &CredentialsFetcherProvider{
-		fetcher:         fetcher,
-		expiredFactor:   options.ExpiredFactor,
-		refreshDuration: options.RefreshDuration,
+	p := &CredentialsFetcherProvider{
+		fetcher:          fetcher,
+		expiredFactor:    options.ExpiredFactor,
+		refreshDuration:  options.RefreshDuration,
+		refreshJitter:    options.RefreshJitter,
+		onRefreshSuccess: options.OnRefreshSuccess,
+		onRefreshFailure: options.OnRefreshFailure,
+		onExpired:        options.OnExpired,
+	}
+
+	if options.BackgroundRefresh && fetcher != nil {
+		ctx := options.RefreshContext
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		ctx, p.cancel = context.WithCancel(ctx)
+		p.done = make(chan struct{})
+		go p.refreshLoop(ctx)
+	}
+
+	return p
+}
+
+// Close stops the background refresh, and waits for the goroutine to exit.
+func (c *CredentialsFetcherProvider) Close() error {
+	if c.cancel != nil {
+		c.cancel()
+		<-c.done
+	}
+	return nil
+}
+
+func (c *CredentialsFetcherProvider) refreshLoop(ctx context.Context) {
+	defer close(c.done)
+	var wait time.Duration
+	for {
+		if wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+
+		c.m.Lock()
+		creds, err := c.fetch(ctx)
+		if err == nil {
+			c.updateCreds(&creds)
+		}
+		c.m.Unlock()
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err == nil {
+			if creds.Expires == nil {
+				// the credentials never expire
+				<-ctx.Done()
+				return
+			}
+			// before the requests find them about to expire
+			wait = time.Duration((1 - c.expiredFactor) * float64(time.Until(*creds.Expires)))
+			if wait <= 0 {
+				wait = c.refreshDuration
+			}
+			wait = c.jitter(wait)
+		} else {
+			wait = c.jitter(c.refreshDuration)
+			if fcreds := c.getCreds(); fcreds != nil && fcreds.Creds.Expires != nil {
+				if c.isExpired(fcreds) {
+					c.notifyExpired(fcreds)
+				} else if remaining := time.Until(*fcreds.Creds.Expires); remaining < wait {
+					// retry when they expire at the latest
+					wait = remaining
+				}
+			}
+		}
+	}
+}
+
+// jitter returns the wait time minus a random jitter.
+func (c *CredentialsFetcherProvider) jitter(wait time.Duration) time.Duration {
+	if wait <= 0 {
+		return 0
+	}
+	if c.refreshJitter > 0 {
+		wait -= time.Duration(rand.Float64() * c.refreshJitter * float64(wait))
+	}
+	return wait
+}
+
+// backgroundRefresh returns whether the background refresh is running.
+func (c *CredentialsFetcherProvider) backgroundRefresh() bool {
+	if c.done == nil {
+		return false
+	}
+	select {
+	case <-c.done:
+		return false
+	default:
+		return true
+	}
+}
+
+func (c *CredentialsFetcherProvider) notifyExpired(fcreds *fetcherCredentials) {
+	if c.onExpired != nil && atomic.CompareAndSwapInt32(&fcreds.expiredNotified, 0, 1) {
+		c.onExpired(fcreds.Creds)
 	}
 }
 
 func (c *CredentialsFetcherProvider) GetCredentials(ctx context.Context) (Credentials, error) {
 	fcreds := c.getCreds()
 	if c.isExpired(fcreds) {
+		if fcreds != nil {
+			c.notifyExpired(fcreds)
+		}
 		c.m.Lock()
 		defer c.m.Unlock()
+		// refreshed by another call or in the background
+		if fcreds1 := c.getCreds(); fcreds1 != fcreds && !c.isExpired(fcreds1) {
+			return fcreds1.Creds, nil
+		}
 		creds, err := c.fetch(ctx)
 		if err == nil {
 			c.updateCreds(&creds)
 		}
 		return creds, err
 	} else {
-		if c.isSoonExpire(fcreds) && c.m.TryLock() {
+		if !c.backgroundRefresh() && c.isSoonExpire(fcreds) && c.m.TryLock() {
 			defer c.m.Unlock()
 			fcreds1 := c.getCreds()
 			if fcreds1 == fcreds {
@@ -124,12 +272,23 @@ func (c *CredentialsFetcherProvider) fetch(ctx context.Context) (Credentials, er
 		return Credentials{}, fmt.Errorf("fetcher is null.")
 	}
 
+	var creds Credentials
+	var err error
 	select {
 	case result, _ := <-c.asyncFetch(ctx):
-		return result.val, result.err
+		creds, err = result.val, result.err
 	case <-ctx.Done():
-		return Credentials{}, fmt.Errorf("FetchCredentialsCanceled")
+		creds, err = Credentials{}, fmt.Errorf("FetchCredentialsCanceled")
+	}
+
+	if err != nil {
+		if c.onRefreshFailure != nil {
+			c.onRefreshFailure(err)
+		}
+	} else if c.onRefreshSuccess != nil {
+		c.onRefreshSuccess(creds)
 	}
+	return creds, err
 }
 
 func (p *CredentialsFetcherProvider) getCreds() *fetcherCredentials {
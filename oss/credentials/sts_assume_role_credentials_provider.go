@@ -0,0 +1,253 @@
+package credentials
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultStsEndpoint = "sts.aliyuncs.com"
+
+	defaultStsDurationSeconds = 3600
+	defaultStsTimeout         = 10 * time.Second
+)
+
+// stsClient calls the STS api in the RPC style.
+type stsClient struct {
+	endpoint   string
+	httpClient *http.Client
+}
+
+type stsCredentialsResult struct {
+	RequestId   string `json:"RequestId"`
+	Code        string `json:"Code"`
+	Message     string `json:"Message"`
+	Credentials *struct {
+		AccessKeyId     string    `json:"AccessKeyId"`
+		AccessKeySecret string    `json:"AccessKeySecret"`
+		SecurityToken   string    `json:"SecurityToken"`
+		Expiration      time.Time `json:"Expiration"`
+	} `json:"Credentials"`
+}
+
+func newStsClient(endpoint string, timeout time.Duration, httpClient *http.Client) *stsClient {
+	if endpoint == "" {
+		endpoint = DefaultStsEndpoint
+	}
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: timeout}
+	}
+	return &stsClient{
+		endpoint:   endpoint,
+		httpClient: httpClient,
+	}
+}
+
+// call sends the action, the request is signed if cred is not nil, or sent anonymously.
+func (c *stsClient) call(ctx context.Context, action string, params map[string]string, cred *Credentials) (Credentials, error) {
+	query := url.Values{}
+	for k, v := range params {
+		if v != "" {
+			query.Set(k, v)
+		}
+	}
+	query.Set("Action", action)
+	query.Set("Format", "JSON")
+	query.Set("Version", "2015-04-01")
+	query.Set("Timestamp", time.Now().UTC().Format("2006-01-02T15:04:05Z"))
+	if cred != nil {
+		query.Set("AccessKeyId", cred.AccessKeyID)
+		if cred.SecurityToken != "" {
+			query.Set("SecurityToken", cred.SecurityToken)
+		}
+		query.Set("SignatureMethod", "HMAC-SHA1")
+		query.Set("SignatureVersion", "1.0")
+		query.Set("SignatureNonce", newStsNonce())
+		query.Set("Signature", stsSignature("POST", query, cred.AccessKeySecret))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.endpoint, strings.NewReader(query.Encode()))
+	if err != nil {
+		return Credentials{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return Credentials{}, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	result := &stsCredentialsResult{}
+	if err = json.Unmarshal(body, result); err != nil {
+		return Credentials{}, fmt.Errorf("failed to %s, StatusCode:%d, invalid response body '%s'", action, resp.StatusCode, string(body))
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return Credentials{}, fmt.Errorf("failed to %s, StatusCode:%d, Code:%s, Message:%s, RequestId:%s",
+			action, resp.StatusCode, result.Code, result.Message, result.RequestId)
+	}
+
+	if result.Credentials == nil || result.Credentials.AccessKeyId == "" || result.Credentials.AccessKeySecret == "" {
+		return Credentials{}, fmt.Errorf("AccessKeyId or AccessKeySecret is empty, response body is '%s'", string(body))
+	}
+
+	creds := Credentials{
+		AccessKeyID:     result.Credentials.AccessKeyId,
+		AccessKeySecret: result.Credentials.AccessKeySecret,
+		SecurityToken:   result.Credentials.SecurityToken,
+	}
+	if !result.Credentials.Expiration.IsZero() {
+		creds.Expires = &result.Credentials.Expiration
+	}
+	return creds, nil
+}
+
+func stsPercentEncode(v string) string {
+	v = url.QueryEscape(v)
+	v = strings.ReplaceAll(v, "+", "%20")
+	v = strings.ReplaceAll(v, "*", "%2A")
+	v = strings.ReplaceAll(v, "%7E", "~")
+	return v
+}
+
+// stsSignature returns the signature of the RPC style api.
+func stsSignature(method string, query url.Values, secret string) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, stsPercentEncode(k)+"="+stsPercentEncode(query.Get(k)))
+	}
+	stringToSign := method + "&" + stsPercentEncode("/") + "&" + stsPercentEncode(strings.Join(pairs, "&"))
+
+	h := hmac.New(sha1.New, []byte(secret+"&"))
+	h.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func newStsNonce() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 10)
+	}
+	return hex.EncodeToString(b)
+}
+
+type AssumeRoleCredentialsProviderOptions struct {
+	// The name of the role session, oss-go-sdk-v2-<timestamp> if not set.
+	RoleSessionName string
+
+	// The external id, required by the trust policy of the role in some cross-account setups.
+	ExternalId string
+
+	// The inline policy to further restrict the permissions of the session credentials.
+	Policy string
+
+	// The validity period of the session credentials, 3600 seconds if not set.
+	DurationSeconds int
+
+	// The STS endpoint, sts.aliyuncs.com if not set. The https scheme is used if not specified.
+	Endpoint string
+
+	// The timeout of the STS request, 10 seconds if not set.
+	Timeout time.Duration
+
+	// The http client to send the STS request, an http client with the Timeout if not set.
+	HttpClient *http.Client
+}
+
+type assumeRoleCredentialsProvider struct {
+	client          *stsClient
+	provider        CredentialsProvider
+	roleArn         string
+	roleSessionName string
+	externalId      string
+	policy          string
+	durationSeconds int
+}
+
+// NewAssumeRoleCredentialsProviderWithoutRefresh returns a provider that calls STS AssumeRole on each call,
+// the request is signed with the credentials from the provider.
+func NewAssumeRoleCredentialsProviderWithoutRefresh(provider CredentialsProvider, roleArn string, optFns ...func(*AssumeRoleCredentialsProviderOptions)) CredentialsProvider {
+	options := AssumeRoleCredentialsProviderOptions{
+		DurationSeconds: defaultStsDurationSeconds,
+		Timeout:         defaultStsTimeout,
+	}
+	for _, fn := range optFns {
+		fn(&options)
+	}
+	if options.RoleSessionName == "" {
+		options.RoleSessionName = "oss-go-sdk-v2-" + strconv.FormatInt(time.Now().UnixNano()/int64(time.Millisecond), 10)
+	}
+	return &assumeRoleCredentialsProvider{
+		client:          newStsClient(options.Endpoint, options.Timeout, options.HttpClient),
+		provider:        provider,
+		roleArn:         roleArn,
+		roleSessionName: options.RoleSessionName,
+		externalId:      options.ExternalId,
+		policy:          options.Policy,
+		durationSeconds: options.DurationSeconds,
+	}
+}
+
+// NewAssumeRoleCredentialsProvider returns a provider that assumes the role, and refreshes the session credentials before they expire.
+func NewAssumeRoleCredentialsProvider(provider CredentialsProvider, roleArn string, optFns ...func(*AssumeRoleCredentialsProviderOptions)) CredentialsProvider {
+	p := NewAssumeRoleCredentialsProviderWithoutRefresh(provider, roleArn, optFns...)
+	return NewCredentialsFetcherProvider(CredentialsFetcherFunc(func(ctx context.Context) (Credentials, error) {
+		return p.GetCredentials(ctx)
+	}))
+}
+
+func (p *assumeRoleCredentialsProvider) GetCredentials(ctx context.Context) (Credentials, error) {
+	if p.provider == nil {
+		return Credentials{}, fmt.Errorf("base credentials provider is null.")
+	}
+	if p.roleArn == "" {
+		return Credentials{}, fmt.Errorf("role arn must not be empty")
+	}
+
+	cred, err := p.provider.GetCredentials(ctx)
+	if err != nil {
+		return Credentials{}, err
+	}
+	if !cred.HasKeys() {
+		return Credentials{}, fmt.Errorf("base credentials are empty, can not assume role")
+	}
+
+	params := map[string]string{
+		"RoleArn":         p.roleArn,
+		"RoleSessionName": p.roleSessionName,
+		"ExternalId":      p.externalId,
+		"Policy":          p.policy,
+	}
+	if p.durationSeconds > 0 {
+		params["DurationSeconds"] = strconv.Itoa(p.durationSeconds)
+	}
+
+	return p.client.call(ctx, "AssumeRole", params, &cred)
+}
@@ -0,0 +1,141 @@
+package credentials
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The environment variables injected by the RRSA (RAM Roles for Service Accounts) of ACK.
+const (
+	EnvRoleArn         = "ALIBABA_CLOUD_ROLE_ARN"
+	EnvOIDCProviderArn = "ALIBABA_CLOUD_OIDC_PROVIDER_ARN"
+	EnvOIDCTokenFile   = "ALIBABA_CLOUD_OIDC_TOKEN_FILE"
+	EnvRoleSessionName = "ALIBABA_CLOUD_ROLE_SESSION_NAME"
+)
+
+type OIDCCredentialsProviderOptions struct {
+	// The arn of the role to assume.
+	RoleArn string
+
+	// The arn of the OIDC identity provider.
+	OIDCProviderArn string
+
+	// The path of the OIDC token file, it is read on each call, so a rotated token is picked up.
+	OIDCTokenFile string
+
+	// The name of the role session, oss-go-sdk-v2-<timestamp> if not set.
+	RoleSessionName string
+
+	// The inline policy to further restrict the permissions of the session credentials.
+	Policy string
+
+	// The validity period of the session credentials, 3600 seconds if not set.
+	DurationSeconds int
+
+	// The STS endpoint, sts.aliyuncs.com if not set. The https scheme is used if not specified.
+	Endpoint string
+
+	// The timeout of the STS request, 10 seconds if not set.
+	Timeout time.Duration
+
+	// The http client to send the STS request, an http client with the Timeout if not set.
+	HttpClient *http.Client
+}
+
+type oidcCredentialsProvider struct {
+	client          *stsClient
+	roleArn         string
+	oidcProviderArn string
+	oidcTokenFile   string
+	roleSessionName string
+	policy          string
+	durationSeconds int
+}
+
+// NewOIDCCredentialsProviderWithoutRefresh returns a provider that exchanges the OIDC token for the session credentials
+// by STS AssumeRoleWithOIDC on each call.
+func NewOIDCCredentialsProviderWithoutRefresh(optFns ...func(*OIDCCredentialsProviderOptions)) CredentialsProvider {
+	options := OIDCCredentialsProviderOptions{
+		DurationSeconds: defaultStsDurationSeconds,
+		Timeout:         defaultStsTimeout,
+	}
+	for _, fn := range optFns {
+		fn(&options)
+	}
+	if options.RoleSessionName == "" {
+		options.RoleSessionName = "oss-go-sdk-v2-" + strconv.FormatInt(time.Now().UnixNano()/int64(time.Millisecond), 10)
+	}
+	return &oidcCredentialsProvider{
+		client:          newStsClient(options.Endpoint, options.Timeout, options.HttpClient),
+		roleArn:         options.RoleArn,
+		oidcProviderArn: options.OIDCProviderArn,
+		oidcTokenFile:   options.OIDCTokenFile,
+		roleSessionName: options.RoleSessionName,
+		policy:          options.Policy,
+		durationSeconds: options.DurationSeconds,
+	}
+}
+
+// NewOIDCCredentialsProvider returns a provider that exchanges the OIDC token for the session credentials,
+// and refreshes them before they expire.
+func NewOIDCCredentialsProvider(optFns ...func(*OIDCCredentialsProviderOptions)) CredentialsProvider {
+	p := NewOIDCCredentialsProviderWithoutRefresh(optFns...)
+	return NewCredentialsFetcherProvider(CredentialsFetcherFunc(func(ctx context.Context) (Credentials, error) {
+		return p.GetCredentials(ctx)
+	}))
+}
+
+// NewRRSACredentialsProvider returns an OIDC credentials provider configured by the environment variables
+// that ACK RRSA injects into the pod, i.e. ALIBABA_CLOUD_ROLE_ARN, ALIBABA_CLOUD_OIDC_PROVIDER_ARN,
+// ALIBABA_CLOUD_OIDC_TOKEN_FILE and the optional ALIBABA_CLOUD_ROLE_SESSION_NAME.
+// The options are applied after the environment variables.
+func NewRRSACredentialsProvider(optFns ...func(*OIDCCredentialsProviderOptions)) CredentialsProvider {
+	return NewOIDCCredentialsProvider(append([]func(*OIDCCredentialsProviderOptions){oidcOptionsFromEnv}, optFns...)...)
+}
+
+func oidcOptionsFromEnv(o *OIDCCredentialsProviderOptions) {
+	o.RoleArn = os.Getenv(EnvRoleArn)
+	o.OIDCProviderArn = os.Getenv(EnvOIDCProviderArn)
+	o.OIDCTokenFile = os.Getenv(EnvOIDCTokenFile)
+	o.RoleSessionName = os.Getenv(EnvRoleSessionName)
+}
+
+func (p *oidcCredentialsProvider) GetCredentials(ctx context.Context) (Credentials, error) {
+	if p.roleArn == "" {
+		return Credentials{}, fmt.Errorf("role arn must not be empty")
+	}
+	if p.oidcProviderArn == "" {
+		return Credentials{}, fmt.Errorf("oidc provider arn must not be empty")
+	}
+	if p.oidcTokenFile == "" {
+		return Credentials{}, fmt.Errorf("oidc token file must not be empty")
+	}
+
+	// the token is rotated, so read it on each call
+	token, err := os.ReadFile(p.oidcTokenFile)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("failed to read oidc token file, %w", err)
+	}
+	if len(strings.TrimSpace(string(token))) == 0 {
+		return Credentials{}, fmt.Errorf("oidc token file %s is empty", p.oidcTokenFile)
+	}
+
+	params := map[string]string{
+		"RoleArn":         p.roleArn,
+		"OIDCProviderArn": p.oidcProviderArn,
+		"OIDCToken":       strings.TrimSpace(string(token)),
+		"RoleSessionName": p.roleSessionName,
+		"Policy":          p.policy,
+	}
+	if p.durationSeconds > 0 {
+		params["DurationSeconds"] = strconv.Itoa(p.durationSeconds)
+	}
+
+	// AssumeRoleWithOIDC is authenticated by the token, the request is not signed
+	return p.client.call(ctx, "AssumeRoleWithOIDC", params, nil)
+}
@@ -0,0 +1,118 @@
+package credentials
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+/*
+The response body is in the same format as the output of the credential process,
+temporary access credentials format
+{
+	"AccessKeyId" : "ak",
+	"AccessKeySecret" : "sk",
+	"Expiration" : "2023-12-29T07:45:02Z",
+	"SecurityToken" : "token",
+}
+*/
+
+type UriCredentialsProviderOptions struct {
+	// The timeout of the request, 10 seconds if not set.
+	Timeout time.Duration
+
+	// The file that contains the token, it is sent in the header "Authorization: Bearer <token>".
+	// The file is read on each call, so a rotated token is picked up.
+	AuthorizationTokenFile string
+
+	// The http client to send the request, an http client with the Timeout if not set.
+	HttpClient *http.Client
+}
+
+type uriCredentialsProvider struct {
+	uri        string
+	tokenFile  string
+	httpClient *http.Client
+}
+
+// NewUriCredentialsProviderWithoutRefresh returns a provider that gets the credentials from the uri on each call,
+// e.g. a credential server on the local node.
+func NewUriCredentialsProviderWithoutRefresh(uri string, optFns ...func(*UriCredentialsProviderOptions)) CredentialsProvider {
+	options := UriCredentialsProviderOptions{
+		Timeout: 10 * time.Second,
+	}
+	for _, fn := range optFns {
+		fn(&options)
+	}
+	httpClient := options.HttpClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: options.Timeout}
+	}
+	return &uriCredentialsProvider{
+		uri:        uri,
+		tokenFile:  options.AuthorizationTokenFile,
+		httpClient: httpClient,
+	}
+}
+
+// NewUriCredentialsProvider returns a provider that gets the credentials from the uri, and refreshes them before they expire.
+func NewUriCredentialsProvider(uri string, optFns ...func(*UriCredentialsProviderOptions)) CredentialsProvider {
+	p := NewUriCredentialsProviderWithoutRefresh(uri, optFns...)
+	return NewCredentialsFetcherProvider(CredentialsFetcherFunc(func(ctx context.Context) (Credentials, error) {
+		return p.GetCredentials(ctx)
+	}))
+}
+
+func (p *uriCredentialsProvider) GetCredentials(ctx context.Context) (Credentials, error) {
+	if p.uri == "" {
+		return Credentials{}, fmt.Errorf("uri must not be empty")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", p.uri, nil)
+	if err != nil {
+		return Credentials{}, err
+	}
+	if p.tokenFile != "" {
+		token, err := os.ReadFile(p.tokenFile)
+		if err != nil {
+			return Credentials{}, fmt.Errorf("failed to read authorization token file, %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return Credentials{}, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Credentials{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Credentials{}, fmt.Errorf("failed to get credentials from %s, StatusCode:%d, response body is '%s'", p.uri, resp.StatusCode, string(body))
+	}
+
+	result := &processCredentialsResult{}
+	if err = json.Unmarshal(body, result); err != nil {
+		return Credentials{}, err
+	}
+
+	creds := Credentials{
+		AccessKeyID:     result.AccessKeyId,
+		AccessKeySecret: result.AccessKeySecret,
+		SecurityToken:   result.SecurityToken,
+		Expires:         result.Expiration,
+	}
+
+	if !creds.HasKeys() {
+		return creds, fmt.Errorf("missing AccessKeyId or AccessKeySecret in response body")
+	}
+
+	return creds, nil
+}
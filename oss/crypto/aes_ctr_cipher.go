@@ -28,6 +28,11 @@ func CreateAesCtrCipher(cipher MasterCipher) ContentCipherBuilder {
 
 // createCipherData create CipherData for encrypt object data
 func (builder aesCtrCipherBuilder) createCipherData() (CipherData, error) {
+	return newCipherData(builder.MasterCipher, AesCtrAlgorithm)
+}
+
+// newCipherData generates a random key and iv, and encrypts them by the master key
+func newCipherData(masterCipher MasterCipher, cekAlg string) (CipherData, error) {
 	var cd CipherData
 	var err error
 	err = cd.RandomKeyIv(aesKeySize, ivSize)
@@ -35,18 +40,22 @@ func (builder aesCtrCipherBuilder) createCipherData() (CipherData, error) {
 		return cd, err
 	}
 
-	cd.WrapAlgorithm = builder.MasterCipher.GetWrapAlgorithm()
-	cd.CEKAlgorithm = AesCtrAlgorithm
-	cd.MatDesc = builder.MasterCipher.GetMatDesc()
+	cd.WrapAlgorithm = masterCipher.GetWrapAlgorithm()
+	cd.CEKAlgorithm = cekAlg
+	cd.MatDesc = masterCipher.GetMatDesc()
 
 	// EncryptedKey
-	cd.EncryptedKey, err = builder.MasterCipher.Encrypt(cd.Key)
+	if generator, ok := masterCipher.(DataKeyGenerator); ok {
+		cd.Key, cd.EncryptedKey, err = generator.GenerateDataKey(aesKeySize)
+	} else {
+		cd.EncryptedKey, err = masterCipher.Encrypt(cd.Key)
+	}
 	if err != nil {
 		return cd, err
 	}
 
 	// EncryptedIV
-	cd.EncryptedIV, err = builder.MasterCipher.Encrypt(cd.IV)
+	cd.EncryptedIV, err = masterCipher.Encrypt(cd.IV)
 	if err != nil {
 		return cd, err
 	}
@@ -78,13 +87,22 @@ func (builder aesCtrCipherBuilder) ContentCipher() (ContentCipher, error) {
 
 // ContentCipherEnv is used to create a decrption ContentCipher from Envelope
 func (builder aesCtrCipherBuilder) ContentCipherEnv(envelope Envelope) (ContentCipher, error) {
+	cd, err := newCipherDataFromEnvelope(builder.MasterCipher, envelope)
+	if err != nil {
+		return nil, err
+	}
+	return builder.contentCipherCD(cd)
+}
+
+// newCipherDataFromEnvelope decrypts the key and iv in the Envelope by the master key
+func newCipherDataFromEnvelope(masterCipher MasterCipher, envelope Envelope) (CipherData, error) {
 	var cd CipherData
 	cd.EncryptedKey = make([]byte, len(envelope.CipherKey))
 	copy(cd.EncryptedKey, []byte(envelope.CipherKey))
 
-	plainKey, err := builder.MasterCipher.Decrypt([]byte(envelope.CipherKey))
+	plainKey, err := masterCipher.Decrypt([]byte(envelope.CipherKey))
 	if err != nil {
-		return nil, err
+		return cd, err
 	}
 	cd.Key = make([]byte, len(plainKey))
 	copy(cd.Key, plainKey)
@@ -92,9 +110,9 @@ func (builder aesCtrCipherBuilder) ContentCipherEnv(envelope Envelope) (ContentC
 	cd.EncryptedIV = make([]byte, len(envelope.IV))
 	copy(cd.EncryptedIV, []byte(envelope.IV))
 
-	plainIV, err := builder.MasterCipher.Decrypt([]byte(envelope.IV))
+	plainIV, err := masterCipher.Decrypt([]byte(envelope.IV))
 	if err != nil {
-		return nil, err
+		return cd, err
 	}
 
 	cd.IV = make([]byte, len(plainIV))
@@ -104,7 +122,7 @@ func (builder aesCtrCipherBuilder) ContentCipherEnv(envelope Envelope) (ContentC
 	cd.WrapAlgorithm = envelope.WrapAlg
 	cd.CEKAlgorithm = envelope.CEKAlg
 
-	return builder.contentCipherCD(cd)
+	return cd, nil
 }
 
 // GetMatDesc is used to get MasterCipher's MatDesc
@@ -0,0 +1,181 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// aesGcm encrypts the data frame by frame, each frame is sealed with its own nonce,
+// so a frame can be decrypted and authenticated without the frames before it.
+type aesGcm struct {
+	aead       cipher.AEAD
+	cipherData CipherData
+}
+
+func newAesGcm(cd CipherData) (Cipher, error) {
+	block, err := aes.NewCipher(cd.Key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(cd.IV) != ivSize {
+		return nil, fmt.Errorf("invalid iv length %d, expect %d", len(cd.IV), ivSize)
+	}
+	return &aesGcm{aead, cd}, nil
+}
+
+// nonce returns the nonce of the frame, the counter of the iv advances by AesGcmFrameSize/len(iv) per frame,
+// the same as SeekIV, so the cipher seeked to a frame boundary continues the nonces.
+func (c *aesGcm) nonce(frame uint64) []byte {
+	nonce := make([]byte, c.aead.NonceSize())
+	copy(nonce, c.cipherData.IV[:4])
+	binary.BigEndian.PutUint64(nonce[4:], c.cipherData.GetIV()+frame*uint64(AesGcmFrameSize/len(c.cipherData.IV)))
+	return nonce
+}
+
+// aad returns the additional data of the frame, it tells whether the frame is the final one of the object,
+// like the STREAM construction, so the frames removed from the end of the object fail the authentication.
+func (c *aesGcm) aad(final bool) []byte {
+	if final {
+		return []byte{1}
+	}
+	return []byte{0}
+}
+
+func (c *aesGcm) Encrypt(src io.Reader) io.Reader {
+	return c.encrypt(src, true)
+}
+
+// encrypt seals the last frame as the final one of the object if final is true, an empty object has an empty final frame.
+func (c *aesGcm) encrypt(src io.Reader, final bool) io.Reader {
+	return &gcmEncryptReader{
+		cipher: c,
+		src:    src,
+		final:  final,
+		frame:  make([]byte, AesGcmFrameSize+1),
+	}
+}
+
+type gcmEncryptReader struct {
+	cipher *aesGcm
+	src    io.Reader
+	final  bool
+	index  uint64
+	// a frame and one byte of the next frame, which tells whether the frame is the last one
+	frame   []byte
+	pending int
+	buf     []byte
+	offset  int
+	err     error
+}
+
+func (reader *gcmEncryptReader) Read(data []byte) (int, error) {
+	for reader.offset >= len(reader.buf) {
+		if reader.err != nil {
+			return 0, reader.err
+		}
+		n, err := io.ReadFull(reader.src, reader.frame[reader.pending:])
+		n += reader.pending
+		reader.pending = 0
+		reader.buf = reader.buf[:0]
+		reader.offset = 0
+		switch err {
+		case nil:
+			reader.buf = reader.cipher.aead.Seal(reader.buf, reader.cipher.nonce(reader.index), reader.frame[:AesGcmFrameSize], reader.cipher.aad(false))
+			reader.index++
+			reader.frame[0] = reader.frame[AesGcmFrameSize]
+			reader.pending = 1
+		case io.EOF, io.ErrUnexpectedEOF:
+			if n > 0 || (reader.final && reader.index == 0) {
+				reader.buf = reader.cipher.aead.Seal(reader.buf, reader.cipher.nonce(reader.index), reader.frame[:n], reader.cipher.aad(reader.final))
+				reader.index++
+			}
+			err = io.EOF
+		}
+		reader.err = err
+	}
+	n := copy(data, reader.buf[reader.offset:])
+	reader.offset += n
+	return n, nil
+}
+
+func (c *aesGcm) Decrypt(src io.Reader) io.Reader {
+	return c.decrypt(src, true)
+}
+
+// decrypt checks that the last frame is the final one of the object if final is true,
+// it is false if the frames do not reach the end of the object.
+func (c *aesGcm) decrypt(src io.Reader, final bool) io.Reader {
+	return &gcmDecryptReader{
+		cipher: c,
+		src:    src,
+		final:  final,
+		frame:  make([]byte, AesGcmFrameSize+AesGcmTagSize+1),
+	}
+}
+
+type gcmDecryptReader struct {
+	cipher *aesGcm
+	src    io.Reader
+	final  bool
+	index  uint64
+	// a frame and one byte of the next frame, which tells whether the frame is the last one
+	frame   []byte
+	pending int
+	buf     []byte
+	offset  int
+	err     error
+}
+
+func (reader *gcmDecryptReader) Read(data []byte) (int, error) {
+	for reader.offset >= len(reader.buf) {
+		if reader.err != nil {
+			return 0, reader.err
+		}
+		n, err := io.ReadFull(reader.src, reader.frame[reader.pending:])
+		n += reader.pending
+		reader.pending = 0
+		reader.buf = reader.buf[:0]
+		reader.offset = 0
+		last := false
+		switch err {
+		case nil:
+			n = AesGcmFrameSize + AesGcmTagSize
+		case io.EOF, io.ErrUnexpectedEOF:
+			last = true
+			err = io.EOF
+		default:
+			reader.err = err
+			continue
+		}
+		if n == 0 && !(reader.final && reader.index == 0) {
+			reader.err = err
+			continue
+		}
+		if n < AesGcmTagSize {
+			reader.err = fmt.Errorf("aes gcm: frame %d is truncated", reader.index)
+			return 0, reader.err
+		}
+		buf, oerr := reader.cipher.aead.Open(reader.buf, reader.cipher.nonce(reader.index), reader.frame[:n], reader.cipher.aad(last && reader.final))
+		if oerr != nil {
+			reader.err = fmt.Errorf("aes gcm: frame %d is tampered or the frames after it are removed, %w", reader.index, oerr)
+			return 0, reader.err
+		}
+		reader.buf = buf
+		reader.index++
+		if !last {
+			reader.frame[0] = reader.frame[n]
+			reader.pending = 1
+		}
+		reader.err = err
+	}
+	n := copy(data, reader.buf[reader.offset:])
+	reader.offset += n
+	return n, nil
+}
@@ -0,0 +1,240 @@
+package crypto
+
+import (
+	"fmt"
+	"io"
+)
+
+const (
+	// AesGcmFrameSize is the size of the plain data in a frame, the last frame may be shorter
+	AesGcmFrameSize = 4096
+
+	// AesGcmTagSize is the size of the authentication tag appended to each frame
+	AesGcmTagSize = 16
+)
+
+// AesGcmEncryptedLen returns the length of the data encrypted by the aes gcm cipher,
+// the empty data is encrypted to an empty final frame
+func AesGcmEncryptedLen(plainTextLen int64) int64 {
+	if plainTextLen < 0 {
+		return plainTextLen
+	}
+	if plainTextLen == 0 {
+		return AesGcmTagSize
+	}
+	return AesGcmEncryptedOffset(plainTextLen)
+}
+
+// AesGcmEncryptedOffset returns the offset in the encrypted data of the plain data offset
+func AesGcmEncryptedOffset(plainOffset int64) int64 {
+	if plainOffset <= 0 {
+		return plainOffset
+	}
+	frames := (plainOffset + AesGcmFrameSize - 1) / AesGcmFrameSize
+	return plainOffset + frames*AesGcmTagSize
+}
+
+// AesGcmDecryptedLen returns the length of the plain data encrypted by the aes gcm cipher
+func AesGcmDecryptedLen(encryptedLen int64) int64 {
+	if encryptedLen <= 0 {
+		return encryptedLen
+	}
+	frames := encryptedLen / (AesGcmFrameSize + AesGcmTagSize)
+	plainTextLen := frames * AesGcmFrameSize
+	if remain := encryptedLen % (AesGcmFrameSize + AesGcmTagSize); remain > AesGcmTagSize {
+		plainTextLen += remain - AesGcmTagSize
+	}
+	return plainTextLen
+}
+
+// aesGcmCipherBuilder for building ContentCipher
+type aesGcmCipherBuilder struct {
+	MasterCipher MasterCipher
+}
+
+// aesGcmCipher will use aes gcm algorithm, the data is authenticated frame by frame
+type aesGcmCipher struct {
+	CipherData CipherData
+	Cipher     Cipher
+}
+
+// CreateAesGcmCipher creates ContentCipherBuilder
+func CreateAesGcmCipher(cipher MasterCipher) ContentCipherBuilder {
+	return aesGcmCipherBuilder{MasterCipher: cipher}
+}
+
+// contentCipherCD is used to create ContentCipher with CipherData
+func (builder aesGcmCipherBuilder) contentCipherCD(cd CipherData) (ContentCipher, error) {
+	cipher, err := newAesGcm(cd)
+	if err != nil {
+		return nil, err
+	}
+
+	return &aesGcmCipher{
+		CipherData: cd,
+		Cipher:     cipher,
+	}, nil
+}
+
+// ContentCipher is used to create ContentCipher interface
+func (builder aesGcmCipherBuilder) ContentCipher() (ContentCipher, error) {
+	cd, err := newCipherData(builder.MasterCipher, AesGcmAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+	return builder.contentCipherCD(cd)
+}
+
+// ContentCipherEnv is used to create a decrption ContentCipher from Envelope
+func (builder aesGcmCipherBuilder) ContentCipherEnv(envelope Envelope) (ContentCipher, error) {
+	cd, err := newCipherDataFromEnvelope(builder.MasterCipher, envelope)
+	if err != nil {
+		return nil, err
+	}
+	return builder.contentCipherCD(cd)
+}
+
+// GetMatDesc is used to get MasterCipher's MatDesc
+func (builder aesGcmCipherBuilder) GetMatDesc() string {
+	return builder.MasterCipher.GetMatDesc()
+}
+
+// EncryptContent will encrypt the data using gcm frame by frame, the last frame is sealed as the final one
+func (cc *aesGcmCipher) EncryptContent(src io.Reader) (io.ReadCloser, error) {
+	return cc.EncryptContentPart(src, true)
+}
+
+// EncryptContentPart encrypts a part of the object, the last frame is sealed as the final one only in the last part
+func (cc *aesGcmCipher) EncryptContentPart(src io.Reader, last bool) (io.ReadCloser, error) {
+	if sr, ok := src.(io.ReadSeeker); ok {
+		if curr, err := sr.Seek(0, io.SeekCurrent); err == nil {
+			return &aesGcmSeekEncrypter{
+				Body:   sr,
+				Start:  curr,
+				Offset: curr,
+				final:  last,
+				cc:     cc,
+			}, nil
+		}
+	}
+	reader := cc.Cipher.(*aesGcm).encrypt(src, last)
+	return &CryptoEncrypter{Body: src, Encrypter: reader}, nil
+}
+
+// DecryptContent is used to decrypt object using gcm, Read returns an error if a frame is tampered
+// or the frames are removed from the end of the object
+func (cc *aesGcmCipher) DecryptContent(src io.Reader) (io.ReadCloser, error) {
+	return cc.DecryptContentRange(src, true)
+}
+
+// DecryptContentRange decrypts the frames of a range, toEnd tells whether the range reaches the end of the object
+func (cc *aesGcmCipher) DecryptContentRange(src io.Reader, toEnd bool) (io.ReadCloser, error) {
+	reader := cc.Cipher.(*aesGcm).decrypt(src, toEnd)
+	return &CryptoDecrypter{Body: src, Decrypter: reader}, nil
+}
+
+// GetCipherData is used to get cipher data information
+func (cc *aesGcmCipher) GetCipherData() *CipherData {
+	return &(cc.CipherData)
+}
+
+// GetEncryptedLen returns the encrypted length, a tag is appended to each frame
+func (cc *aesGcmCipher) GetEncryptedLen(plainTextLen int64) int64 {
+	return AesGcmEncryptedLen(plainTextLen)
+}
+
+// GetAlignLen is used to get align length, the data is decrypted from a frame boundary
+func (cc *aesGcmCipher) GetAlignLen() int {
+	return AesGcmFrameSize
+}
+
+// Clone is used to create a new aesGcmCipher from itself
+func (cc *aesGcmCipher) Clone(cd CipherData) (ContentCipher, error) {
+	cipher, err := newAesGcm(cd)
+	if err != nil {
+		return nil, err
+	}
+
+	return &aesGcmCipher{
+		CipherData: cd,
+		Cipher:     cipher,
+	}, nil
+}
+
+// aesGcmSeekEncrypter provides close and seek method for Encrypter,
+// the offsets of Seek are in the encrypted data, so the length of the encrypted data can be got by seeking
+type aesGcmSeekEncrypter struct {
+	Body      io.ReadSeeker
+	Encrypter io.Reader
+	isClosed  bool
+	Start     int64
+	Offset    int64
+	final     bool
+	cc        *aesGcmCipher
+}
+
+// Close lets the aesGcmSeekEncrypter satisfy io.ReadCloser interface
+func (rc *aesGcmSeekEncrypter) Close() error {
+	rc.isClosed = true
+	if closer, ok := rc.Body.(io.ReadCloser); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Read lets the aesGcmSeekEncrypter satisfy io.ReadCloser interface
+func (rc *aesGcmSeekEncrypter) Read(b []byte) (int, error) {
+	if rc.isClosed {
+		return 0, io.EOF
+	}
+	if rc.Encrypter == nil {
+		if rc.Start != rc.Offset {
+			return 0, fmt.Errorf("Cant not encrypt from offset %v, must start from %v", rc.Offset, rc.Start)
+		}
+		rc.Encrypter = rc.cc.Cipher.(*aesGcm).encrypt(rc.Body, rc.final)
+	}
+	return rc.Encrypter.Read(b)
+}
+
+// Seek lets the aesGcmSeekEncrypter satisfy io.Seeker interface
+func (rc *aesGcmSeekEncrypter) Seek(offset int64, whence int) (int64, error) {
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = rc.encryptedOffset(rc.Offset) + offset
+	case io.SeekEnd:
+		end, err := rc.Body.Seek(0, io.SeekEnd)
+		if err != nil {
+			return 0, err
+		}
+		if end == rc.Start && rc.final && offset == 0 {
+			// the empty data is encrypted to an empty final frame
+			rc.Encrypter = nil
+			rc.Offset = end
+			return end + AesGcmTagSize, nil
+		}
+		pos = rc.encryptedOffset(end) + offset
+	default:
+		return 0, fmt.Errorf("invalid whence")
+	}
+
+	plainPos := pos
+	if pos > rc.Start {
+		plainPos = rc.Start + AesGcmDecryptedLen(pos-rc.Start)
+	}
+	off, err := rc.Body.Seek(plainPos, io.SeekStart)
+	//Reset Encrypter Reader
+	rc.Encrypter = nil
+	rc.Offset = off
+
+	return rc.encryptedOffset(off), err
+}
+
+func (rc *aesGcmSeekEncrypter) encryptedOffset(plainOffset int64) int64 {
+	if plainOffset <= rc.Start {
+		return plainOffset
+	}
+	return rc.Start + AesGcmEncryptedOffset(plainOffset-rc.Start)
+}
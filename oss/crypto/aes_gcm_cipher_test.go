@@ -0,0 +1,218 @@
+package crypto
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAesGcmLen(t *testing.T) {
+	assert.Equal(t, int64(16), AesGcmEncryptedLen(0))
+	assert.Equal(t, int64(0), AesGcmEncryptedOffset(0))
+	assert.Equal(t, int64(4112), AesGcmEncryptedOffset(4096))
+	assert.Equal(t, int64(17), AesGcmEncryptedLen(1))
+	assert.Equal(t, int64(4112), AesGcmEncryptedLen(4096))
+	assert.Equal(t, int64(4129), AesGcmEncryptedLen(4097))
+
+	for _, n := range []int64{0, 1, 15, 16, 17, 4095, 4096, 4097, 8192, 10000} {
+		assert.Equal(t, n, AesGcmDecryptedLen(AesGcmEncryptedLen(n)))
+	}
+}
+
+func TestAesGcm(t *testing.T) {
+	masterRsaCipher, _ := CreateMasterRsa(matDesc, rsaPublicKey, rsaPrivateKey)
+	builder := CreateAesGcmCipher(masterRsaCipher)
+
+	for _, n := range []int{0, 1, 4095, 4096, 4097, 3*4096 + 123} {
+		data := []byte(randStr(n))
+		cc, err := builder.ContentCipher()
+		assert.Nil(t, err)
+		cd := cc.GetCipherData()
+		assert.Equal(t, AesGcmAlgorithm, cd.CEKAlgorithm)
+		assert.Equal(t, AesGcmFrameSize, cc.GetAlignLen())
+
+		reader, err := cc.EncryptContent(bytes.NewReader(data))
+		assert.Nil(t, err)
+		encrypted, err := io.ReadAll(reader)
+		assert.Nil(t, err)
+		assert.Equal(t, cc.GetEncryptedLen(int64(n)), int64(len(encrypted)))
+
+		envelope := Envelope{
+			IV:        string(cd.EncryptedIV),
+			CipherKey: string(cd.EncryptedKey),
+			MatDesc:   cd.MatDesc,
+			WrapAlg:   cd.WrapAlgorithm,
+			CEKAlg:    cd.CEKAlgorithm,
+		}
+		dcc, err := builder.ContentCipherEnv(envelope)
+		assert.Nil(t, err)
+		reader, err = dcc.DecryptContent(bytes.NewReader(encrypted))
+		assert.Nil(t, err)
+		decrypted, err := io.ReadAll(reader)
+		assert.Nil(t, err)
+		assert.EqualValues(t, data, decrypted)
+
+		// decrypt from the second frame
+		if n > AesGcmFrameSize {
+			seekCd := dcc.GetCipherData().Clone()
+			seekCd.SeekIV(AesGcmFrameSize)
+			scc, err := dcc.Clone(seekCd)
+			assert.Nil(t, err)
+			reader, err = scc.DecryptContent(bytes.NewReader(encrypted[AesGcmFrameSize+AesGcmTagSize:]))
+			assert.Nil(t, err)
+			decrypted, err = io.ReadAll(reader)
+			assert.Nil(t, err)
+			assert.EqualValues(t, data[AesGcmFrameSize:], decrypted)
+		}
+
+		// the empty final frame is removed
+		if n == 0 {
+			assert.Len(t, encrypted, AesGcmTagSize)
+			reader, _ = dcc.DecryptContent(bytes.NewReader(nil))
+			_, err = io.ReadAll(reader)
+			assert.NotNil(t, err)
+			assert.Contains(t, err.Error(), "is truncated")
+			continue
+		}
+
+		// tampered
+		tampered := append([]byte{}, encrypted...)
+		tampered[len(tampered)/2] ^= 0x01
+		reader, _ = dcc.DecryptContent(bytes.NewReader(tampered))
+		_, err = io.ReadAll(reader)
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "is tampered")
+
+		// the frames are reordered
+		if n > 2*AesGcmFrameSize {
+			frameLen := AesGcmFrameSize + AesGcmTagSize
+			reordered := append([]byte{}, encrypted[frameLen:2*frameLen]...)
+			reordered = append(reordered, encrypted[:frameLen]...)
+			reordered = append(reordered, encrypted[2*frameLen:]...)
+			reader, _ = dcc.DecryptContent(bytes.NewReader(reordered))
+			_, err = io.ReadAll(reader)
+			assert.NotNil(t, err)
+		}
+
+		// truncated
+		reader, _ = dcc.DecryptContent(bytes.NewReader(encrypted[:len(encrypted)-AesGcmTagSize]))
+		_, err = io.ReadAll(reader)
+		assert.NotNil(t, err)
+
+		// truncated at a frame boundary
+		if n > AesGcmFrameSize {
+			frameLen := AesGcmFrameSize + AesGcmTagSize
+			reader, _ = dcc.DecryptContent(bytes.NewReader(encrypted[:frameLen]))
+			_, err = io.ReadAll(reader)
+			assert.NotNil(t, err)
+			assert.Contains(t, err.Error(), "frame 0 is tampered or the frames after it are removed")
+
+			// the range does not reach the end of the object
+			reader, err = dcc.(*aesGcmCipher).DecryptContentRange(bytes.NewReader(encrypted[:frameLen]), false)
+			assert.Nil(t, err)
+			decrypted, err = io.ReadAll(reader)
+			assert.Nil(t, err)
+			assert.EqualValues(t, data[:AesGcmFrameSize], decrypted)
+
+			// the final frame is not in the middle of the object
+			reader, _ = dcc.(*aesGcmCipher).DecryptContentRange(bytes.NewReader(encrypted), false)
+			_, err = io.ReadAll(reader)
+			assert.NotNil(t, err)
+		}
+	}
+}
+
+func TestAesGcmParts(t *testing.T) {
+	masterRsaCipher, _ := CreateMasterRsa(matDesc, rsaPublicKey, rsaPrivateKey)
+	cc, err := CreateAesGcmCipher(masterRsaCipher).ContentCipher()
+	assert.Nil(t, err)
+
+	partSize := 2 * AesGcmFrameSize
+	for _, n := range []int{2 * partSize, 2*partSize + 123} {
+		data := []byte(randStr(n))
+		var encrypted []byte
+		for offset := 0; offset < n; offset += partSize {
+			end := offset + partSize
+			if end > n {
+				end = n
+			}
+			cd := cc.GetCipherData().Clone()
+			cd.SeekIV(uint64(offset))
+			pcc, err := cc.Clone(cd)
+			assert.Nil(t, err)
+			// not seekable
+			reader, err := pcc.(*aesGcmCipher).EncryptContentPart(io.LimitReader(bytes.NewReader(data[offset:end]), int64(end-offset)), end == n)
+			assert.Nil(t, err)
+			part, err := io.ReadAll(reader)
+			assert.Nil(t, err)
+			assert.Equal(t, pcc.GetEncryptedLen(int64(end-offset)), int64(len(part)))
+			encrypted = append(encrypted, part...)
+		}
+
+		reader, err := cc.DecryptContent(bytes.NewReader(encrypted))
+		assert.Nil(t, err)
+		decrypted, err := io.ReadAll(reader)
+		assert.Nil(t, err)
+		assert.EqualValues(t, data, decrypted)
+
+		// the last part is removed
+		reader, _ = cc.DecryptContent(bytes.NewReader(encrypted[:AesGcmEncryptedLen(int64(partSize))]))
+		_, err = io.ReadAll(reader)
+		assert.NotNil(t, err)
+	}
+}
+
+func TestAesGcmSeekEncrypter(t *testing.T) {
+	masterRsaCipher, _ := CreateMasterRsa(matDesc, rsaPublicKey, rsaPrivateKey)
+	cc, err := CreateAesGcmCipher(masterRsaCipher).ContentCipher()
+	assert.Nil(t, err)
+
+	data := []byte(randStr(10000))
+	body := bytes.NewReader(data)
+	body.Seek(100, io.SeekStart)
+	reader, err := cc.EncryptContent(body)
+	assert.Nil(t, err)
+	seeker, ok := reader.(io.ReadSeeker)
+	assert.True(t, ok)
+
+	// the offsets are in the encrypted data
+	curr, err := seeker.Seek(0, io.SeekCurrent)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(100), curr)
+	end, err := seeker.Seek(0, io.SeekEnd)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(100)+AesGcmEncryptedLen(9900), end)
+	_, err = seeker.Seek(curr, io.SeekStart)
+	assert.Nil(t, err)
+
+	encrypted, err := io.ReadAll(seeker)
+	assert.Nil(t, err)
+	assert.Len(t, encrypted, int(AesGcmEncryptedLen(9900)))
+
+	// read again after seeking to the start
+	_, err = seeker.Seek(curr, io.SeekStart)
+	assert.Nil(t, err)
+	encrypted2, err := io.ReadAll(seeker)
+	assert.Nil(t, err)
+	assert.EqualValues(t, encrypted, encrypted2)
+
+	// the empty data
+	reader, err = cc.EncryptContent(bytes.NewReader(nil))
+	assert.Nil(t, err)
+	end, err = reader.(io.Seeker).Seek(0, io.SeekEnd)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(AesGcmTagSize), end)
+	_, err = reader.(io.Seeker).Seek(0, io.SeekStart)
+	assert.Nil(t, err)
+	encrypted, err = io.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.Len(t, encrypted, AesGcmTagSize)
+
+	// can not encrypt from the middle
+	_, err = seeker.Seek(curr+AesGcmFrameSize+AesGcmTagSize, io.SeekStart)
+	assert.Nil(t, err)
+	_, err = seeker.Read(make([]byte, 10))
+	assert.NotNil(t, err)
+}
@@ -5,4 +5,5 @@ const (
 	RsaCryptoWrap    string = "RSA/NONE/PKCS1Padding"
 	KmsAliCryptoWrap string = "KMS/ALICLOUD"
 	AesCtrAlgorithm  string = "AES/CTR/NoPadding"
+	AesGcmAlgorithm  string = "AES/GCM-FRAMED/NoPadding"
 )
@@ -0,0 +1,114 @@
+package crypto
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// MemoryKmsClient is an in-memory key management service, the keys are lost when the process exits.
+// It is a stand-in of the real service for tests.
+type MemoryKmsClient struct {
+	mu   sync.Mutex
+	keys map[string][]byte
+}
+
+// NewMemoryKmsClient returns an in-memory kms with the keys
+func NewMemoryKmsClient(keyIds ...string) *MemoryKmsClient {
+	c := &MemoryKmsClient{keys: map[string][]byte{}}
+	for _, keyId := range keyIds {
+		c.CreateKey(keyId)
+	}
+	return c
+}
+
+// CreateKey creates a random key with the key id if it does not exist
+func (c *MemoryKmsClient) CreateKey(keyId string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.keys[keyId]; ok {
+		return nil
+	}
+	key := make([]byte, aesKeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return err
+	}
+	c.keys[keyId] = key
+	return nil
+}
+
+// DeleteKey deletes the key, the data encrypted with it can not be decrypted anymore
+func (c *MemoryKmsClient) DeleteKey(keyId string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.keys, keyId)
+}
+
+func (c *MemoryKmsClient) aead(keyId string) (cipher.AEAD, error) {
+	c.mu.Lock()
+	key, ok := c.keys[keyId]
+	c.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("kms key %s is not found", keyId)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Encrypt the ciphertext blob is the length of the key id, the key id, the nonce and the sealed data
+func (c *MemoryKmsClient) Encrypt(_ context.Context, keyId string, plaintext []byte) ([]byte, error) {
+	aead, err := c.aead(keyId)
+	if err != nil {
+		return nil, err
+	}
+	blob := make([]byte, 2+len(keyId)+aead.NonceSize())
+	binary.BigEndian.PutUint16(blob, uint16(len(keyId)))
+	copy(blob[2:], keyId)
+	nonce := blob[2+len(keyId):]
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(blob, nonce, plaintext, []byte(keyId)), nil
+}
+
+// Decrypt the key is identified by the key id in the ciphertext blob
+func (c *MemoryKmsClient) Decrypt(_ context.Context, ciphertextBlob []byte) ([]byte, error) {
+	if len(ciphertextBlob) < 2 {
+		return nil, fmt.Errorf("invalid ciphertext blob")
+	}
+	n := int(binary.BigEndian.Uint16(ciphertextBlob))
+	if len(ciphertextBlob) < 2+n {
+		return nil, fmt.Errorf("invalid ciphertext blob")
+	}
+	keyId := string(ciphertextBlob[2 : 2+n])
+	aead, err := c.aead(keyId)
+	if err != nil {
+		return nil, err
+	}
+	data := ciphertextBlob[2+n:]
+	if len(data) < aead.NonceSize() {
+		return nil, fmt.Errorf("invalid ciphertext blob")
+	}
+	return aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], []byte(keyId))
+}
+
+// GenerateDataKey returns a random data key and the data key encrypted by Encrypt
+func (c *MemoryKmsClient) GenerateDataKey(ctx context.Context, keyId string, numberOfBytes int) ([]byte, []byte, error) {
+	plaintext := make([]byte, numberOfBytes)
+	if _, err := io.ReadFull(rand.Reader, plaintext); err != nil {
+		return nil, nil, err
+	}
+	ciphertextBlob, err := c.Encrypt(ctx, keyId, plaintext)
+	if err != nil {
+		return nil, nil, err
+	}
+	return plaintext, ciphertextBlob, nil
+}
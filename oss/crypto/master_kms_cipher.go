@@ -0,0 +1,95 @@
+package crypto
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// KmsKeyIdMatDesc is the name of the key id in the MatDesc of the kms master key
+const KmsKeyIdMatDesc = "kms-key-id"
+
+// KmsClient is the interface of the key management service used by the kms master key
+type KmsClient interface {
+	// Encrypt encrypts the plaintext with the key, and returns the ciphertext blob
+	Encrypt(ctx context.Context, keyId string, plaintext []byte) ([]byte, error)
+
+	// Decrypt decrypts the ciphertext blob, the key is identified by the blob
+	Decrypt(ctx context.Context, ciphertextBlob []byte) ([]byte, error)
+
+	// GenerateDataKey returns a random data key, in plaintext and encrypted with the key
+	GenerateDataKey(ctx context.Context, keyId string, numberOfBytes int) (plaintext []byte, ciphertextBlob []byte, err error)
+}
+
+// DataKeyGenerator is implemented by the master key that generates the data key itself,
+// so the data key never leaves the key management service in plaintext before it is used
+type DataKeyGenerator interface {
+	GenerateDataKey(keyLen int) (plainKey []byte, encryptedKey []byte, err error)
+}
+
+// CreateMasterKms Create master key interface implemented by kms
+// the keyId is recorded in the matDesc, matDesc will be converted to json string
+func CreateMasterKms(matDesc map[string]string, keyId string, client KmsClient) (MasterCipher, error) {
+	var masterCipher MasterKmsCipher
+	if len(keyId) == 0 {
+		return masterCipher, fmt.Errorf("kms key id is empty")
+	}
+	if client == nil {
+		return masterCipher, fmt.Errorf("kms client is null")
+	}
+
+	desc := map[string]string{}
+	for k, v := range matDesc {
+		desc[k] = v
+	}
+	desc[KmsKeyIdMatDesc] = keyId
+	b, err := json.Marshal(desc)
+	if err != nil {
+		return masterCipher, err
+	}
+	masterCipher.MatDesc = string(b)
+	masterCipher.KeyId = keyId
+	masterCipher.Client = client
+	return masterCipher, nil
+}
+
+// MasterKmsCipher kms master key interface
+type MasterKmsCipher struct {
+	MatDesc string
+	KeyId   string
+	Client  KmsClient
+}
+
+// GetWrapAlgorithm get master key wrap algorithm
+func (mkc MasterKmsCipher) GetWrapAlgorithm() string {
+	return KmsAliCryptoWrap
+}
+
+// GetMatDesc get master key describe
+func (mkc MasterKmsCipher) GetMatDesc() string {
+	return mkc.MatDesc
+}
+
+// Encrypt encrypt data by kms
+// Mainly used to encrypt object's symmetric secret key and iv
+func (mkc MasterKmsCipher) Encrypt(plainData []byte) ([]byte, error) {
+	return mkc.Client.Encrypt(context.Background(), mkc.KeyId, plainData)
+}
+
+// Decrypt decrypt data by kms
+// Mainly used to decrypt object's symmetric secret key and iv
+func (mkc MasterKmsCipher) Decrypt(cryptoData []byte) ([]byte, error) {
+	return mkc.Client.Decrypt(context.Background(), cryptoData)
+}
+
+// GenerateDataKey generate object's symmetric secret key by kms
+func (mkc MasterKmsCipher) GenerateDataKey(keyLen int) ([]byte, []byte, error) {
+	plainKey, encryptedKey, err := mkc.Client.GenerateDataKey(context.Background(), mkc.KeyId, keyLen)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(plainKey) != keyLen {
+		return nil, nil, fmt.Errorf("kms returns data key of length %d, expect %d", len(plainKey), keyLen)
+	}
+	return plainKey, encryptedKey, nil
+}
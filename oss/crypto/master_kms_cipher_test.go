@@ -0,0 +1,74 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMasterKms(t *testing.T) {
+	kms := NewMemoryKmsClient("key-1")
+
+	_, err := CreateMasterKms(nil, "", kms)
+	assert.NotNil(t, err)
+	_, err = CreateMasterKms(nil, "key-1", nil)
+	assert.NotNil(t, err)
+
+	mc, err := CreateMasterKms(map[string]string{"tag": "value"}, "key-1", kms)
+	assert.Nil(t, err)
+	assert.Equal(t, KmsAliCryptoWrap, mc.GetWrapAlgorithm())
+	assert.Equal(t, "{\"kms-key-id\":\"key-1\",\"tag\":\"value\"}", mc.GetMatDesc())
+
+	blob, err := mc.Encrypt([]byte("hello"))
+	assert.Nil(t, err)
+	plain, err := mc.Decrypt(blob)
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", string(plain))
+
+	// tampered
+	blob[len(blob)-1] ^= 0xff
+	_, err = mc.Decrypt(blob)
+	assert.NotNil(t, err)
+	_, err = mc.Decrypt([]byte{0})
+	assert.NotNil(t, err)
+
+	// the data key is generated by kms
+	builder := CreateAesCtrCipher(mc)
+	cc, err := builder.ContentCipher()
+	assert.Nil(t, err)
+	cd := cc.GetCipherData()
+	assert.Len(t, cd.Key, aesKeySize)
+	key, err := kms.Decrypt(context.TODO(), cd.EncryptedKey)
+	assert.Nil(t, err)
+	assert.Equal(t, cd.Key, key)
+
+	reader, err := cc.EncryptContent(bytes.NewReader([]byte("hello world")))
+	assert.Nil(t, err)
+	encrypted, err := io.ReadAll(reader)
+	assert.Nil(t, err)
+
+	cc2, err := builder.ContentCipherEnv(Envelope{
+		IV:        string(cd.EncryptedIV),
+		CipherKey: string(cd.EncryptedKey),
+		MatDesc:   cd.MatDesc,
+		WrapAlg:   cd.WrapAlgorithm,
+		CEKAlg:    cd.CEKAlgorithm,
+	})
+	assert.Nil(t, err)
+	reader, err = cc2.DecryptContent(bytes.NewReader(encrypted))
+	assert.Nil(t, err)
+	decrypted, err := io.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, "hello world", string(decrypted))
+
+	// unknown key
+	mc, err = CreateMasterKms(nil, "key-2", kms)
+	assert.Nil(t, err)
+	_, err = mc.Encrypt([]byte("hello"))
+	assert.NotNil(t, err)
+	_, err = CreateAesCtrCipher(mc).ContentCipher()
+	assert.NotNil(t, err)
+}
@@ -1,6 +1,9 @@
 package oss
 
-import "os"
+import (
+	"os"
+	"time"
+)
 
 const (
 	MaxUploadParts int32 = 10000
@@ -47,6 +50,9 @@ const (
 	// DefaultCopyThreshold Default threshold to use muitipart copy in Copier, 256M
 	DefaultCopyThreshold int64 = 200 * 1024 * 1024
 
+	// DefaultPresignExpires Default expiration duration of the presigned requests, 15 minutes
+	DefaultPresignExpires = 15 * time.Minute
+
 	// FilePermMode File permission
 	FilePermMode = os.FileMode(0664)
 
@@ -0,0 +1,219 @@
+package oss
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Diagnostics records how an operation went, it is available on the result by ResultCommon.Diagnostics
+// and on the error by OperationError.Diagnostics.
+type Diagnostics struct {
+	Operation string
+
+	// The endpoint host of the last attempt.
+	Endpoint string
+
+	// The attempts, in the order they are sent.
+	Attempts []AttemptDiagnostics
+
+	// The clock offset applied when signing the request.
+	ClockOffset time.Duration
+
+	// The bytes of the request body sent, including the retries.
+	BytesSent int64
+
+	// The duration of the operation, until the response headers of the last attempt are received.
+	Total time.Duration
+
+	mu            sync.Mutex
+	done          bool
+	bytesReceived int64
+}
+
+// AttemptDiagnostics records an http attempt. The durations are zero if the phase does not happen,
+// e.g. no DNS lookup and no connect for a reused connection.
+type AttemptDiagnostics struct {
+	Number int
+
+	Endpoint string
+
+	// The delay before the attempt.
+	Delay time.Duration
+
+	StatusCode int
+
+	RequestId string
+
+	Err error
+
+	ReusedConn bool
+
+	DNS time.Duration
+
+	Connect time.Duration
+
+	TLS time.Duration
+
+	// From the start of the attempt to the first byte of the response.
+	TimeToFirstByte time.Duration
+
+	// From the start of the attempt to the end of the response headers, or the error.
+	Total time.Duration
+
+	start      time.Time
+	dnsStart   time.Time
+	connStart  time.Time
+	tlsStart   time.Time
+	firstByte  bool
+	connecting bool
+}
+
+// BytesReceived returns the bytes of the response body read so far.
+// If the body is returned to the caller, e.g. GetObject, it grows as the body is read.
+func (d *Diagnostics) BytesReceived() int64 {
+	return atomic.LoadInt64(&d.bytesReceived)
+}
+
+func (d *Diagnostics) startAttempt(number int, delay time.Duration, endpoint string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.Endpoint = endpoint
+	d.Attempts = append(d.Attempts, AttemptDiagnostics{
+		Number:   number,
+		Endpoint: endpoint,
+		Delay:    delay,
+		start:    time.Now(),
+	})
+}
+
+func (d *Diagnostics) endAttempt(response *http.Response, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	a := d.current()
+	if a == nil {
+		return
+	}
+	a.Total = time.Since(a.start)
+	a.Err = err
+	if response != nil {
+		a.StatusCode = response.StatusCode
+		a.RequestId = response.Header.Get(HeaderOssRequestID)
+	}
+}
+
+// current returns the attempt in progress, the caller must hold the lock.
+func (d *Diagnostics) current() *AttemptDiagnostics {
+	if len(d.Attempts) == 0 {
+		return nil
+	}
+	return &d.Attempts[len(d.Attempts)-1]
+}
+
+func (d *Diagnostics) update(fn func(a *AttemptDiagnostics, now time.Time)) {
+	now := time.Now()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	// the canceled hedged requests may report after the operation ends
+	if d.done {
+		return
+	}
+	if a := d.current(); a != nil {
+		fn(a, now)
+	}
+}
+
+func (d *Diagnostics) finish(start time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.done = true
+	d.Total = time.Since(start)
+}
+
+// clientTrace records the phases of the http attempts, the hedged requests share the attempt.
+func (d *Diagnostics) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			d.update(func(a *AttemptDiagnostics, _ time.Time) {
+				a.ReusedConn = info.Reused
+			})
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			d.update(func(a *AttemptDiagnostics, now time.Time) {
+				a.dnsStart = now
+			})
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			d.update(func(a *AttemptDiagnostics, now time.Time) {
+				if !a.dnsStart.IsZero() {
+					a.DNS = now.Sub(a.dnsStart)
+				}
+			})
+		},
+		ConnectStart: func(_, _ string) {
+			d.update(func(a *AttemptDiagnostics, now time.Time) {
+				if !a.connecting {
+					a.connecting = true
+					a.connStart = now
+				}
+			})
+		},
+		ConnectDone: func(_, _ string, err error) {
+			d.update(func(a *AttemptDiagnostics, now time.Time) {
+				if a.connecting && (err == nil || a.Connect == 0) {
+					a.Connect = now.Sub(a.connStart)
+				}
+			})
+		},
+		TLSHandshakeStart: func() {
+			d.update(func(a *AttemptDiagnostics, now time.Time) {
+				a.tlsStart = now
+			})
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			d.update(func(a *AttemptDiagnostics, now time.Time) {
+				if !a.tlsStart.IsZero() {
+					a.TLS = now.Sub(a.tlsStart)
+				}
+			})
+		},
+		GotFirstResponseByte: func() {
+			d.update(func(a *AttemptDiagnostics, now time.Time) {
+				if !a.firstByte {
+					a.firstByte = true
+					a.TimeToFirstByte = now.Sub(a.start)
+				}
+			})
+		},
+	}
+}
+
+type diagnosticsKey struct{}
+
+func withDiagnostics(ctx context.Context, d *Diagnostics) context.Context {
+	return httptrace.WithClientTrace(context.WithValue(ctx, diagnosticsKey{}, d), d.clientTrace())
+}
+
+// diagnosticsFromContext returns the diagnostics of the operation, or a discarded one.
+func diagnosticsFromContext(ctx context.Context) *Diagnostics {
+	if d, ok := ctx.Value(diagnosticsKey{}).(*Diagnostics); ok {
+		return d
+	}
+	return &Diagnostics{}
+}
+
+type diagnosticsReadCloser struct {
+	io.ReadCloser
+	d *Diagnostics
+}
+
+func (r *diagnosticsReadCloser) Read(p []byte) (n int, err error) {
+	n, err = r.ReadCloser.Read(p)
+	atomic.AddInt64(&r.d.bytesReceived, int64(n))
+	return n, err
+}
@@ -0,0 +1,121 @@
+package oss
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/credentials"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiagnostics_Result(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Header().Set("x-oss-request-id", "id-1234")
+		w.WriteHeader(200)
+		io.WriteString(w, "hello world")
+	}))
+	defer server.Close()
+
+	cfg := LoadDefaultConfig().
+		WithCredentialsProvider(credentials.NewAnonymousCredentialsProvider()).
+		WithRegion("cn-hangzhou").
+		WithEndpoint(server.URL).
+		WithInsecureSkipVerify(true)
+	client := NewClient(cfg)
+	u, _ := url.Parse(server.URL)
+
+	putResult, err := client.PutObject(context.TODO(), &PutObjectRequest{
+		Bucket: Ptr("bucket"),
+		Key:    Ptr("key"),
+		Body:   strings.NewReader("hello oss"),
+	})
+	assert.Nil(t, err)
+	diag := putResult.Diagnostics()
+	assert.NotNil(t, diag)
+	assert.Equal(t, "PutObject", diag.Operation)
+	assert.Equal(t, u.Host, diag.Endpoint)
+	assert.Equal(t, int64(9), diag.BytesSent)
+	assert.True(t, diag.Total > 0)
+	assert.Len(t, diag.Attempts, 1)
+	attempt := diag.Attempts[0]
+	assert.Equal(t, 1, attempt.Number)
+	assert.Equal(t, 200, attempt.StatusCode)
+	assert.Equal(t, "id-1234", attempt.RequestId)
+	assert.Nil(t, attempt.Err)
+	assert.False(t, attempt.ReusedConn)
+	assert.True(t, attempt.Connect > 0)
+	assert.True(t, attempt.TLS > 0)
+	assert.True(t, attempt.TimeToFirstByte > 0)
+	assert.True(t, attempt.Total >= attempt.TimeToFirstByte)
+
+	getResult, err := client.GetObject(context.TODO(), &GetObjectRequest{
+		Bucket: Ptr("bucket"),
+		Key:    Ptr("key"),
+	})
+	assert.Nil(t, err)
+	diag = getResult.Diagnostics()
+	assert.Equal(t, int64(0), diag.BytesReceived())
+	data, err := io.ReadAll(getResult.Body)
+	assert.Nil(t, err)
+	getResult.Body.Close()
+	assert.Equal(t, "hello world", string(data))
+	assert.Equal(t, int64(11), diag.BytesReceived())
+	assert.True(t, diag.Attempts[0].ReusedConn)
+	assert.Equal(t, int64(0), diag.Attempts[0].TLS.Nanoseconds())
+}
+
+func TestDiagnostics_OperationError(t *testing.T) {
+	server := testSetupMockServer(t, 503, map[string]string{"x-oss-request-id": "id-1234"},
+		[]byte(`<Error><Code>ServiceUnavailable</Code><Message>error</Message><RequestId>id-1234</RequestId></Error>`),
+		func(t *testing.T, r *http.Request) {})
+	defer server.Close()
+
+	cfg := LoadDefaultConfig().
+		WithCredentialsProvider(credentials.NewAnonymousCredentialsProvider()).
+		WithRegion("cn-hangzhou").
+		WithEndpoint(server.URL).
+		WithRetryMaxAttempts(3)
+	client := NewClient(cfg)
+
+	_, err := client.HeadObject(context.TODO(), &HeadObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")})
+	var operr *OperationError
+	assert.True(t, errors.As(err, &operr))
+	diag := operr.Diagnostics()
+	assert.NotNil(t, diag)
+	assert.Equal(t, "HeadObject", diag.Operation)
+	assert.Len(t, diag.Attempts, 3)
+	for i, a := range diag.Attempts {
+		assert.Equal(t, i+1, a.Number)
+		assert.Equal(t, 503, a.StatusCode)
+		var serr *ServiceError
+		assert.True(t, errors.As(a.Err, &serr))
+		if i == 0 {
+			assert.Equal(t, int64(0), a.Delay.Nanoseconds())
+		} else {
+			assert.True(t, a.Delay > 0)
+		}
+	}
+
+	// connection error
+	cfg = LoadDefaultConfig().
+		WithCredentialsProvider(credentials.NewAnonymousCredentialsProvider()).
+		WithRegion("cn-hangzhou").
+		WithEndpoint("http://127.0.0.1:1").
+		WithRetryMaxAttempts(1)
+	client = NewClient(cfg)
+	_, err = client.HeadObject(context.TODO(), &HeadObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")})
+	assert.True(t, errors.As(err, &operr))
+	diag = operr.Diagnostics()
+	assert.Len(t, diag.Attempts, 1)
+	assert.Equal(t, "127.0.0.1:1", diag.Endpoint)
+	assert.Equal(t, 0, diag.Attempts[0].StatusCode)
+	assert.NotNil(t, diag.Attempts[0].Err)
+	assert.Equal(t, int64(0), diag.Attempts[0].TimeToFirstByte.Nanoseconds())
+}
@@ -49,6 +49,8 @@ type Downloader struct {
 	options      DownloaderOptions
 	client       DownloadAPIClient
 	featureFlags FeatureFlagsType
+	tracer       Tracer
+	metrics      MetricsCollector
 }
 
 // NewDownloader creates a new Downloader instance to downloads objects.
@@ -67,6 +69,8 @@ func NewDownloader(c DownloadAPIClient, optFns ...func(*DownloaderOptions)) *Dow
 	u := &Downloader{
 		client:  c,
 		options: options,
+		tracer:  getTracer(c),
+		metrics: getMetricsCollector(c),
 	}
 
 	//Get Client Feature
@@ -102,6 +106,11 @@ func (m *DownloadError) Unwrap() error {
 }
 
 func (d *Downloader) DownloadFile(ctx context.Context, request *GetObjectRequest, filePath string, optFns ...func(*DownloaderOptions)) (result *DownloadResult, err error) {
+	ctx = ensureCorrelationId(ctx)
+	ctx, span := startSpan(ctx, d.tracer, "Downloader.DownloadFile",
+		append(downloadSpanAttributes(request), Attribute{Key: AttributeFilePath, Value: filePath})...)
+	defer func() { endSpan(span, err) }()
+
 	// Downloader wrapper
 	delegate, err := d.newDelegate(ctx, request, optFns...)
 	if err != nil {
@@ -269,6 +278,8 @@ func (d *downloaderDelegate) checkSource() error {
 	d.modTime = result.Headers.Get(HTTPHeaderLastModified)
 	d.etag = result.Headers.Get(HTTPHeaderETag)
 	d.headers = result.Headers
+	// the ranged reads of the encryption client are aligned for the content algorithm of the object
+	d.context = withContentAlg(d.context, result.Headers)
 
 	return nil
 }
@@ -549,6 +560,8 @@ func (d *downloaderDelegate) incrWritten(n int64) {
 }
 
 func (d *downloaderDelegate) downloadChunk(chunk downloaderChunk, hash hash.Hash64) (downloadedChunk, error) {
+	defer trackPartInFlight(d.base.metrics, "download", d.request.Bucket)()
+
 	// Get the next byte range of data
 	var request GetObjectRequest
 	copyRequest(&request, d.request)
@@ -4,7 +4,9 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
 
 	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/crypto"
 )
@@ -16,12 +18,19 @@ const (
 
 type EncryptionClientOptions struct {
 	MasterCiphers []crypto.MasterCipher
+
+	// The content encryption algorithm of the uploaded objects, crypto.AesCtrAlgorithm if not set.
+	// The objects are decrypted by the algorithm in their envelope.
+	CEKAlgorithm string
 }
 
 type EncryptionClient struct {
 	client           *Client
 	defualtCCBuilder crypto.ContentCipherBuilder
-	ccBuilderMap     map[string]crypto.ContentCipherBuilder
+	masterCipher     crypto.MasterCipher
+	matDescMap       map[string]crypto.MasterCipher
+	wrapAlgMap       map[string]crypto.MasterCipher
+	cekAlg           string
 	alignLen         int
 }
 
@@ -50,19 +59,38 @@ func NewEncryptionClient(c *Client, masterCipher crypto.MasterCipher, optFns ...
 		return nil, NewErrParamNull("masterCipher")
 	}
 
-	defualtCCBuilder := crypto.CreateAesCtrCipher(masterCipher)
-	ccBuilderMap := map[string]crypto.ContentCipherBuilder{}
+	cekAlg := options.CEKAlgorithm
+	if cekAlg == "" {
+		cekAlg = crypto.AesCtrAlgorithm
+	}
+	if !isValidContentAlg(cekAlg) {
+		return nil, NewErrParamInvalid("CEKAlgorithm")
+	}
+
+	matDescMap := map[string]crypto.MasterCipher{}
+	wrapAlgMap := map[string]crypto.MasterCipher{}
 	for _, m := range options.MasterCiphers {
-		if m != nil && len(m.GetMatDesc()) > 0 {
-			ccBuilderMap[m.GetMatDesc()] = crypto.CreateAesCtrCipher(m)
+		if m == nil {
+			continue
+		}
+		if len(m.GetMatDesc()) > 0 {
+			matDescMap[m.GetMatDesc()] = m
+		}
+		// the first master key of the wrap algorithm decrypts the objects with an unknown matDesc
+		if _, ok := wrapAlgMap[m.GetWrapAlgorithm()]; !ok {
+			wrapAlgMap[m.GetWrapAlgorithm()] = m
 		}
 	}
+	wrapAlgMap[masterCipher.GetWrapAlgorithm()] = masterCipher
 
 	e := &EncryptionClient{
 		client:           c,
-		defualtCCBuilder: defualtCCBuilder,
-		ccBuilderMap:     ccBuilderMap,
-		alignLen:         16,
+		defualtCCBuilder: newContentCipherBuilder(cekAlg, masterCipher),
+		masterCipher:     masterCipher,
+		matDescMap:       matDescMap,
+		wrapAlgMap:       wrapAlgMap,
+		cekAlg:           cekAlg,
+		alignLen:         int(contentAlignLen(cekAlg)),
 	}
 
 	return e, nil
@@ -72,13 +100,41 @@ func (e *EncryptionClient) Unwrap() *Client { return e.client }
 
 // GetObjectMeta Queries the metadata of an object, including ETag, Size, and LastModified.
 // The content of the object is not returned.
+// The ContentLength is the length of the plain data, the envelope of the object is got by HeadObject if it is not returned.
 func (e *EncryptionClient) GetObjectMeta(ctx context.Context, request *GetObjectMetaRequest, optFns ...func(*Options)) (*GetObjectMetaResult, error) {
-	return e.client.GetObjectMeta(ctx, request, optFns...)
+	result, err := e.client.GetObjectMeta(ctx, request, optFns...)
+	if err != nil {
+		return nil, err
+	}
+	headers := result.Headers
+	if !hasEncryptedHeader(headers) {
+		var hRequest HeadObjectRequest
+		copyRequest(&hRequest, request)
+		hResult, err := e.client.HeadObject(ctx, &hRequest, optFns...)
+		if err != nil {
+			return nil, err
+		}
+		headers = hResult.Headers
+	}
+	if contentAlgOf(headers) == crypto.AesGcmAlgorithm {
+		result.ContentLength = crypto.AesGcmDecryptedLen(result.ContentLength)
+		result.Headers.Set(HTTPHeaderContentLength, fmt.Sprint(result.ContentLength))
+	}
+	return result, nil
 }
 
 // HeadObject Queries information about all objects in a bucket.
+// The ContentLength is the length of the plain data.
 func (e *EncryptionClient) HeadObject(ctx context.Context, request *HeadObjectRequest, optFns ...func(*Options)) (*HeadObjectResult, error) {
-	return e.client.HeadObject(ctx, request, optFns...)
+	result, err := e.client.HeadObject(ctx, request, optFns...)
+	if err != nil {
+		return nil, err
+	}
+	if contentAlgOf(result.Headers) == crypto.AesGcmAlgorithm {
+		result.ContentLength = crypto.AesGcmDecryptedLen(result.ContentLength)
+		result.Headers.Set(HTTPHeaderContentLength, fmt.Sprint(result.ContentLength))
+	}
+	return result, nil
 }
 
 // GetObject Downloads a object.
@@ -137,11 +193,8 @@ func (e *EncryptionClient) getObjectSecurely(ctx context.Context, request *GetOb
 	}
 
 	var (
-		err          error
-		httpRange    *HTTPRange
-		discardCount int64 = 0
-		adjustOffset int64 = 0
-		closeBody    bool  = true
+		err       error
+		httpRange *HTTPRange
 	)
 
 	if request.Range != nil {
@@ -149,28 +202,88 @@ func (e *EncryptionClient) getObjectSecurely(ctx context.Context, request *GetOb
 		if err != nil {
 			return nil, err
 		}
+	}
+
+	// the range is aligned for the content algorithm of the object
+	var cekAlg string
+	if httpRange != nil {
+		if cekAlg, err = e.objectContentAlg(ctx, request, optFns...); err != nil {
+			return nil, err
+		}
+	}
+	return e.getObjectAligned(ctx, request, httpRange, cekAlg, optFns...)
+}
+
+type contentAlgKey struct{}
+
+// withContentAlg saves the content algorithm of the object in the context, e.g. by the file or the downloader
+// that has got the headers of the object, so that the ranged reads of the object do not get it again.
+func withContentAlg(ctx context.Context, headers http.Header) context.Context {
+	return context.WithValue(ctx, contentAlgKey{}, contentAlgOf(headers))
+}
+
+// contentAlgOf returns the content algorithm in the headers of the object, or empty if the object is not encrypted.
+func contentAlgOf(headers http.Header) string {
+	if hasEncryptedHeader(headers) {
+		return headers.Get(OssClientSideEncryptionCekAlg)
+	}
+	return ""
+}
+
+// objectContentAlg returns the content algorithm saved in the context, or gets it by HeadObject.
+func (e *EncryptionClient) objectContentAlg(ctx context.Context, request *GetObjectRequest, optFns ...func(*Options)) (string, error) {
+	if cekAlg, ok := ctx.Value(contentAlgKey{}).(string); ok {
+		return cekAlg, nil
+	}
+	var hRequest HeadObjectRequest
+	copyRequest(&hRequest, request)
+	result, err := e.client.HeadObject(ctx, &hRequest, optFns...)
+	if err != nil {
+		return "", err
+	}
+	return contentAlgOf(result.Headers), nil
+}
+
+// getObjectAligned gets the object with the range aligned for the content algorithm of the object.
+func (e *EncryptionClient) getObjectAligned(ctx context.Context, request *GetObjectRequest, httpRange *HTTPRange, cekAlg string, optFns ...func(*Options)) (*GetObjectResult, error) {
+	var (
+		err          error
+		framed       bool  = cekAlg == crypto.AesGcmAlgorithm
+		discardCount int64 = 0
+		adjustOffset int64 = 0
+		closeBody    bool  = true
+	)
+
+	eRequest := request
+	if httpRange != nil {
 		offset := httpRange.Offset
 		count := httpRange.Count
-		adjustOffset = adjustRangeStart(offset, int64(e.alignLen))
-		discardCount = httpRange.Offset - adjustOffset
+		alignLen := contentAlignLen(cekAlg)
+		adjustOffset = adjustRangeStart(offset, alignLen)
+		discardCount = offset - adjustOffset
 
-		if discardCount != 0 {
+		if framed {
+			// get the whole frames
+			eRange := HTTPRange{Offset: crypto.AesGcmEncryptedOffset(adjustOffset)}
+			if count > 0 {
+				end := adjustRangeStart(offset+count+alignLen-1, alignLen)
+				eRange.Count = crypto.AesGcmEncryptedOffset(end) - eRange.Offset
+			}
+			_request := *request
+			eRequest = &_request
+			eRequest.Range = eRange.FormatHTTPRange()
+			eRequest.RangeBehavior = Ptr("standard")
+		} else if discardCount > 0 {
 			if count > 0 {
 				count += discardCount
 			}
-			httpRange.Offset = adjustOffset
-			httpRange.Count = count
+			_request := *request
+			eRequest = &_request
+			eRequest.Range = (&HTTPRange{Offset: adjustOffset, Count: count}).FormatHTTPRange()
+			eRequest.RangeBehavior = Ptr("standard")
 		}
 	}
 
-	eRequest := request
-	if httpRange != nil && discardCount > 0 {
-		_request := *request
-		eRequest = &_request
-		eRequest.Range = httpRange.FormatHTTPRange()
-		eRequest.RangeBehavior = Ptr("standard")
-	}
-
 	result, err := e.client.GetObject(ctx, eRequest, optFns...)
 
 	if err != nil {
@@ -183,6 +296,12 @@ func (e *EncryptionClient) getObjectSecurely(ctx context.Context, request *GetOb
 		}
 	}()
 
+	objectAlg := contentAlgOf(result.Headers)
+	if httpRange != nil && objectAlg != cekAlg {
+		return nil, fmt.Errorf("the content algorithm of the object is changed from %q to %q,object:%s", cekAlg, objectAlg, ToString(request.Key))
+	}
+	framed = objectAlg == crypto.AesGcmAlgorithm
+
 	if hasEncryptedHeader(result.Headers) {
 		envelope, err := getEnvelopeFromHeader(result.Headers)
 		if err != nil {
@@ -195,7 +314,7 @@ func (e *EncryptionClient) getObjectSecurely(ctx context.Context, request *GetOb
 			return nil, fmt.Errorf("getEnvelopeFromHeader error,object:%s", ToString(request.Key))
 		}
 
-		// use ContentCipherBuilder to decrpt object by default
+		// the ContentCipherBuilder is chosen by the envelope of the object
 		cc, err := e.getContentCipherBuilder(envelope).ContentCipherEnv(envelope)
 		if err != nil {
 			return nil, fmt.Errorf("%s,object:%s", err.Error(), ToString(request.Key))
@@ -207,7 +326,25 @@ func (e *EncryptionClient) getObjectSecurely(ctx context.Context, request *GetOb
 			cc, _ = cc.Clone(cipherData)
 		}
 
-		result.Body, err = cc.DecryptContent(result.Body)
+		if rc, ok := cc.(interface {
+			DecryptContentRange(io.Reader, bool) (io.ReadCloser, error)
+		}); ok && framed {
+			// the last frame must be the final one if the range reaches the end of the object
+			result.Body, err = rc.DecryptContentRange(result.Body, rangeToEnd(result))
+		} else {
+			result.Body, err = cc.DecryptContent(result.Body)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if framed {
+			if err = convertFramedResult(result, httpRange, discardCount); err != nil {
+				return nil, fmt.Errorf("%s,object:%s", err.Error(), ToString(request.Key))
+			}
+			closeBody = false
+			return result, nil
+		}
 	}
 
 	if discardCount > 0 && err == nil {
@@ -236,6 +373,63 @@ func (e *EncryptionClient) getObjectSecurely(ctx context.Context, request *GetOb
 	return result, err
 }
 
+// rangeToEnd reports whether the range of the result reaches the end of the object.
+func rangeToEnd(result *GetObjectResult) bool {
+	if result.ContentRange == nil {
+		return true
+	}
+	_, to, total, err := ParseContentRange(*result.ContentRange)
+	return err != nil || to+1 >= total
+}
+
+// convertFramedResult converts the lengths of the encrypted frames to the plain data,
+// and checks the plain data size against the envelope.
+func convertFramedResult(result *GetObjectResult, httpRange *HTTPRange, discardCount int64) error {
+	// the whole object is returned if the range starts from 0 and has no end
+	total := crypto.AesGcmDecryptedLen(result.ContentLength)
+	if result.ContentRange != nil {
+		_, _, encryptedTotal, err := ParseContentRange(*result.ContentRange)
+		if err != nil {
+			return err
+		}
+		total = crypto.AesGcmDecryptedLen(encryptedTotal)
+	}
+
+	for _, name := range []string{OssClientSideEncryptionUnencryptedContentLength, OssClientSideEncryptionDataSize} {
+		if v := result.Headers.Get(name); v != "" {
+			if size, err := strconv.ParseInt(v, 10, 64); err == nil && size != total {
+				return fmt.Errorf("the plain data size %v mismatches %v in the envelope, the object is tampered", total, size)
+			}
+			break
+		}
+	}
+
+	if httpRange == nil {
+		result.ContentLength = total
+		result.Headers.Set(HTTPHeaderContentLength, fmt.Sprint(total))
+		return nil
+	}
+
+	from := httpRange.Offset
+	to := total - 1
+	if httpRange.Count > 0 && from+httpRange.Count < total {
+		to = from + httpRange.Count - 1
+	}
+	if from > to {
+		return fmt.Errorf("invalid range %s, the plain data size is %v", ToString(httpRange.FormatHTTPRange()), total)
+	}
+	value := fmt.Sprintf("bytes %v-%v/%v", from, to, total)
+	result.ContentRange = Ptr(value)
+	result.Headers.Set(HTTPHeaderContentRange, value)
+	result.ContentLength = to - from + 1
+	result.Headers.Set(HTTPHeaderContentLength, fmt.Sprint(result.ContentLength))
+	result.Body = NewLimitedReadCloser(&DiscardReadCloser{
+		RC:      result.Body,
+		Discard: int(discardCount),
+	}, result.ContentLength)
+	return nil
+}
+
 func (e *EncryptionClient) putObjectSecurely(ctx context.Context, request *PutObjectRequest, optFns ...func(*Options)) (*PutObjectResult, error) {
 	if request == nil {
 		return nil, NewErrParamNull("request")
@@ -251,6 +445,12 @@ func (e *EncryptionClient) putObjectSecurely(ctx context.Context, request *PutOb
 
 	eRequest := *request
 	eRequest.Body = cryptoReader
+	// the plain data size is saved in the envelope, and checked when the object is got
+	if eRequest.ContentLength == nil && e.cekAlg == crypto.AesGcmAlgorithm {
+		if size := GetReaderLen(request.Body); size >= 0 {
+			eRequest.ContentLength = Ptr(size)
+		}
+	}
 	addCryptoHeaders(&eRequest, cc.GetCipherData())
 
 	return e.client.PutObject(ctx, &eRequest, optFns...)
@@ -295,8 +495,12 @@ func (e *EncryptionClient) uploadPartSecurely(ctx context.Context, request *Uplo
 	if !cseCtx.Valid() {
 		return nil, fmt.Errorf("request.CSEMultiPartContext is invalid")
 	}
-	if cseCtx.PartSize%int64(e.alignLen) != 0 {
-		return nil, fmt.Errorf("CSEMultiPartContext's PartSize must be aligned to %v", e.alignLen)
+	alignLen := e.alignLen
+	if n := cseCtx.ContentCipher.GetAlignLen(); n > alignLen {
+		alignLen = n
+	}
+	if cseCtx.PartSize%int64(alignLen) != 0 {
+		return nil, fmt.Errorf("CSEMultiPartContext's PartSize must be aligned to %v", alignLen)
 	}
 
 	cipherData := cseCtx.ContentCipher.GetCipherData().Clone()
@@ -308,13 +512,30 @@ func (e *EncryptionClient) uploadPartSecurely(ctx context.Context, request *Uplo
 	// for parallel upload part
 	cc, _ := cseCtx.ContentCipher.Clone(cipherData)
 
-	cryptoReader, err := cc.EncryptContent(request.Body)
+	var (
+		cryptoReader io.ReadCloser
+		err          error
+	)
+	if pc, ok := cc.(interface {
+		EncryptContentPart(io.Reader, bool) (io.ReadCloser, error)
+	}); ok {
+		// only the last frame of the last part is sealed as the final one
+		if cseCtx.DataSize < 0 {
+			return nil, fmt.Errorf("CSEMultiPartContext's DataSize must be set for %v", cc.GetCipherData().CEKAlgorithm)
+		}
+		cryptoReader, err = pc.EncryptContentPart(request.Body, int64(request.PartNumber)*cseCtx.PartSize >= cseCtx.DataSize)
+	} else {
+		cryptoReader, err = cc.EncryptContent(request.Body)
+	}
 	if err != nil {
 		return nil, err
 	}
 
 	eRequest := *request
 	eRequest.Body = cryptoReader
+	if request.ContentLength != nil {
+		eRequest.ContentLength = Ptr(cc.GetEncryptedLen(*request.ContentLength))
+	}
 
 	addUploadPartCryptoHeaders(&eRequest, cseCtx, cc.GetCipherData())
 
@@ -322,10 +543,20 @@ func (e *EncryptionClient) uploadPartSecurely(ctx context.Context, request *Uplo
 }
 
 func (e *EncryptionClient) getContentCipherBuilder(envelope crypto.Envelope) crypto.ContentCipherBuilder {
-	if ccb, ok := e.ccBuilderMap[envelope.MatDesc]; ok {
-		return ccb
+	masterCipher := e.masterCipher
+	if m, ok := e.matDescMap[envelope.MatDesc]; ok {
+		masterCipher = m
+	} else if m, ok := e.wrapAlgMap[envelope.WrapAlg]; ok {
+		masterCipher = m
+	}
+	return newContentCipherBuilder(envelope.CEKAlg, masterCipher)
+}
+
+func newContentCipherBuilder(cekAlg string, masterCipher crypto.MasterCipher) crypto.ContentCipherBuilder {
+	if cekAlg == crypto.AesGcmAlgorithm {
+		return crypto.CreateAesGcmCipher(masterCipher)
 	}
-	return e.defualtCCBuilder
+	return crypto.CreateAesCtrCipher(masterCipher)
 }
 
 func (e *EncryptionClient) validEncryptionContext(request *InitiateMultipartUploadRequest) error {
@@ -338,6 +569,11 @@ func (e *EncryptionClient) validEncryptionContext(request *InitiateMultipartUplo
 		return fmt.Errorf("request.CSEPartSize must aligned to the %v", e.alignLen)
 	}
 
+	// the last part is known by the data size
+	if e.cekAlg == crypto.AesGcmAlgorithm && ToInt64(request.CSEDataSize) <= 0 {
+		return NewErrParamInvalid("request.CSEDataSize")
+	}
+
 	return nil
 }
 
@@ -448,8 +684,16 @@ func addUploadPartCryptoHeaders(request *UploadPartRequest, cseContext *Encrypti
 }
 
 func isValidContentAlg(algName string) bool {
-	// now content encyrption only support aec/ctr algorithm
-	return algName == crypto.AesCtrAlgorithm
+	// now content encyrption supports aes/ctr and the framed aes/gcm algorithm
+	return algName == crypto.AesCtrAlgorithm || algName == crypto.AesGcmAlgorithm
+}
+
+// contentAlignLen returns the alignment of the range of the object encrypted by the algorithm
+func contentAlignLen(algName string) int64 {
+	if algName == crypto.AesGcmAlgorithm {
+		return crypto.AesGcmFrameSize
+	}
+	return 16
 }
 
 func adjustRangeStart(start, align int64) int64 {
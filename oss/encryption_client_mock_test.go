@@ -15,6 +15,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -82,6 +83,8 @@ type encryptionMockTracker struct {
 	uploadPartErr  []bool
 	checkMPTime    []time.Time
 	listPartsNoCES bool
+	headCount      int32
+	getCount       int32
 }
 
 func testSetupEncryptionMockServer(t *testing.T, tracker *encryptionMockTracker) *httptest.Server {
@@ -100,11 +103,17 @@ func testSetupEncryptionMockServer(t *testing.T, tracker *encryptionMockTracker)
 			</Error>`)
 		switch r.Method {
 		case "HEAD":
+			atomic.AddInt32(&tracker.headCount, 1)
 			// header
 			w.Header().Set(HTTPHeaderLastModified, tracker.lastModified)
 			w.Header().Set(HTTPHeaderContentLength, fmt.Sprint(length))
 			w.Header().Set(HTTPHeaderETag, "fba9dede5f27731c9771645a3986****")
 			w.Header().Set(HTTPHeaderContentType, "text/plain")
+			// GetObjectMeta does not return the user metadata
+			if _, ok := query["objectMeta"]; ok {
+				w.WriteHeader(200)
+				return
+			}
 			for k, vv := range tracker.saveHeaders {
 				lk := strings.ToLower(k)
 				if strings.HasPrefix(lk, "x-oss-meta-client-side-encryption-") {
@@ -214,6 +223,7 @@ func testSetupEncryptionMockServer(t *testing.T, tracker *encryptionMockTracker)
 
 			} else {
 				// GetObject
+				atomic.AddInt32(&tracker.getCount, 1)
 				// header
 				var httpRange *HTTPRange
 				if r.Header.Get("Range") != "" {
@@ -223,6 +233,12 @@ func testSetupEncryptionMockServer(t *testing.T, tracker *encryptionMockTracker)
 				offset := int64(0)
 				statusCode := 200
 				sendLen := int64(length)
+				if httpRange != nil && httpRange.Offset >= int64(length) {
+					w.Header().Set(HTTPHeaderContentType, "application/xml")
+					w.WriteHeader(416)
+					w.Write([]byte("<Error><Code>InvalidRange</Code><Message>The requested range cannot be satisfied</Message></Error>"))
+					return
+				}
 				if httpRange != nil {
 					offset = httpRange.Offset
 					sendLen = int64(length) - httpRange.Offset
@@ -1602,3 +1618,384 @@ func TestMockEncryptionUploaderWithCheckpoint(t *testing.T) {
 	io.Copy(hashGet, gresult.Body)
 	assert.Equal(t, dataCrc64ecma, fmt.Sprint(hashGet.Sum64()))
 }
+
+func TestMockEncryptionKms(t *testing.T) {
+	partSize := int64(100 * 1024)
+	length := 3*100*1024 + 123
+	partsNum := length/int(partSize) + 1
+	data := []byte(randStr(length))
+	tracker := &encryptionMockTracker{
+		lastModified:  getNowGMT(),
+		saveMPData:    make([][]byte, partsNum),
+		saveMPHeaders: make([]http.Header, partsNum),
+	}
+	server := testSetupEncryptionMockServer(t, tracker)
+	defer server.Close()
+
+	cfg := LoadDefaultConfig().
+		WithCredentialsProvider(credentials.NewAnonymousCredentialsProvider()).
+		WithRegion("cn-hangzhou").
+		WithEndpoint(server.URL).
+		WithReadWriteTimeout(300 * time.Second)
+	client := NewClient(cfg)
+
+	kms := crypto.NewMemoryKmsClient("key-1", "key-2")
+	mc, err := crypto.CreateMasterKms(map[string]string{"tag": "value"}, "key-1", kms)
+	assert.Nil(t, err)
+	eclient, err := NewEncryptionClient(client, mc)
+	assert.Nil(t, err)
+
+	// PutObject & GetObject
+	_, err = eclient.PutObject(context.TODO(), &PutObjectRequest{
+		Bucket: Ptr("bucket"),
+		Key:    Ptr("key"),
+		Body:   bytes.NewReader(data),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, crypto.KmsAliCryptoWrap, tracker.saveHeaders.Get(OssClientSideEncryptionWrapAlg))
+	assert.Equal(t, crypto.AesCtrAlgorithm, tracker.saveHeaders.Get(OssClientSideEncryptionCekAlg))
+	assert.Equal(t, "{\"kms-key-id\":\"key-1\",\"tag\":\"value\"}", tracker.saveHeaders.Get(OssClientSideEncryptionMatDesc))
+	assert.NotEqualValues(t, data, tracker.savedata)
+
+	gResult, err := eclient.GetObject(context.TODO(), &GetObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")})
+	assert.Nil(t, err)
+	gData, err := io.ReadAll(gResult.Body)
+	assert.Nil(t, err)
+	assert.EqualValues(t, data, gData)
+
+	// Uploader
+	u := eclient.NewUploader(func(uo *UploaderOptions) {
+		uo.ParallelNum = 3
+		uo.PartSize = partSize
+	})
+	uResult, err := u.UploadFrom(context.TODO(), &PutObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")}, bytes.NewReader(data))
+	assert.Nil(t, err)
+	assert.NotNil(t, uResult.UploadId)
+	assert.Equal(t, crypto.KmsAliCryptoWrap, tracker.saveMPHeaders[0].Get(OssClientSideEncryptionWrapAlg))
+	assert.Len(t, tracker.savedata, length)
+
+	// Downloader
+	d := eclient.NewDownloader(func(do *DownloaderOptions) {
+		do.ParallelNum = 3
+		do.PartSize = 123 * 1024
+	})
+	localFile := randStr(8) + "-no-surfix"
+	defer os.Remove(localFile)
+	dResult, err := d.DownloadFile(context.TODO(), &GetObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")}, localFile)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(length), dResult.Written)
+	dData, err := os.ReadFile(localFile)
+	assert.Nil(t, err)
+	assert.EqualValues(t, data, dData)
+
+	// the rsa client decrypts the kms object by the kms master key of the same wrap algorithm
+	rsaMc, err := crypto.CreateMasterRsa(map[string]string{"tag": "value"}, rsaPublicKey, rsaPrivateKey)
+	assert.Nil(t, err)
+	otherMc, err := crypto.CreateMasterKms(nil, "key-2", kms)
+	assert.Nil(t, err)
+	eclient2, err := NewEncryptionClient(client, rsaMc, func(eco *EncryptionClientOptions) {
+		eco.MasterCiphers = []crypto.MasterCipher{otherMc}
+	})
+	assert.Nil(t, err)
+	gResult, err = eclient2.GetObject(context.TODO(), &GetObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")})
+	assert.Nil(t, err)
+	gData, err = io.ReadAll(gResult.Body)
+	assert.Nil(t, err)
+	assert.EqualValues(t, data, gData)
+
+	// the key is deleted
+	kms.DeleteKey("key-1")
+	_, err = eclient.GetObject(context.TODO(), &GetObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "kms key key-1 is not found")
+}
+
+func TestMockEncryptionAesGcm(t *testing.T) {
+	partSize := int64(100 * 1024)
+	length := 3*100*1024 + 123
+	partsNum := length/int(partSize) + 1
+	data := []byte(randStr(length))
+	tracker := &encryptionMockTracker{
+		lastModified:  getNowGMT(),
+		saveMPData:    make([][]byte, partsNum),
+		saveMPHeaders: make([]http.Header, partsNum),
+	}
+	server := testSetupEncryptionMockServer(t, tracker)
+	defer server.Close()
+
+	cfg := LoadDefaultConfig().
+		WithCredentialsProvider(credentials.NewAnonymousCredentialsProvider()).
+		WithRegion("cn-hangzhou").
+		WithEndpoint(server.URL).
+		WithReadWriteTimeout(300 * time.Second)
+	client := NewClient(cfg)
+
+	mc, err := crypto.CreateMasterRsa(map[string]string{"tag": "value"}, rsaPublicKey, rsaPrivateKey)
+	assert.Nil(t, err)
+
+	_, err = NewEncryptionClient(client, mc, func(eco *EncryptionClientOptions) {
+		eco.CEKAlgorithm = "AES/CBC/PKCS5Padding"
+	})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "invalid field, CEKAlgorithm")
+
+	gcmClient, err := NewEncryptionClient(client, mc, func(eco *EncryptionClientOptions) {
+		eco.CEKAlgorithm = crypto.AesGcmAlgorithm
+	})
+	assert.Nil(t, err)
+	ctrClient, err := NewEncryptionClient(client, mc)
+	assert.Nil(t, err)
+
+	checkRanges := func(eclient *EncryptionClient, checkContentRange bool) {
+		for _, r := range []HTTPRange{
+			{0, 10}, {1, 0}, {4095, 2}, {4096, 4096}, {5000, 10000}, {int64(length) - 10, 0},
+			{int64(length) - 10, 100}, {123, 1}, {4096 * 10, 0},
+		} {
+			end := int64(length)
+			if r.Count > 0 {
+				end = minInt64(r.Offset+r.Count, end)
+			}
+			result, err := eclient.GetObject(context.TODO(), &GetObjectRequest{
+				Bucket: Ptr("bucket"),
+				Key:    Ptr("key"),
+				Range:  r.FormatHTTPRange(),
+			})
+			assert.Nil(t, err)
+			got, err := io.ReadAll(result.Body)
+			assert.Nil(t, err)
+			assert.EqualValues(t, data[r.Offset:end], got)
+			assert.Equal(t, end-r.Offset, result.ContentLength)
+			if checkContentRange {
+				assert.Equal(t, fmt.Sprintf("bytes %v-%v/%v", r.Offset, end-1, length), ToString(result.ContentRange))
+			}
+		}
+	}
+
+	// PutObject
+	_, err = gcmClient.PutObject(context.TODO(), &PutObjectRequest{
+		Bucket: Ptr("bucket"),
+		Key:    Ptr("key"),
+		Body:   bytes.NewReader(data),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, crypto.AesGcmAlgorithm, tracker.saveHeaders.Get(OssClientSideEncryptionCekAlg))
+	assert.Equal(t, fmt.Sprint(length), tracker.saveHeaders.Get(OssClientSideEncryptionUnencryptedContentLength))
+	assert.Len(t, tracker.savedata, int(crypto.AesGcmEncryptedLen(int64(length))))
+
+	hResult, err := gcmClient.HeadObject(context.TODO(), &HeadObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(length), hResult.ContentLength)
+
+	// the gcm and ctr clients read the gcm object
+	for _, eclient := range []*EncryptionClient{gcmClient, ctrClient} {
+		gResult, err := eclient.GetObject(context.TODO(), &GetObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")})
+		assert.Nil(t, err)
+		assert.Equal(t, int64(length), gResult.ContentLength)
+		gData, err := io.ReadAll(gResult.Body)
+		assert.Nil(t, err)
+		assert.EqualValues(t, data, gData)
+		checkRanges(eclient, true)
+	}
+
+	// ReadOnlyFile, the algorithm of the object is got by the open
+	atomic.StoreInt32(&tracker.headCount, 0)
+	f, err := gcmClient.OpenFile(context.TODO(), "bucket", "key")
+	assert.Nil(t, err)
+	_, err = f.Seek(5000, io.SeekStart)
+	assert.Nil(t, err)
+	fData, err := io.ReadAll(f)
+	assert.Nil(t, err)
+	assert.EqualValues(t, data[5000:], fData)
+	f.Close()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&tracker.headCount))
+
+	// Uploader & Downloader
+	u := gcmClient.NewUploader(func(uo *UploaderOptions) {
+		uo.ParallelNum = 3
+		uo.PartSize = partSize
+	})
+	uResult, err := u.UploadFrom(context.TODO(), &PutObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")}, bytes.NewReader(data))
+	assert.Nil(t, err)
+	assert.NotNil(t, uResult.UploadId)
+	assert.Equal(t, crypto.AesGcmAlgorithm, tracker.saveMPHeaders[0].Get(OssClientSideEncryptionCekAlg))
+	assert.Len(t, tracker.savedata, int(crypto.AesGcmEncryptedLen(int64(length))))
+
+	d := gcmClient.NewDownloader(func(do *DownloaderOptions) {
+		do.ParallelNum = 3
+		do.PartSize = 123 * 1024
+	})
+	localFile := randStr(8) + "-no-surfix"
+	defer os.Remove(localFile)
+	atomic.StoreInt32(&tracker.headCount, 0)
+	dResult, err := d.DownloadFile(context.TODO(), &GetObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")}, localFile)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(length), dResult.Written)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&tracker.headCount))
+	dData, err := os.ReadFile(localFile)
+	assert.Nil(t, err)
+	assert.EqualValues(t, data, dData)
+
+	// the part size must be aligned to the frame
+	_, err = gcmClient.InitiateMultipartUpload(context.TODO(), &InitiateMultipartUploadRequest{
+		Bucket:      Ptr("bucket"),
+		Key:         Ptr("key"),
+		CSEPartSize: Ptr(int64(100 * 1000)),
+	})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "request.CSEPartSize must aligned to the 4096")
+
+	// tampered
+	saved := tracker.savedata
+	tracker.savedata = append([]byte{}, saved...)
+	tracker.savedata[10000] ^= 0x01
+	gResult, err := gcmClient.GetObject(context.TODO(), &GetObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")})
+	assert.Nil(t, err)
+	_, err = io.ReadAll(gResult.Body)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "is tampered")
+
+	// the last frame is removed
+	tracker.savedata = saved[:len(saved)-123-crypto.AesGcmTagSize]
+	_, err = gcmClient.GetObject(context.TODO(), &GetObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "the object is tampered")
+
+	// the frames are removed at a frame boundary, and the sizes in the envelope are removed too
+	savedHeaders := tracker.saveHeaders
+	tracker.saveHeaders = savedHeaders.Clone()
+	tracker.saveHeaders.Del(OssClientSideEncryptionUnencryptedContentLength)
+	tracker.saveHeaders.Del(OssClientSideEncryptionDataSize)
+	for _, r := range []*string{nil, Ptr("bytes=0-"), Ptr("bytes=5000-")} {
+		gResult, err = gcmClient.GetObject(context.TODO(), &GetObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key"), Range: r})
+		assert.Nil(t, err)
+		_, err = io.ReadAll(gResult.Body)
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "the frames after it are removed")
+	}
+	// the range before the end is not affected
+	gResult, err = gcmClient.GetObject(context.TODO(), &GetObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key"), Range: Ptr("bytes=0-4095")})
+	assert.Nil(t, err)
+	gData, err := io.ReadAll(gResult.Body)
+	assert.Nil(t, err)
+	assert.EqualValues(t, data[:4096], gData)
+	tracker.savedata = saved
+	tracker.saveHeaders = savedHeaders
+
+	// the algorithm of the object is got once for a ranged read
+	atomic.StoreInt32(&tracker.headCount, 0)
+	atomic.StoreInt32(&tracker.getCount, 0)
+	gResult, err = ctrClient.GetObject(context.TODO(), &GetObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key"), Range: Ptr("bytes=5000-5009")})
+	assert.Nil(t, err)
+	gData, err = io.ReadAll(gResult.Body)
+	assert.Nil(t, err)
+	assert.EqualValues(t, data[5000:5010], gData)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&tracker.headCount))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&tracker.getCount))
+
+	// GetObjectMeta
+	mResult, err := gcmClient.GetObjectMeta(context.TODO(), &GetObjectMetaRequest{Bucket: Ptr("bucket"), Key: Ptr("key")})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(length), mResult.ContentLength)
+
+	// the streamed data has no size in the envelope
+	_, err = gcmClient.PutObject(context.TODO(), &PutObjectRequest{
+		Bucket: Ptr("bucket"),
+		Key:    Ptr("key"),
+		Body:   io.LimitReader(bytes.NewReader(data), int64(length)),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "", tracker.saveHeaders.Get(OssClientSideEncryptionUnencryptedContentLength))
+	assert.Len(t, tracker.savedata, int(crypto.AesGcmEncryptedLen(int64(length))))
+	gResult, err = gcmClient.GetObject(context.TODO(), &GetObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")})
+	assert.Nil(t, err)
+	gData, err = io.ReadAll(gResult.Body)
+	assert.Nil(t, err)
+	assert.EqualValues(t, data, gData)
+	tracker.savedata = tracker.savedata[:crypto.AesGcmEncryptedOffset(4096*10)]
+	gResult, err = gcmClient.GetObject(context.TODO(), &GetObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")})
+	assert.Nil(t, err)
+	_, err = io.ReadAll(gResult.Body)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "frame 9 is tampered or the frames after it are removed")
+
+	// the empty object
+	_, err = gcmClient.PutObject(context.TODO(), &PutObjectRequest{
+		Bucket: Ptr("bucket"),
+		Key:    Ptr("key"),
+		Body:   bytes.NewReader(nil),
+	})
+	assert.Nil(t, err)
+	assert.Len(t, tracker.savedata, crypto.AesGcmTagSize)
+	gResult, err = gcmClient.GetObject(context.TODO(), &GetObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), gResult.ContentLength)
+	gData, err = io.ReadAll(gResult.Body)
+	assert.Nil(t, err)
+	assert.Len(t, gData, 0)
+	tracker.savedata = nil
+	gResult, err = gcmClient.GetObject(context.TODO(), &GetObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")})
+	assert.Nil(t, err)
+	_, err = io.ReadAll(gResult.Body)
+	assert.NotNil(t, err)
+
+	// the parts with the content length, the data size is a multiple of the part size
+	mpData := data[:2*partSize]
+	initResult, err := gcmClient.InitiateMultipartUpload(context.TODO(), &InitiateMultipartUploadRequest{
+		Bucket:      Ptr("bucket"),
+		Key:         Ptr("key"),
+		CSEPartSize: Ptr(partSize),
+		CSEDataSize: Ptr(int64(len(mpData))),
+	})
+	assert.Nil(t, err)
+	for i := 0; i < 2; i++ {
+		_, err = gcmClient.UploadPart(context.TODO(), &UploadPartRequest{
+			Bucket:              Ptr("bucket"),
+			Key:                 Ptr("key"),
+			UploadId:            initResult.UploadId,
+			PartNumber:          int32(i + 1),
+			CSEMultiPartContext: initResult.CSEMultiPartContext,
+			ContentLength:       Ptr(partSize),
+			Body:                io.LimitReader(bytes.NewReader(mpData[int64(i)*partSize:]), partSize),
+		})
+		assert.Nil(t, err)
+	}
+	tracker.saveMPData = tracker.saveMPData[:2]
+	_, err = gcmClient.CompleteMultipartUpload(context.TODO(), &CompleteMultipartUploadRequest{
+		Bucket:   Ptr("bucket"),
+		Key:      Ptr("key"),
+		UploadId: initResult.UploadId,
+	})
+	assert.Nil(t, err)
+	gResult, err = gcmClient.GetObject(context.TODO(), &GetObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")})
+	assert.Nil(t, err)
+	gData, err = io.ReadAll(gResult.Body)
+	assert.Nil(t, err)
+	assert.EqualValues(t, mpData, gData)
+
+	// the data size must be set
+	_, err = gcmClient.InitiateMultipartUpload(context.TODO(), &InitiateMultipartUploadRequest{
+		Bucket:      Ptr("bucket"),
+		Key:         Ptr("key"),
+		CSEPartSize: Ptr(partSize),
+	})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "invalid field, request.CSEDataSize")
+
+	// the gcm and ctr clients read the ctr object
+	_, err = ctrClient.PutObject(context.TODO(), &PutObjectRequest{
+		Bucket: Ptr("bucket"),
+		Key:    Ptr("key"),
+		Body:   bytes.NewReader(data),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, crypto.AesCtrAlgorithm, tracker.saveHeaders.Get(OssClientSideEncryptionCekAlg))
+	assert.Len(t, tracker.savedata, length)
+	for _, eclient := range []*EncryptionClient{gcmClient, ctrClient} {
+		gResult, err := eclient.GetObject(context.TODO(), &GetObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")})
+		assert.Nil(t, err)
+		gData, err := io.ReadAll(gResult.Body)
+		assert.Nil(t, err)
+		assert.EqualValues(t, data, gData)
+		checkRanges(eclient, false)
+	}
+}
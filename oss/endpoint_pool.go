@@ -0,0 +1,192 @@
+package oss
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	DefaultEndpointPoolCooldown         = 30 * time.Second
+	DefaultEndpointPoolFailureThreshold = 1
+)
+
+type EndpointPoolOptions struct {
+	// The number of consecutive connection errors that marks an endpoint unhealthy.
+	FailureThreshold int
+
+	// How long an unhealthy endpoint is skipped before it is tried again.
+	Cooldown time.Duration
+}
+
+// EndpointPool is an ordered list of endpoints, e.g. the internal endpoint first and then the public one.
+// A request is sent to the first healthy endpoint, and when an attempt fails with a connection error,
+// the next attempt fails over to the next healthy endpoint and is signed again for the new host.
+// It can be shared by several clients.
+type EndpointPool struct {
+	mu        sync.Mutex
+	options   EndpointPoolOptions
+	endpoints []*pooledEndpoint
+	now       func() time.Time
+}
+
+type pooledEndpoint struct {
+	url            *url.URL
+	failures       int
+	unhealthyUntil time.Time
+}
+
+// NewEndpointPool creates an endpoint pool, in the order of preference.
+// The endpoints without a scheme use DefaultEndpointScheme.
+func NewEndpointPool(endpoints []string, optFns ...func(*EndpointPoolOptions)) (*EndpointPool, error) {
+	if len(endpoints) == 0 {
+		return nil, NewErrParamRequired("endpoints")
+	}
+
+	options := EndpointPoolOptions{
+		FailureThreshold: DefaultEndpointPoolFailureThreshold,
+		Cooldown:         DefaultEndpointPoolCooldown,
+	}
+
+	for _, fn := range optFns {
+		fn(&options)
+	}
+
+	if options.FailureThreshold <= 0 {
+		options.FailureThreshold = DefaultEndpointPoolFailureThreshold
+	}
+
+	if options.Cooldown <= 0 {
+		options.Cooldown = DefaultEndpointPoolCooldown
+	}
+
+	p := &EndpointPool{
+		options: options,
+		now:     time.Now,
+	}
+	for _, endpoint := range endpoints {
+		u, err := url.Parse(addEndpointScheme(endpoint, false))
+		if err != nil || u.Host == "" {
+			return nil, NewErrParamInvalid("endpoints")
+		}
+		p.endpoints = append(p.endpoints, &pooledEndpoint{url: u})
+	}
+	return p, nil
+}
+
+// Endpoints returns the endpoints of the pool, in the order of preference.
+func (p *EndpointPool) Endpoints() []*url.URL {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	urls := make([]*url.URL, 0, len(p.endpoints))
+	for _, e := range p.endpoints {
+		u := *e.url
+		urls = append(urls, &u)
+	}
+	return urls
+}
+
+// IsHealthy reports whether the endpoint host is not in its cooldown.
+func (p *EndpointPool) IsHealthy(host string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now()
+	for _, e := range p.endpoints {
+		if e.url.Host == host {
+			return !now.Before(e.unhealthyUntil)
+		}
+	}
+	return false
+}
+
+func (p *EndpointPool) primary() *url.URL {
+	u := *p.endpoints[0].url
+	return &u
+}
+
+// pick returns the first healthy endpoint that is usable.
+// If none of them is healthy, the one whose cooldown ends first is returned.
+func (p *EndpointPool) pick(usable func(*url.URL) bool) *url.URL {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now()
+	var fallback *pooledEndpoint
+	for _, e := range p.endpoints {
+		if usable != nil && !usable(e.url) {
+			continue
+		}
+		if !now.Before(e.unhealthyUntil) {
+			return e.url
+		}
+		if fallback == nil || e.unhealthyUntil.Before(fallback.unhealthyUntil) {
+			fallback = e
+		}
+	}
+	if fallback == nil {
+		return p.endpoints[0].url
+	}
+	return fallback.url
+}
+
+func (p *EndpointPool) markFailure(endpoint *url.URL) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, e := range p.endpoints {
+		if e.url == endpoint {
+			e.failures++
+			if e.failures >= p.options.FailureThreshold {
+				e.failures = 0
+				e.unhealthyUntil = p.now().Add(p.options.Cooldown)
+			}
+			return
+		}
+	}
+}
+
+func (p *EndpointPool) markSuccess(endpoint *url.URL) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, e := range p.endpoints {
+		if e.url == endpoint {
+			e.failures = 0
+			e.unhealthyUntil = time.Time{}
+			return
+		}
+	}
+}
+
+// isConnectionError reports whether the attempt failed to connect to the endpoint,
+// i.e. the dial, DNS or connection refused errors. The errors after the connection is made are not counted.
+func isConnectionError(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	var oerr *net.OpError
+	if errors.As(err, &oerr) && oerr.Op == "dial" {
+		return true
+	}
+	var derr *net.DNSError
+	if errors.As(err, &derr) {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// endpointHost returns the host of the request sent to the endpoint, the url style is kept.
+func endpointHost(input *OperationInput, opts *Options, endpoint *url.URL) string {
+	o := *opts
+	o.Endpoint = endpoint
+	host, _ := buildURL(input, &o)
+	return host
+}
+
+func setRequestEndpoint(request *http.Request, host string, endpoint *url.URL) {
+	request.URL.Scheme = endpoint.Scheme
+	request.URL.Host = host
+	request.Host = ""
+}
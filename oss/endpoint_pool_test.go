@@ -0,0 +1,166 @@
+package oss
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/credentials"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewEndpointPool(t *testing.T) {
+	_, err := NewEndpointPool(nil)
+	assert.NotNil(t, err)
+
+	_, err = NewEndpointPool([]string{"oss-cn-hangzhou.aliyuncs.com", ""})
+	assert.NotNil(t, err)
+
+	p, err := NewEndpointPool([]string{"oss-cn-hangzhou-internal.aliyuncs.com", "http://oss-cn-hangzhou.aliyuncs.com"})
+	assert.Nil(t, err)
+	endpoints := p.Endpoints()
+	assert.Len(t, endpoints, 2)
+	assert.Equal(t, "https://oss-cn-hangzhou-internal.aliyuncs.com", endpoints[0].String())
+	assert.Equal(t, "http://oss-cn-hangzhou.aliyuncs.com", endpoints[1].String())
+	assert.Equal(t, "oss-cn-hangzhou-internal.aliyuncs.com", p.primary().Host)
+}
+
+func TestEndpointPool_Pick(t *testing.T) {
+	p, err := NewEndpointPool([]string{"internal", "public", "accelerate"}, func(o *EndpointPoolOptions) {
+		o.FailureThreshold = 2
+		o.Cooldown = time.Minute
+	})
+	assert.Nil(t, err)
+	now := time.Now()
+	p.now = func() time.Time { return now }
+
+	internal := p.pick(nil)
+	assert.Equal(t, "internal", internal.Host)
+
+	p.markFailure(internal)
+	assert.True(t, p.IsHealthy("internal"))
+	p.markFailure(internal)
+	assert.False(t, p.IsHealthy("internal"))
+	public := p.pick(nil)
+	assert.Equal(t, "public", public.Host)
+
+	// ordered failover
+	p.markFailure(public)
+	p.markFailure(public)
+	assert.Equal(t, "accelerate", p.pick(nil).Host)
+
+	// the filter
+	assert.Equal(t, "public", p.pick(func(u *url.URL) bool { return u.Host != "accelerate" && u.Host != "internal" }).Host)
+
+	// none is healthy, the one whose cooldown ends first
+	p.markFailure(p.pick(nil))
+	p.markFailure(p.pick(nil))
+	assert.Equal(t, "internal", p.pick(nil).Host)
+
+	// back after the cooldown
+	now = now.Add(time.Minute)
+	assert.True(t, p.IsHealthy("internal"))
+	assert.Equal(t, "internal", p.pick(nil).Host)
+	p.markSuccess(internal)
+	assert.False(t, p.IsHealthy("unknown"))
+}
+
+func TestEndpointPool_Client(t *testing.T) {
+	var hosts []string
+	server := testSetupMockServer(t, 200, map[string]string{"x-oss-request-id": "id-1234"}, nil,
+		func(t *testing.T, r *http.Request) {
+			hosts = append(hosts, r.Host)
+			assert.NotEmpty(t, r.Header.Get("Authorization"))
+		})
+	defer server.Close()
+
+	// nothing listens on the port
+	down := "http://127.0.0.1:1"
+	pool, err := NewEndpointPool([]string{down, server.URL})
+	assert.Nil(t, err)
+
+	cfg := LoadDefaultConfig().
+		WithCredentialsProvider(credentials.NewStaticCredentialsProvider("ak", "sk")).
+		WithRegion("cn-hangzhou").
+		WithEndpointPool(pool)
+
+	client := NewClient(cfg)
+	assert.Equal(t, "127.0.0.1:1", client.options.Endpoint.Host)
+	assert.Equal(t, UrlStylePath, client.options.UrlStyle)
+
+	_, err = client.HeadObject(context.TODO(), &HeadObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{strings.TrimPrefix(server.URL, "http://")}, hosts)
+	assert.False(t, pool.IsHealthy("127.0.0.1:1"))
+
+	// the unhealthy endpoint is skipped
+	_, err = client.PutObject(context.TODO(), &PutObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key"), Body: strings.NewReader("hello")})
+	assert.Nil(t, err)
+	assert.Len(t, hosts, 2)
+
+	// no retry, no failover
+	pool, err = NewEndpointPool([]string{down, server.URL})
+	assert.Nil(t, err)
+	_, err = client.HeadObject(context.TODO(), &HeadObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")}, func(o *Options) {
+		o.EndpointPool = pool
+		o.RetryMaxAttempts = Ptr(1)
+	})
+	var uerr *url.Error
+	assert.True(t, errors.As(err, &uerr))
+	assert.Len(t, hosts, 2)
+}
+
+func TestIsConnectionError(t *testing.T) {
+	ctx := context.TODO()
+	dial := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("i/o timeout")}
+	assert.True(t, isConnectionError(ctx, dial))
+	assert.True(t, isConnectionError(ctx, &url.Error{Op: "Get", URL: "http://host", Err: dial}))
+	assert.True(t, isConnectionError(ctx, &url.Error{Op: "Get", URL: "http://host", Err: &net.DNSError{Err: "no such host", Name: "host"}}))
+	assert.True(t, isConnectionError(ctx, &url.Error{Op: "Get", URL: "http://host", Err: syscall.ECONNREFUSED}))
+
+	// the connection is made
+	assert.False(t, isConnectionError(ctx, nil))
+	assert.False(t, isConnectionError(ctx, &url.Error{Op: "Get", URL: "http://host", Err: &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")}}))
+	assert.False(t, isConnectionError(ctx, &url.Error{Op: "Get", URL: "http://host", Err: errors.New("net/http: timeout awaiting response headers")}))
+	assert.False(t, isConnectionError(ctx, &url.Error{Op: "Get", URL: "https://host", Err: &tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}}}))
+	assert.False(t, isConnectionError(ctx, &ServiceError{StatusCode: 503}))
+
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.False(t, isConnectionError(cctx, dial))
+}
+
+func TestEndpointPool_Presign(t *testing.T) {
+	pool, err := NewEndpointPool([]string{"oss-cn-hangzhou-internal.aliyuncs.com", "oss-cn-hangzhou.aliyuncs.com"}, func(o *EndpointPoolOptions) {
+		o.FailureThreshold = 2
+	})
+	assert.Nil(t, err)
+	cfg := LoadDefaultConfig().
+		WithCredentialsProvider(credentials.NewStaticCredentialsProvider("ak", "sk")).
+		WithRegion("cn-hangzhou").
+		WithEndpointPool(pool)
+	client := NewClient(cfg)
+
+	internal := pool.pick(nil)
+	pool.markFailure(internal)
+
+	// presigning sends nothing, the failures are not reset
+	result, err := client.Presign(context.TODO(), &GetObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")})
+	assert.Nil(t, err)
+	assert.Contains(t, result.URL, "bucket.oss-cn-hangzhou-internal.aliyuncs.com")
+
+	pool.markFailure(internal)
+	assert.False(t, pool.IsHealthy("oss-cn-hangzhou-internal.aliyuncs.com"))
+	result, err = client.Presign(context.TODO(), &GetObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")})
+	assert.Nil(t, err)
+	assert.Contains(t, result.URL, "bucket.oss-cn-hangzhou.aliyuncs.com")
+	assert.False(t, pool.IsHealthy("oss-cn-hangzhou-internal.aliyuncs.com"))
+}
@@ -274,6 +274,7 @@ const (
 	HeaderOssAllowSameActionOverLap             = "X-Oss-Allow-Same-Action-Overlap"
 	HeaderOssDate                               = "X-Oss-Date"
 	HeaderOssContentSha256                      = "X-Oss-Content-Sha256"
+	HeaderOssTrailer                            = "X-Oss-Trailer"
 	HeaderOssEC                                 = "X-Oss-Ec"
 	HeaderOssERR                                = "X-Oss-Err"
 )
@@ -358,6 +359,11 @@ const (
 	// This feature takes effect for Downloader.DownloadFile
 	FeatureEnableCRC64CheckDownload
 
+	// FeatureEnableChunkedSigning signs the body of unknown length chunk by chunk with the V4 signature,
+	// and sends the crc64 of the body in the trailer, so the body is streamed without buffering.
+	// This feature takes effect for PutObject, UploadPart and Uploader.UploadFrom
+	FeatureEnableChunkedSigning
+
 	FeatureFlagsDefault = FeatureCorrectClockSkew + FeatureAutoDetectMimeType +
 		FeatureEnableCRC64CheckUpload + FeatureEnableCRC64CheckDownload
 )
@@ -399,4 +405,5 @@ const (
 	OpMetaKeyResponsHandler     string = "opm-response-handler"
 	OpMetaKeyRequestBodyTracker string = "opm-request-body-tracker"
 	OpMetaKeyIsBucketArn        string = "opm-is-bucket-arn"
+	OpMetaKeyDiagnostics        string = "opm-diagnostics"
 )
@@ -2,8 +2,10 @@ package oss
 
 import (
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -20,9 +22,19 @@ type ServiceError struct {
 	Timestamp     time.Time
 	RequestTarget string
 	Headers       http.Header
+
+	// The comparison of the client and server signatures, it is set for the SignatureDoesNotMatch error only.
+	SignatureDiagnostics *SignatureDiagnostics `xml:"-" json:"-"`
 }
 
 func (e *ServiceError) Error() string {
+	if e.SignatureDiagnostics != nil {
+		return fmt.Sprintf("%s\nSignature Diagnostics: %s.", e.serviceErrorMessage(), e.SignatureDiagnostics)
+	}
+	return e.serviceErrorMessage()
+}
+
+func (e *ServiceError) serviceErrorMessage() string {
 	return fmt.Sprintf(
 		`Error returned by Service. 
 Http Status Code: %d. 
@@ -43,6 +55,26 @@ func (e *ServiceError) ErrorCode() string {
 	return e.Code
 }
 
+// RetryAfter returns the delay hinted by the Retry-After header, in seconds or as a http date, or 0 if none.
+func (e *ServiceError) RetryAfter() time.Duration {
+	v := e.Headers.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+		if secs > 0 {
+			return time.Duration(secs) * time.Second
+		}
+		return 0
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
 type ClientError struct {
 	Code    string
 	Message string
@@ -56,12 +88,16 @@ func (e *ClientError) Error() string {
 }
 
 type OperationError struct {
-	name string
-	err  error
+	name        string
+	err         error
+	diagnostics *Diagnostics
 }
 
 func (e *OperationError) Operation() string { return e.name }
 
+// Diagnostics returns how the operation went, or nil if not available.
+func (e *OperationError) Diagnostics() *Diagnostics { return e.diagnostics }
+
 func (e *OperationError) Unwrap() error { return e.err }
 
 func (e *OperationError) Error() string {
@@ -111,6 +147,65 @@ func (e *CanceledError) Error() string {
 	return fmt.Sprintf("canceled, %v", e.Err)
 }
 
+// RetryStoppedError is returned when the retryer refuses to retry, e.g. the retry quota is exceeded.
+// It wraps the error of the retryer, and errors.As also finds the error of the last attempt.
+type RetryStoppedError struct {
+	Err error
+
+	// The error of the last attempt.
+	LastErr error
+}
+
+func (e *RetryStoppedError) Error() string {
+	return fmt.Sprintf("retry stopped, %v, last error: %v", e.Err, e.LastErr)
+}
+
+func (e *RetryStoppedError) Unwrap() error { return e.Err }
+
+func (e *RetryStoppedError) Is(target error) bool { return errors.Is(e.LastErr, target) }
+
+func (e *RetryStoppedError) As(target any) bool { return errors.As(e.LastErr, target) }
+
+// RequestRateLimitError is returned when the request rate limiter is in fail-fast mode and has no token.
+type RequestRateLimitError struct {
+	// The limit that is exceeded, global, bucket or the operation class.
+	Scope string
+
+	// The time to wait for a token.
+	RetryAfter time.Duration
+}
+
+func (e *RequestRateLimitError) Error() string {
+	return fmt.Sprintf("request rate limit exceeded, scope: %s, retry after: %v", e.Scope, e.RetryAfter)
+}
+
+// CircuitOpenError is returned when the circuit of the endpoint host is open, the request is not sent.
+type CircuitOpenError struct {
+	Host string
+
+	// The time until the probe requests are let through, 0 if the probes are in progress.
+	RetryAfter time.Duration
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker is open for %s, retry after: %v", e.Host, e.RetryAfter)
+}
+
+// PresignedURLError is returned when the presigned url is not valid.
+type PresignedURLError struct {
+	// Expired, SignatureDoesNotMatch or InvalidArgument.
+	Code string
+
+	Message string
+
+	// The string to sign that is calculated locally, it is set for SignatureDoesNotMatch.
+	StringToSign string
+}
+
+func (e *PresignedURLError) Error() string {
+	return fmt.Sprintf("invalid presigned url, Code: %s, Message: %s", e.Code, e.Message)
+}
+
 type InvalidParamError interface {
 	error
 	Field() string
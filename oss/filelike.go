@@ -31,6 +31,7 @@ type OpenOptions struct {
 type ReadOnlyFile struct {
 	client  OpenFileAPIClient
 	context context.Context
+	tracer  Tracer
 
 	// object info
 	bucket       string
@@ -69,6 +70,7 @@ type ReadOnlyFile struct {
 // NewReadOnlyFile OpenFile opens the named file for reading.
 // If successful, methods on the returned file can be used for reading.
 func NewReadOnlyFile(ctx context.Context, c OpenFileAPIClient, bucket string, key string, optFns ...func(*OpenOptions)) (*ReadOnlyFile, error) {
+	ctx = ensureCorrelationId(ctx)
 	options := OpenOptions{
 		Offset:                  0,
 		EnablePrefetch:          false,
@@ -103,6 +105,7 @@ func NewReadOnlyFile(ctx context.Context, c OpenFileAPIClient, bucket string, ke
 	f := &ReadOnlyFile{
 		client:  c,
 		context: ctx,
+		tracer:  getTracer(c),
 
 		bucket:       bucket,
 		key:          key,
@@ -134,6 +137,8 @@ func NewReadOnlyFile(ctx context.Context, c OpenFileAPIClient, bucket string, ke
 	f.modTime = result.Headers.Get(HTTPHeaderLastModified)
 	f.etag = result.Headers.Get(HTTPHeaderETag)
 	f.headers = result.Headers
+	// the ranged reads of the encryption client are aligned for the content algorithm of the object
+	f.context = withContentAlg(f.context, result.Headers)
 
 	if f.sizeInBytes < 0 {
 		return nil, fmt.Errorf("file size is invaid, got %v", f.sizeInBytes)
@@ -467,8 +472,10 @@ func (f *ReadOnlyFile) prefetch(offset int64, _ /*needAtLeast*/ int) (err error)
 					request.Range = rangeStr
 					request.RangeBehavior = Ptr("standard")
 				}
+				spanCtx, span := startSpan(f.context, f.tracer, "ReadOnlyFile.Prefetch", downloadSpanAttributes(request)...)
+				defer func() { endSpan(span, err) }()
 				var result *GetObjectResult
-				result, err = f.client.GetObject(f.context, request)
+				result, err = f.client.GetObject(spanCtx, request)
 				if err != nil {
 					return nil, err
 				}
@@ -531,6 +538,7 @@ type AppendOnlyFile struct {
 // NewAppendFile AppendFile opens or creates the named file for appending.
 // If successful, methods on the returned file can be used for appending.
 func NewAppendFile(ctx context.Context, c AppendFileAPIClient, bucket string, key string, optFns ...func(*AppendOptions)) (*AppendOnlyFile, error) {
+	ctx = ensureCorrelationId(ctx)
 	options := AppendOptions{}
 
 	for _, fn := range optFns {
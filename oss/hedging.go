@@ -0,0 +1,236 @@
+package oss
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultHedgingDelay      = 100 * time.Millisecond
+	DefaultHedgingPercentile = 0.95
+	DefaultHedgingMinSamples = 20
+	DefaultHedgingWindowSize = 200
+	DefaultHedgingMaxHedges  = 1
+)
+
+// DefaultHedgingOperations are the read operations that are hedged by default.
+// The ranged reads of ReadOnlyFile and Downloader are GetObject requests.
+var DefaultHedgingOperations = []string{
+	"GetObject",
+	"HeadObject",
+	"GetObjectMeta",
+}
+
+type HedgingOptions struct {
+	// The delay before a hedged request is sent, until enough latencies are observed.
+	Delay time.Duration
+
+	// The percentile of the observed latencies to the response headers that is used as the delay, in (0, 1].
+	Percentile float64
+
+	// The number of observed latencies required to use the percentile.
+	MinSamples int
+
+	// The number of the latest latencies that are kept.
+	WindowSize int
+
+	// The maximum number of hedged requests sent in addition to the first one.
+	MaxHedges int
+
+	// The operations that are hedged, only the requests without body are hedged.
+	Operations []string
+}
+
+// HedgingPolicy sends a duplicate of a read request when the response headers are not received
+// within a delay derived from the observed latencies, and uses the response that comes first.
+// The other requests are canceled. It can be shared by several clients.
+type HedgingPolicy struct {
+	options    HedgingOptions
+	operations map[string]struct{}
+
+	mu      sync.Mutex
+	samples []time.Duration
+	next    int
+}
+
+func NewHedgingPolicy(optFns ...func(*HedgingOptions)) *HedgingPolicy {
+	options := HedgingOptions{
+		Delay:      DefaultHedgingDelay,
+		Percentile: DefaultHedgingPercentile,
+		MinSamples: DefaultHedgingMinSamples,
+		WindowSize: DefaultHedgingWindowSize,
+		MaxHedges:  DefaultHedgingMaxHedges,
+		Operations: DefaultHedgingOperations,
+	}
+
+	for _, fn := range optFns {
+		fn(&options)
+	}
+
+	if options.Delay <= 0 {
+		options.Delay = DefaultHedgingDelay
+	}
+
+	if options.Percentile <= 0 || options.Percentile > 1 {
+		options.Percentile = DefaultHedgingPercentile
+	}
+
+	if options.WindowSize <= 0 {
+		options.WindowSize = DefaultHedgingWindowSize
+	}
+
+	if options.MinSamples > options.WindowSize {
+		options.MinSamples = options.WindowSize
+	}
+
+	if options.MaxHedges <= 0 {
+		options.MaxHedges = DefaultHedgingMaxHedges
+	}
+
+	p := &HedgingPolicy{
+		options:    options,
+		operations: map[string]struct{}{},
+	}
+	for _, op := range options.Operations {
+		p.operations[op] = struct{}{}
+	}
+	return p
+}
+
+// Delay returns the delay before a hedged request is sent.
+func (p *HedgingPolicy) Delay() time.Duration {
+	p.mu.Lock()
+	if len(p.samples) == 0 || len(p.samples) < p.options.MinSamples {
+		p.mu.Unlock()
+		return p.options.Delay
+	}
+	samples := append([]time.Duration(nil), p.samples...)
+	p.mu.Unlock()
+
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	i := int(float64(len(samples))*p.options.Percentile+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	return samples[i]
+}
+
+func (p *HedgingPolicy) observe(latency time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.samples) < p.options.WindowSize {
+		p.samples = append(p.samples, latency)
+		return
+	}
+	p.samples[p.next] = latency
+	p.next = (p.next + 1) % p.options.WindowSize
+}
+
+func (p *HedgingPolicy) applies(input *OperationInput, request *http.Request) bool {
+	if request.Method != "GET" && request.Method != "HEAD" {
+		return false
+	}
+	if request.ContentLength > 0 {
+		return false
+	}
+	_, ok := p.operations[input.OpName]
+	return ok
+}
+
+type hedgeResult struct {
+	response *http.Response
+	err      error
+	index    int
+	latency  time.Duration
+}
+
+// do sends the request, and the hedged requests if the response headers are late.
+// The body of the returned response cancels the request when closed.
+func (p *HedgingPolicy) do(request *http.Request, do func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	ctx := request.Context()
+	results := make(chan hedgeResult, p.options.MaxHedges+1)
+	var cancels []context.CancelFunc
+	launch := func() {
+		rctx, cancel := context.WithCancel(ctx)
+		index := len(cancels)
+		cancels = append(cancels, cancel)
+		req := request.Clone(rctx)
+		req.Body = http.NoBody
+		req.GetBody = nil
+		req.ContentLength = 0
+		go func() {
+			start := time.Now()
+			response, err := do(req)
+			results <- hedgeResult{response: response, err: err, index: index, latency: time.Since(start)}
+		}()
+	}
+
+	delay := p.Delay()
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	launch()
+	launched, pending := 1, 1
+	var firstErr error
+	for {
+		select {
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				p.observe(r.latency)
+				for i, cancel := range cancels {
+					// the winner is canceled when its body is closed
+					if i != r.index {
+						cancel()
+					}
+				}
+				discardHedgeResults(results, pending)
+				r.response.Body = &hedgedReadCloser{ReadCloser: r.response.Body, cancel: cancels[r.index]}
+				return r.response, nil
+			}
+			cancels[r.index]()
+			if firstErr == nil {
+				firstErr = r.err
+			}
+			if pending == 0 {
+				return nil, firstErr
+			}
+		case <-timer.C:
+			if launched <= p.options.MaxHedges {
+				launch()
+				launched++
+				pending++
+				timer.Reset(delay)
+			}
+		}
+	}
+}
+
+func discardHedgeResults(results chan hedgeResult, pending int) {
+	if pending == 0 {
+		return
+	}
+	go func() {
+		for i := 0; i < pending; i++ {
+			r := <-results
+			if r.response != nil {
+				r.response.Body.Close()
+			}
+		}
+	}()
+}
+
+type hedgedReadCloser struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (r *hedgedReadCloser) Close() error {
+	err := r.ReadCloser.Close()
+	r.cancel()
+	return err
+}
@@ -0,0 +1,137 @@
+package oss
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/credentials"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHedgingPolicy_Delay(t *testing.T) {
+	p := NewHedgingPolicy(func(o *HedgingOptions) {
+		o.Delay = 50 * time.Millisecond
+		o.Percentile = 0.9
+		o.MinSamples = 10
+		o.WindowSize = 10
+	})
+	assert.Equal(t, 50*time.Millisecond, p.Delay())
+
+	for i := 1; i <= 9; i++ {
+		p.observe(time.Duration(i) * time.Millisecond)
+	}
+	assert.Equal(t, 50*time.Millisecond, p.Delay())
+	p.observe(10 * time.Millisecond)
+	assert.Equal(t, 9*time.Millisecond, p.Delay())
+
+	// only the latest samples are kept
+	for i := 0; i < 10; i++ {
+		p.observe(100 * time.Millisecond)
+	}
+	assert.Equal(t, 100*time.Millisecond, p.Delay())
+
+	assert.True(t, p.applies(&OperationInput{OpName: "GetObject"}, httptest.NewRequest("GET", "/", nil)))
+	assert.True(t, p.applies(&OperationInput{OpName: "HeadObject"}, httptest.NewRequest("HEAD", "/", nil)))
+	assert.False(t, p.applies(&OperationInput{OpName: "ListObjects"}, httptest.NewRequest("GET", "/", nil)))
+	assert.False(t, p.applies(&OperationInput{OpName: "GetObject"}, httptest.NewRequest("PUT", "/", strings.NewReader("123"))))
+}
+
+func TestHedgingPolicy_Client(t *testing.T) {
+	var count int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&count, 1)
+		if n%2 == 1 {
+			// the first request is slow
+			select {
+			case <-time.After(2 * time.Second):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		w.Header().Set("x-oss-request-id", "id-1234")
+		w.Header().Set("x-oss-hedge", r.Header.Get("Range"))
+		w.WriteHeader(200)
+		io.WriteString(w, "hello world")
+	}))
+	defer server.Close()
+
+	policy := NewHedgingPolicy(func(o *HedgingOptions) {
+		o.Delay = 50 * time.Millisecond
+	})
+	cfg := LoadDefaultConfig().
+		WithCredentialsProvider(credentials.NewAnonymousCredentialsProvider()).
+		WithRegion("cn-hangzhou").
+		WithEndpoint(server.URL).
+		WithHedgingPolicy(policy)
+	client := NewClient(cfg)
+
+	start := time.Now()
+	result, err := client.GetObject(context.TODO(), &GetObjectRequest{
+		Bucket: Ptr("bucket"),
+		Key:    Ptr("key"),
+		Range:  Ptr("bytes=0-10"),
+	})
+	assert.Nil(t, err)
+	data, err := io.ReadAll(result.Body)
+	assert.Nil(t, err)
+	result.Body.Close()
+	assert.Equal(t, "hello world", string(data))
+	assert.Equal(t, "bytes=0-10", result.Headers.Get("x-oss-hedge"))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&count))
+	assert.True(t, time.Since(start) < time.Second)
+
+	atomic.StoreInt32(&count, 0)
+	start = time.Now()
+	_, err = client.HeadObject(context.TODO(), &HeadObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&count))
+	assert.True(t, time.Since(start) < time.Second)
+
+	// writes are not hedged
+	atomic.StoreInt32(&count, 0)
+	start = time.Now()
+	_, err = client.PutObject(context.TODO(), &PutObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&count))
+	assert.True(t, time.Since(start) >= 2*time.Second)
+}
+
+func TestHedgingPolicy_Presign(t *testing.T) {
+	var count int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		time.Sleep(100 * time.Millisecond)
+		w.Header().Set("x-oss-request-id", "id-1234")
+		w.WriteHeader(200)
+	}))
+	defer server.Close()
+
+	policy := NewHedgingPolicy(func(o *HedgingOptions) {
+		o.Delay = time.Second
+		o.MinSamples = 10
+	})
+	cfg := LoadDefaultConfig().
+		WithCredentialsProvider(credentials.NewStaticCredentialsProvider("ak", "sk")).
+		WithRegion("cn-hangzhou").
+		WithEndpoint(server.URL).
+		WithHedgingPolicy(policy)
+	client := NewClient(cfg)
+
+	// presigning sends nothing, no latency is observed
+	for i := 0; i < 20; i++ {
+		_, err := client.Presign(context.TODO(), &GetObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")})
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, time.Second, policy.Delay())
+
+	result, err := client.GetObject(context.TODO(), &GetObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")})
+	assert.Nil(t, err)
+	result.Body.Close()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&count))
+}
@@ -606,6 +606,92 @@ func NewMultiBytesReader(b [][]byte) *MultiBytesReader {
 	return r
 }
 
+// multiReadSeeker is the logical concatenation of the readers, each one is read from its current position
+// up to its size. Unlike io.MultiReader, it can be rewound.
+type multiReadSeeker struct {
+	readers []io.ReadSeeker
+	bases   []int64
+	sizes   []int64
+	size    int64
+
+	offset     int64
+	cur        int
+	positioned bool
+}
+
+func newMultiReadSeeker(readers []io.ReadSeeker, sizes []int64) (*multiReadSeeker, error) {
+	r := &multiReadSeeker{
+		readers: readers,
+		bases:   make([]int64, len(readers)),
+		sizes:   sizes,
+	}
+	for i, rd := range readers {
+		base, err := rd.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, err
+		}
+		r.bases[i] = base
+		r.size += sizes[i]
+	}
+	return r, nil
+}
+
+func (r *multiReadSeeker) Len() int {
+	if r.offset >= r.size {
+		return 0
+	}
+	return int(r.size - r.offset)
+}
+
+func (r *multiReadSeeker) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+
+	i, pos := 0, r.offset
+	for pos >= r.sizes[i] {
+		pos -= r.sizes[i]
+		i++
+	}
+	if i != r.cur || !r.positioned {
+		if _, err := r.readers[i].Seek(r.bases[i]+pos, io.SeekStart); err != nil {
+			return 0, err
+		}
+		r.cur, r.positioned = i, true
+	}
+
+	if remaining := r.sizes[i] - pos; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err := r.readers[i].Read(p)
+	r.offset += int64(n)
+	if err == io.EOF {
+		if pos+int64(n) < r.sizes[i] {
+			return n, io.ErrUnexpectedEOF
+		}
+		err = nil
+	}
+	return n, err
+}
+
+func (r *multiReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, errors.New("multiReadSeeker.Seek: invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("multiReadSeeker.Seek: negative position")
+	}
+	r.offset = offset
+	r.positioned = false
+	return offset, nil
+}
+
 type RangeReader struct {
 	in     io.ReadCloser // Input reader
 	closed bool          // whether we have closed the underlying stream
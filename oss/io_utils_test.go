@@ -788,6 +788,46 @@ func TestMultiBytesReaderReset(t *testing.T) {
 	}
 }
 
+func TestMultiReadSeeker(t *testing.T) {
+	body := strings.NewReader("xxhello world")
+	body.Seek(2, io.SeekStart)
+	r, err := newMultiReadSeeker(
+		[]io.ReadSeeker{strings.NewReader("head-"), strings.NewReader(""), body, strings.NewReader("-tail")},
+		[]int64{5, 0, 5, 5})
+	assert.Nil(t, err)
+	assert.Equal(t, 15, r.Len())
+
+	// the body is read up to its size
+	data, err := io.ReadAll(iotest.OneByteReader(r))
+	assert.Nil(t, err)
+	assert.Equal(t, "head-hello-tail", string(data))
+	assert.Equal(t, 0, r.Len())
+
+	// rewound from the position of the body
+	n, err := r.Seek(0, io.SeekStart)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), n)
+	data, err = io.ReadAll(r)
+	assert.Nil(t, err)
+	assert.Equal(t, "head-hello-tail", string(data))
+
+	n, err = r.Seek(-8, io.SeekEnd)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(7), n)
+	data, err = io.ReadAll(r)
+	assert.Nil(t, err)
+	assert.Equal(t, "llo-tail", string(data))
+
+	_, err = r.Seek(-1, io.SeekStart)
+	assert.NotNil(t, err)
+
+	// the body is shorter than its size
+	r, err = newMultiReadSeeker([]io.ReadSeeker{strings.NewReader("abc"), strings.NewReader("d")}, []int64{3, 2})
+	assert.Nil(t, err)
+	_, err = io.ReadAll(r)
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+}
+
 func TestReaderZero(t *testing.T) {
 	if l := (&MultiBytesReader{}).Len(); l != 0 {
 		t.Errorf("Len: got %d, want 0", l)
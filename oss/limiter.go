@@ -2,6 +2,9 @@ package oss
 
 import (
 	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/time/rate"
@@ -17,10 +20,14 @@ type BwTokenBucket struct {
 	// Byte/S
 	Bandwidth int64
 	Limiter   *rate.Limiter
+
+	metrics MetricsCollector
+	labels  MetricLabels
 }
 
 type BwTokenBuckets [BwTokenBucketSlots]*BwTokenBucket
 
+// newBwTokenBucket creates a bandwidth token bucket, 0 means no limit.
 func newBwTokenBucket(bandwidth int64) *BwTokenBucket {
 	return &BwTokenBucket{
 		Bandwidth: bandwidth,
@@ -28,17 +35,255 @@ func newBwTokenBucket(bandwidth int64) *BwTokenBucket {
 	}
 }
 
-func newEmptyTokenBucket(bandwidth int64) *rate.Limiter {
+func maxBurstSize(bandwidth int64) int {
 	const defaultMaxBurstSize = 4 * 1024 * 1024
 	maxBurstSize := (bandwidth * defaultMaxBurstSize) / (256 * 1024 * 1024)
 	if maxBurstSize < defaultMaxBurstSize {
 		maxBurstSize = defaultMaxBurstSize
 	}
-	tb := rate.NewLimiter(rate.Limit(bandwidth), int(maxBurstSize))
-	tb.AllowN(time.Now(), int(maxBurstSize))
+	return int(maxBurstSize)
+}
+
+func newEmptyTokenBucket(bandwidth int64) *rate.Limiter {
+	if bandwidth <= 0 {
+		// no limit, the bucket is bypassed
+		return rate.NewLimiter(0, 0)
+	}
+	burst := maxBurstSize(bandwidth)
+	tb := rate.NewLimiter(rate.Limit(bandwidth), burst)
+	tb.AllowN(time.Now(), burst)
 	return tb
 }
 
+// SetBandwidth changes the bandwidth at runtime, in Byte/S. 0 means no limit.
+func (tb *BwTokenBucket) SetBandwidth(bandwidth int64) {
+	if bandwidth < 0 {
+		bandwidth = 0
+	}
+	if bandwidth > 0 {
+		now := time.Now()
+		tb.Limiter.SetBurstAt(now, maxBurstSize(bandwidth))
+		tb.Limiter.SetLimitAt(now, rate.Limit(bandwidth))
+	}
+	atomic.StoreInt64(&tb.Bandwidth, bandwidth)
+}
+
+func (tb *BwTokenBucket) setMetricsCollector(mc MetricsCollector, direction string) {
+	tb.metrics = mc
+	tb.labels = MetricLabels{MetricLabelDirection: direction}
+}
+
 func (tb *BwTokenBucket) LimitBandwidth(n int) {
-	tb.Limiter.WaitN(context.Background(), n)
+	if atomic.LoadInt64(&tb.Bandwidth) <= 0 {
+		return
+	}
+	if tb.metrics == nil {
+		tb.Limiter.WaitN(context.Background(), n)
+		return
+	}
+	// observe the delay of the token bucket, not the precision of the timer
+	now := time.Now()
+	r := tb.Limiter.ReserveN(now, n)
+	if !r.OK() {
+		return
+	}
+	delay := r.DelayFrom(now)
+	if delay <= 0 {
+		return
+	}
+	time.Sleep(delay)
+	if delay >= time.Millisecond {
+		tb.metrics.ObserveHistogram(MetricThrottleWaitDuration, delay.Seconds(), tb.labels)
+	}
+}
+
+// OperationClass groups the operations for the request rate limiter.
+type OperationClass string
+
+const (
+	OperationClassList   OperationClass = "list"
+	OperationClassWrite  OperationClass = "write"
+	OperationClassDelete OperationClass = "delete"
+)
+
+// operationClassOf returns the class of the operation, or empty string for the read operations.
+func operationClassOf(input *OperationInput) OperationClass {
+	switch {
+	case strings.HasPrefix(input.OpName, "List"):
+		return OperationClassList
+	case strings.HasPrefix(input.OpName, "Delete"),
+		input.OpName == "AbortMultipartUpload",
+		input.Method == "DELETE":
+		return OperationClassDelete
+	case input.Method == "PUT", input.Method == "POST":
+		return OperationClassWrite
+	}
+	return ""
+}
+
+type RequestRateLimiterOptions struct {
+	// The operations per second of all requests, 0 means no limit.
+	Limit float64
+
+	// The operations per second of the requests to a bucket.
+	BucketLimits map[string]float64
+
+	// The operations per second of the requests in an operation class.
+	ClassLimits map[OperationClass]float64
+
+	// Return a RequestRateLimitError instead of waiting for a token.
+	FailFast bool
+}
+
+// RequestRateLimiter limits the operations per second of the client.
+// It is safe for concurrent use and can be shared by several clients, so that they stay under the same budget.
+type RequestRateLimiter struct {
+	mu       sync.RWMutex
+	global   *rate.Limiter
+	buckets  map[string]*rate.Limiter
+	classes  map[OperationClass]*rate.Limiter
+	failFast bool
+}
+
+// NewRequestRateLimiter creates a request rate limiter, every limit allows a burst of one second.
+func NewRequestRateLimiter(optFns ...func(*RequestRateLimiterOptions)) *RequestRateLimiter {
+	options := RequestRateLimiterOptions{}
+	for _, fn := range optFns {
+		fn(&options)
+	}
+
+	l := &RequestRateLimiter{
+		buckets:  map[string]*rate.Limiter{},
+		classes:  map[OperationClass]*rate.Limiter{},
+		failFast: options.FailFast,
+	}
+	l.global = newRequestLimiter(options.Limit)
+	for bucket, limit := range options.BucketLimits {
+		l.buckets[bucket] = newRequestLimiter(limit)
+	}
+	for class, limit := range options.ClassLimits {
+		l.classes[class] = newRequestLimiter(limit)
+	}
+	return l
+}
+
+func newRequestLimiter(limit float64) *rate.Limiter {
+	if limit <= 0 {
+		return nil
+	}
+	return rate.NewLimiter(rate.Limit(limit), requestBurst(limit))
+}
+
+func requestBurst(limit float64) int {
+	if limit < 1 {
+		return 1
+	}
+	return int(limit)
+}
+
+func setRequestLimit(l *rate.Limiter, limit float64) *rate.Limiter {
+	if limit <= 0 {
+		return nil
+	}
+	if l == nil {
+		return newRequestLimiter(limit)
+	}
+	now := time.Now()
+	l.SetBurstAt(now, requestBurst(limit))
+	l.SetLimitAt(now, rate.Limit(limit))
+	return l
+}
+
+// SetLimit changes the operations per second of all requests, 0 means no limit.
+func (l *RequestRateLimiter) SetLimit(limit float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.global = setRequestLimit(l.global, limit)
+}
+
+// SetBucketLimit changes the operations per second of the requests to the bucket, 0 means no limit.
+func (l *RequestRateLimiter) SetBucketLimit(bucket string, limit float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if rl := setRequestLimit(l.buckets[bucket], limit); rl != nil {
+		l.buckets[bucket] = rl
+	} else {
+		delete(l.buckets, bucket)
+	}
+}
+
+// SetClassLimit changes the operations per second of the requests in the operation class, 0 means no limit.
+func (l *RequestRateLimiter) SetClassLimit(class OperationClass, limit float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if rl := setRequestLimit(l.classes[class], limit); rl != nil {
+		l.classes[class] = rl
+	} else {
+		delete(l.classes, class)
+	}
+}
+
+// Wait takes a token from every limit that applies to the request.
+// It blocks until all tokens are available, or returns a RequestRateLimitError at once in fail-fast mode.
+func (l *RequestRateLimiter) Wait(ctx context.Context, bucket string, class OperationClass) error {
+	type scopedLimiter struct {
+		scope   string
+		limiter *rate.Limiter
+	}
+
+	l.mu.RLock()
+	var limiters []scopedLimiter
+	if l.global != nil {
+		limiters = append(limiters, scopedLimiter{"global", l.global})
+	}
+	if rl, ok := l.buckets[bucket]; ok && bucket != "" {
+		limiters = append(limiters, scopedLimiter{"bucket", rl})
+	}
+	if rl, ok := l.classes[class]; ok && class != "" {
+		limiters = append(limiters, scopedLimiter{string(class), rl})
+	}
+	l.mu.RUnlock()
+
+	if len(limiters) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	var (
+		delay        time.Duration
+		scope        string
+		reservations []*rate.Reservation
+	)
+	cancel := func() {
+		for _, r := range reservations {
+			r.CancelAt(now)
+		}
+	}
+	for _, sl := range limiters {
+		r := sl.limiter.ReserveN(now, 1)
+		reservations = append(reservations, r)
+		if !r.OK() {
+			cancel()
+			return &RequestRateLimitError{Scope: sl.scope}
+		}
+		if d := r.DelayFrom(now); d > delay {
+			delay = d
+			scope = sl.scope
+		}
+	}
+
+	if delay == 0 {
+		return nil
+	}
+
+	if l.failFast {
+		cancel()
+		return &RequestRateLimitError{Scope: scope, RetryAfter: delay}
+	}
+
+	if err := sleepWithContext(ctx, delay); err != nil {
+		cancel()
+		return &CanceledError{Err: err}
+	}
+	return nil
 }
@@ -0,0 +1,247 @@
+package oss
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/credentials"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOperationClassOf(t *testing.T) {
+	assert.Equal(t, OperationClassList, operationClassOf(&OperationInput{OpName: "ListObjectsV2", Method: "GET"}))
+	assert.Equal(t, OperationClassList, operationClassOf(&OperationInput{OpName: "ListParts", Method: "GET"}))
+	assert.Equal(t, OperationClassDelete, operationClassOf(&OperationInput{OpName: "DeleteObject", Method: "DELETE"}))
+	assert.Equal(t, OperationClassDelete, operationClassOf(&OperationInput{OpName: "DeleteMultipleObjects", Method: "POST"}))
+	assert.Equal(t, OperationClassDelete, operationClassOf(&OperationInput{OpName: "AbortMultipartUpload", Method: "DELETE"}))
+	assert.Equal(t, OperationClassWrite, operationClassOf(&OperationInput{OpName: "PutObject", Method: "PUT"}))
+	assert.Equal(t, OperationClassWrite, operationClassOf(&OperationInput{OpName: "CompleteMultipartUpload", Method: "POST"}))
+	assert.Equal(t, OperationClass(""), operationClassOf(&OperationInput{OpName: "GetObject", Method: "GET"}))
+	assert.Equal(t, OperationClass(""), operationClassOf(&OperationInput{OpName: "HeadObject", Method: "HEAD"}))
+}
+
+func TestRequestRateLimiter_Wait(t *testing.T) {
+	l := NewRequestRateLimiter(func(o *RequestRateLimiterOptions) {
+		o.Limit = 100
+		o.BucketLimits = map[string]float64{"bucket": 10}
+		o.ClassLimits = map[OperationClass]float64{OperationClassDelete: 5}
+	})
+
+	// burst of one second
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		assert.Nil(t, l.Wait(context.TODO(), "bucket", OperationClassWrite))
+	}
+	assert.True(t, time.Since(start) < 50*time.Millisecond)
+
+	// bucket limit
+	assert.Nil(t, l.Wait(context.TODO(), "bucket", OperationClassWrite))
+	assert.True(t, time.Since(start) >= 80*time.Millisecond)
+
+	// other bucket is only limited by the global limit
+	start = time.Now()
+	for i := 0; i < 50; i++ {
+		assert.Nil(t, l.Wait(context.TODO(), "other", ""))
+	}
+	assert.True(t, time.Since(start) < 50*time.Millisecond)
+
+	// canceled
+	ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Millisecond)
+	defer cancel()
+	err := l.Wait(ctx, "bucket", "")
+	var cerr *CanceledError
+	assert.True(t, errors.As(err, &cerr))
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestRequestRateLimiter_FailFast(t *testing.T) {
+	l := NewRequestRateLimiter(func(o *RequestRateLimiterOptions) {
+		o.Limit = 100
+		o.ClassLimits = map[OperationClass]float64{OperationClassDelete: 2}
+		o.FailFast = true
+	})
+
+	assert.Nil(t, l.Wait(context.TODO(), "bucket", OperationClassDelete))
+	assert.Nil(t, l.Wait(context.TODO(), "bucket", OperationClassDelete))
+	err := l.Wait(context.TODO(), "bucket", OperationClassDelete)
+	var rerr *RequestRateLimitError
+	assert.True(t, errors.As(err, &rerr))
+	assert.Equal(t, "delete", rerr.Scope)
+	assert.True(t, rerr.RetryAfter > 0)
+
+	// the global tokens of the failed request are given back
+	for i := 0; i < 98; i++ {
+		assert.Nil(t, l.Wait(context.TODO(), "bucket", OperationClassWrite))
+	}
+	err = l.Wait(context.TODO(), "bucket", OperationClassWrite)
+	assert.True(t, errors.As(err, &rerr))
+	assert.Equal(t, "global", rerr.Scope)
+}
+
+func TestRequestRateLimiter_SetLimit(t *testing.T) {
+	l := NewRequestRateLimiter(func(o *RequestRateLimiterOptions) {
+		o.FailFast = true
+	})
+	for i := 0; i < 100; i++ {
+		assert.Nil(t, l.Wait(context.TODO(), "bucket", OperationClassList))
+	}
+
+	l.SetBucketLimit("bucket", 1)
+	assert.Nil(t, l.Wait(context.TODO(), "bucket", OperationClassList))
+	assert.NotNil(t, l.Wait(context.TODO(), "bucket", OperationClassList))
+	l.SetBucketLimit("bucket", 0)
+	assert.Nil(t, l.Wait(context.TODO(), "bucket", OperationClassList))
+
+	l.SetClassLimit(OperationClassList, 1)
+	assert.Nil(t, l.Wait(context.TODO(), "bucket", OperationClassList))
+	assert.NotNil(t, l.Wait(context.TODO(), "bucket", OperationClassList))
+	assert.Nil(t, l.Wait(context.TODO(), "bucket", OperationClassWrite))
+	l.SetClassLimit(OperationClassList, 0)
+
+	l.SetLimit(1)
+	assert.Nil(t, l.Wait(context.TODO(), "bucket", ""))
+	assert.NotNil(t, l.Wait(context.TODO(), "bucket", ""))
+	l.SetLimit(0)
+	assert.Nil(t, l.Wait(context.TODO(), "bucket", ""))
+}
+
+func TestRequestRateLimiter_Client(t *testing.T) {
+	var count int32
+	server := testSetupMockServer(t, 200, nil, nil, func(t *testing.T, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+	})
+	defer server.Close()
+
+	limiter := NewRequestRateLimiter(func(o *RequestRateLimiterOptions) {
+		o.ClassLimits = map[OperationClass]float64{OperationClassDelete: 1}
+		o.FailFast = true
+	})
+	cfg := LoadDefaultConfig().
+		WithCredentialsProvider(credentials.NewAnonymousCredentialsProvider()).
+		WithRegion("cn-hangzhou").
+		WithEndpoint(server.URL).
+		WithRequestRateLimiter(limiter)
+
+	client := NewClient(cfg)
+	_, err := client.DeleteObject(context.TODO(), &DeleteObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")})
+	assert.Nil(t, err)
+	_, err = client.DeleteObject(context.TODO(), &DeleteObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")})
+	var rerr *RequestRateLimitError
+	assert.True(t, errors.As(err, &rerr))
+	var operr *OperationError
+	assert.True(t, errors.As(err, &operr))
+	assert.Equal(t, "DeleteObject", operr.Operation())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&count))
+
+	// reads are not limited by the delete class
+	_, err = client.HeadObject(context.TODO(), &HeadObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")})
+	assert.Nil(t, err)
+
+	// shared by goroutines
+	limiter = NewRequestRateLimiter(func(o *RequestRateLimiterOptions) {
+		o.Limit = 20
+	})
+	client = NewClient(cfg, func(o *Options) { o.RequestRateLimiter = limiter })
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				_, err := client.HeadObject(context.TODO(), &HeadObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")})
+				assert.Nil(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+	// 20 in the burst, the others at 20 per second
+	assert.True(t, time.Since(start) >= 900*time.Millisecond)
+}
+
+func TestRequestRateLimiter_Presign(t *testing.T) {
+	var count int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		io.Copy(io.Discard, r.Body)
+		w.Header().Set("Content-Type", "application/xml")
+		w.WriteHeader(200)
+		io.WriteString(w, `<InitiateMultipartUploadResult><Bucket>bucket</Bucket><Key>key</Key><UploadId>upload-id</UploadId></InitiateMultipartUploadResult>`)
+	}))
+	defer server.Close()
+
+	limiter := NewRequestRateLimiter(func(o *RequestRateLimiterOptions) {
+		o.Limit = 1
+		o.FailFast = true
+	})
+	cfg := LoadDefaultConfig().
+		WithCredentialsProvider(credentials.NewStaticCredentialsProvider("ak", "sk")).
+		WithRegion("cn-hangzhou").
+		WithEndpoint(server.URL).
+		WithRequestRateLimiter(limiter)
+	client := NewClient(cfg)
+
+	// presigning sends no request, it is not limited
+	for i := 0; i < 5; i++ {
+		_, err := client.Presign(context.TODO(), &GetObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")})
+		assert.Nil(t, err)
+	}
+
+	// only the initiate request takes a token
+	result, err := client.PresignMultipartUpload(context.TODO(), &PresignMultipartUploadRequest{
+		InitiateRequest: &InitiateMultipartUploadRequest{Bucket: Ptr("bucket"), Key: Ptr("key")},
+		PartCount:       10,
+	})
+	assert.Nil(t, err)
+	assert.Len(t, result.Parts, 10)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&count))
+
+	_, err = client.HeadObject(context.TODO(), &HeadObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")})
+	var rerr *RequestRateLimitError
+	assert.True(t, errors.As(err, &rerr))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&count))
+}
+
+func TestBwTokenBucket_SetBandwidth(t *testing.T) {
+	tb := newBwTokenBucket(0)
+	start := time.Now()
+	tb.LimitBandwidth(100 * 1024 * 1024)
+	assert.True(t, time.Since(start) < 10*time.Millisecond)
+
+	tb.SetBandwidth(100 * 1024)
+	assert.Equal(t, int64(100*1024), tb.Bandwidth)
+	// the bucket is empty at first
+	start = time.Now()
+	tb.LimitBandwidth(10 * 1024)
+	assert.True(t, time.Since(start) >= 80*time.Millisecond)
+
+	tb.SetBandwidth(0)
+	start = time.Now()
+	tb.LimitBandwidth(100 * 1024 * 1024)
+	assert.True(t, time.Since(start) < 10*time.Millisecond)
+}
+
+func TestClient_SetBandwidthlimit(t *testing.T) {
+	cfg := LoadDefaultConfig().
+		WithRegion("cn-hangzhou").
+		WithUploadBandwidthlimit(100)
+	client := NewClient(cfg)
+	assert.Equal(t, int64(100*1024), client.inner.BwTokenBuckets[BwTokenBucketSlotTx].Bandwidth)
+	assert.Equal(t, int64(0), client.inner.BwTokenBuckets[BwTokenBucketSlotRx].Bandwidth)
+
+	assert.Nil(t, client.SetUploadBandwidthlimit(200))
+	assert.Equal(t, int64(200*1024), client.inner.BwTokenBuckets[BwTokenBucketSlotTx].Bandwidth)
+	assert.Nil(t, client.SetDownloadBandwidthlimit(300))
+	assert.Equal(t, int64(300*1024), client.inner.BwTokenBuckets[BwTokenBucketSlotRx].Bandwidth)
+	assert.NotNil(t, client.SetDownloadBandwidthlimit(-1))
+
+	client = NewClient(cfg.WithHttpClient(&http.Client{}))
+	assert.NotNil(t, client.SetUploadBandwidthlimit(200))
+}
@@ -1,8 +1,15 @@
 package oss
 
 import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"log"
+	"net/http"
+	"net/url"
+	"sort"
 	"strings"
 )
 
@@ -39,6 +46,40 @@ type Logger interface {
 	Level() int
 }
 
+// LogField is a key-value pair of a structured log entry.
+type LogField struct {
+	Key   string
+	Value any
+}
+
+// StructuredLogger is a Logger that also accepts structured log entries.
+// The client logs the entries with LogFields when the logger implements it,
+// otherwise the fields are formatted into the message.
+type StructuredLogger interface {
+	Logger
+	LogFields(ctx context.Context, level int, msg string, fields ...LogField)
+}
+
+// Log field keys
+const (
+	LogFieldCorrelationId = "correlation_id"
+	LogFieldOperation     = "op"
+	LogFieldBucket        = "bucket"
+	LogFieldKey           = "key"
+	LogFieldAttempt       = "attempt"
+	LogFieldRequestId     = "request_id"
+	LogFieldLatency       = "latency"
+	LogFieldDelay         = "delay"
+	LogFieldStatus        = "status"
+	LogFieldMethod        = "method"
+	LogFieldHost          = "host"
+	LogFieldPath          = "path"
+	LogFieldQuery         = "query"
+	LogFieldHeaders       = "headers"
+	LogFieldError         = "error"
+	LogFieldStringToSign  = "string_to_sign"
+)
+
 type nopLogger struct {
 }
 
@@ -110,6 +151,149 @@ func (l *standardLogger) Level() int {
 	return l.level
 }
 
+func (l *standardLogger) LogFields(_ context.Context, level int, msg string, fields ...LogField) {
+	if l.level < level || level <= LogOff {
+		return
+	}
+	l.printf(level, "%s", formatLogFields(msg, fields))
+}
+
+// formatLogFields formats the fields as "msg, k1:v1, k2:v2".
+func formatLogFields(msg string, fields []LogField) string {
+	var buf bytes.Buffer
+	buf.WriteString(msg)
+	for _, f := range fields {
+		buf.WriteString(", ")
+		buf.WriteString(f.Key)
+		buf.WriteString(":")
+		switch v := f.Value.(type) {
+		case http.Header:
+			keys := make([]string, 0, len(v))
+			for k := range v {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for i, k := range keys {
+				if i > 0 {
+					buf.WriteString("\t")
+				}
+				buf.WriteString(k + ":" + strings.Join(v[k], " "))
+			}
+		default:
+			fmt.Fprintf(&buf, "%v", v)
+		}
+	}
+	return buf.String()
+}
+
+// logFields logs the entry with LogFields if the logger supports it, otherwise as formatted text.
+// The correlation id in the context is always added as the first field.
+func logFields(ctx context.Context, l Logger, level int, msg string, fields ...LogField) {
+	if l == nil || l.Level() < level {
+		return
+	}
+	if cid := CorrelationIdFromContext(ctx); cid != "" {
+		fields = append([]LogField{{Key: LogFieldCorrelationId, Value: cid}}, fields...)
+	}
+	if sl, ok := l.(StructuredLogger); ok {
+		sl.LogFields(ctx, level, msg, fields...)
+		return
+	}
+	text := formatLogFields(msg, fields)
+	switch level {
+	case LogError:
+		l.Errorf("%s", text)
+	case LogWarn:
+		l.Warnf("%s", text)
+	case LogInfo:
+		l.Infof("%s", text)
+	case LogDebug:
+		l.Debugf("%s", text)
+	}
+}
+
+type correlationIdKey struct{}
+
+// WithCorrelationId returns a copy of the context with the correlation id.
+// All the operations invoked with the context, including their retries, log the same correlation id.
+func WithCorrelationId(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIdKey{}, id)
+}
+
+// CorrelationIdFromContext returns the correlation id in the context, or empty string if not set.
+func CorrelationIdFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(correlationIdKey{}).(string)
+	return id
+}
+
+// ensureCorrelationId adds a new correlation id into the context if it does not have one.
+func ensureCorrelationId(ctx context.Context) context.Context {
+	if ctx == nil || CorrelationIdFromContext(ctx) != "" {
+		return ctx
+	}
+	return WithCorrelationId(ctx, newCorrelationId())
+}
+
+func newCorrelationId() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+const redactedValue = "******"
+
+// sensitive headers, in canonical form
+var redactedHeaders = map[string]bool{
+	"Authorization":        true,
+	"Proxy-Authorization":  true,
+	"X-Oss-Security-Token": true,
+	HeaderOssSSECKey:       true,
+	"X-Oss-Copy-Source-Server-Side-Encryption-Customer-Key": true,
+}
+
+// sensitive query parameters, in lower case
+var redactedQueries = map[string]bool{
+	"signature":            true,
+	"x-oss-signature":      true,
+	"security-token":       true,
+	"x-oss-security-token": true,
+}
+
+// redactHeader returns a copy of the header with the credentials and the keys masked.
+func redactHeader(header http.Header) http.Header {
+	h := make(http.Header, len(header))
+	for k, v := range header {
+		if redactedHeaders[http.CanonicalHeaderKey(k)] {
+			h[k] = []string{redactedValue}
+		} else {
+			h[k] = v
+		}
+	}
+	return h
+}
+
+// redactQuery masks the signature and the security token in the raw query.
+func redactQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return rawQuery
+	}
+	parts := strings.Split(rawQuery, "&")
+	for i, part := range parts {
+		name, _, _ := strings.Cut(part, "=")
+		key := name
+		if n, err := url.QueryUnescape(name); err == nil {
+			key = n
+		}
+		if redactedQueries[strings.ToLower(key)] {
+			parts[i] = name + "=" + redactedValue
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
 func ToLogLevel(s string) int {
 	s = strings.ToLower(s)
 	switch s {
@@ -128,3 +312,4 @@ func ToLogLevel(s string) int {
 
 var _ Logger = (*nopLogger)(nil)
 var _ Logger = (*standardLogger)(nil)
+var _ StructuredLogger = (*standardLogger)(nil)
@@ -0,0 +1,102 @@
+//go:build go1.21
+
+package oss
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// NewSlogLogger returns a Logger that writes structured records to the slog handler.
+// The log level is derived from the levels that the handler enables.
+func NewSlogLogger(h slog.Handler) Logger {
+	return &slogLogger{
+		logger: slog.New(h),
+	}
+}
+
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+func toSlogLevel(level int) slog.Level {
+	switch level {
+	case LogError:
+		return slog.LevelError
+	case LogWarn:
+		return slog.LevelWarn
+	case LogInfo:
+		return slog.LevelInfo
+	}
+	return slog.LevelDebug
+}
+
+func (l *slogLogger) log(level int, format string, v ...any) {
+	ctx := context.Background()
+	if !l.logger.Enabled(ctx, toSlogLevel(level)) {
+		return
+	}
+	l.logger.Log(ctx, toSlogLevel(level), fmt.Sprintf(format, v...))
+}
+
+func (l *slogLogger) Debugf(format string, v ...any) {
+	l.log(LogDebug, format, v...)
+}
+
+func (l *slogLogger) Infof(format string, v ...any) {
+	l.log(LogInfo, format, v...)
+}
+
+func (l *slogLogger) Warnf(format string, v ...any) {
+	l.log(LogWarn, format, v...)
+}
+
+func (l *slogLogger) Errorf(format string, v ...any) {
+	l.log(LogError, format, v...)
+}
+
+func (l *slogLogger) Level() int {
+	ctx := context.Background()
+	for _, level := range []int{LogDebug, LogInfo, LogWarn, LogError} {
+		if l.logger.Enabled(ctx, toSlogLevel(level)) {
+			return level
+		}
+	}
+	return LogOff
+}
+
+func (l *slogLogger) LogFields(ctx context.Context, level int, msg string, fields ...LogField) {
+	if level <= LogOff {
+		return
+	}
+	attrs := make([]slog.Attr, 0, len(fields))
+	for _, f := range fields {
+		attrs = append(attrs, toSlogAttr(f))
+	}
+	l.logger.LogAttrs(ctx, toSlogLevel(level), msg, attrs...)
+}
+
+func toSlogAttr(f LogField) slog.Attr {
+	switch v := f.Value.(type) {
+	case http.Header:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		attrs := make([]any, 0, len(keys))
+		for _, k := range keys {
+			attrs = append(attrs, slog.String(k, strings.Join(v[k], " ")))
+		}
+		return slog.Group(f.Key, attrs...)
+	case error:
+		return slog.String(f.Key, v.Error())
+	}
+	return slog.Any(f.Key, f.Value)
+}
+
+var _ StructuredLogger = (*slogLogger)(nil)
@@ -0,0 +1,93 @@
+//go:build go1.21
+
+package oss
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/credentials"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlogLogger(t *testing.T) {
+	buff := bytes.NewBuffer(nil)
+	l := NewSlogLogger(slog.NewTextHandler(buff, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	assert.Equal(t, LogWarn, l.Level())
+
+	l.Infof("%s", "123")
+	assert.Equal(t, "", buff.String())
+	l.Warnf("%s", "123")
+	assert.Contains(t, buff.String(), "level=WARN msg=123")
+
+	l = NewSlogLogger(slog.NewTextHandler(buff, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	assert.Equal(t, LogDebug, l.Level())
+	l = NewSlogLogger(slog.NewTextHandler(buff, &slog.HandlerOptions{Level: slog.LevelError + 1}))
+	assert.Equal(t, LogOff, l.Level())
+}
+
+func TestSlogLogger_Client(t *testing.T) {
+	server := testSetupMockServer(t, 200, map[string]string{"x-oss-request-id": "id-1234"}, nil,
+		func(t *testing.T, r *http.Request) {})
+	defer server.Close()
+
+	buff := bytes.NewBuffer(nil)
+	cfg := LoadDefaultConfig().
+		WithCredentialsProvider(credentials.NewStaticCredentialsProvider("ak", "sk", "token")).
+		WithRegion("cn-hangzhou").
+		WithEndpoint(server.URL).
+		WithLogger(NewSlogLogger(slog.NewJSONHandler(buff, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	client := NewClient(cfg)
+	_, err := client.PutObject(context.TODO(), &PutObjectRequest{
+		Bucket: Ptr("bucket"),
+		Key:    Ptr("key"),
+	})
+	assert.Nil(t, err)
+
+	var records []map[string]any
+	scanner := bufio.NewScanner(buff)
+	for scanner.Scan() {
+		var r map[string]any
+		assert.Nil(t, json.Unmarshal(scanner.Bytes(), &r))
+		records = append(records, r)
+	}
+	assert.NotEmpty(t, records)
+
+	cid := records[0][LogFieldCorrelationId]
+	assert.NotEmpty(t, cid)
+	var end, attempt, request map[string]any
+	for _, r := range records {
+		assert.Equal(t, cid, r[LogFieldCorrelationId])
+		switch r["msg"] {
+		case "InvokeOperation End":
+			end = r
+		case "Attempt End":
+			attempt = r
+		}
+		if _, ok := r[LogFieldMethod]; ok {
+			request = r
+		}
+	}
+
+	assert.Equal(t, "INFO", end["level"])
+	assert.Equal(t, "PutObject", end[LogFieldOperation])
+	assert.Equal(t, "bucket", end[LogFieldBucket])
+	assert.Equal(t, "key", end[LogFieldKey])
+	assert.Equal(t, float64(200), end[LogFieldStatus])
+	assert.Equal(t, "id-1234", end[LogFieldRequestId])
+	assert.NotNil(t, end[LogFieldLatency])
+
+	assert.Equal(t, "DEBUG", attempt["level"])
+	assert.Equal(t, float64(1), attempt[LogFieldAttempt])
+
+	headers, ok := request[LogFieldHeaders].(map[string]any)
+	assert.True(t, ok)
+	assert.Equal(t, "******", headers["Authorization"])
+	assert.Equal(t, "******", headers["X-Oss-Security-Token"])
+}
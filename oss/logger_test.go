@@ -2,9 +2,14 @@ package oss
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"net/http"
+	"strings"
 	"testing"
 
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/credentials"
+
 	"github.com/stretchr/testify/assert"
 )
 
@@ -94,3 +99,122 @@ func TestStrToLogLevel(t *testing.T) {
 	assert.Equal(t, LogError, ToLogLevel("err"))
 	assert.Equal(t, LogError, ToLogLevel("eRR"))
 }
+
+func TestLogFields(t *testing.T) {
+	buff := bytes.NewBuffer(nil)
+	l := NewLogger(LogInfo, LogPrinterFunc(func(a ...any) {
+		fmt.Fprint(buff, a...)
+	}))
+
+	ctx := WithCorrelationId(context.Background(), "cid-123")
+	logFields(ctx, l, LogInfo, "msg",
+		LogField{Key: LogFieldOperation, Value: "PutObject"},
+		LogField{Key: LogFieldHeaders, Value: http.Header{"B": {"2"}, "A": {"1", "11"}}})
+	assert.Equal(t, "INFO msg, correlation_id:cid-123, op:PutObject, headers:A:1 11\tB:2", buff.String())
+
+	buff.Reset()
+	logFields(ctx, l, LogDebug, "msg")
+	assert.Equal(t, "", buff.String())
+
+	// plain Logger
+	buff.Reset()
+	logFields(context.Background(), &testPlainLogger{buff: buff}, LogWarn, "msg", LogField{Key: "k", Value: 1})
+	assert.Equal(t, "warn:msg, k:1", buff.String())
+}
+
+type testPlainLogger struct {
+	buff *bytes.Buffer
+}
+
+func (l *testPlainLogger) Debugf(format string, v ...any) {}
+func (l *testPlainLogger) Infof(format string, v ...any)  {}
+func (l *testPlainLogger) Warnf(format string, v ...any) {
+	fmt.Fprintf(l.buff, "warn:"+format, v...)
+}
+func (l *testPlainLogger) Errorf(format string, v ...any) {}
+func (l *testPlainLogger) Level() int                     { return LogDebug }
+
+func TestCorrelationId(t *testing.T) {
+	assert.Equal(t, "", CorrelationIdFromContext(context.Background()))
+	assert.Equal(t, "", CorrelationIdFromContext(nil))
+
+	ctx := ensureCorrelationId(context.Background())
+	cid := CorrelationIdFromContext(ctx)
+	assert.Len(t, cid, 16)
+	assert.Equal(t, cid, CorrelationIdFromContext(ensureCorrelationId(ctx)))
+
+	ctx = WithCorrelationId(context.Background(), "my-id")
+	assert.Equal(t, "my-id", CorrelationIdFromContext(ensureCorrelationId(ctx)))
+}
+
+func TestRedact(t *testing.T) {
+	header := http.Header{
+		"Authorization":                                         {"OSS4-HMAC-SHA256 Credential=ak/20240101/cn-hangzhou/oss/aliyun_v4_request,Signature=abc"},
+		"X-Oss-Security-Token":                                  {"token"},
+		"X-Oss-Server-Side-Encryption-Customer-Key":             {"key"},
+		"X-Oss-Server-Side-Encryption-Customer-Key-Md5":         {"md5"},
+		"X-Oss-Copy-Source-Server-Side-Encryption-Customer-Key": {"key"},
+		"Content-Type":                                          {"text/plain"},
+	}
+	h := redactHeader(header)
+	assert.Equal(t, "******", h.Get("Authorization"))
+	assert.Equal(t, "******", h.Get("X-Oss-Security-Token"))
+	assert.Equal(t, "******", h.Get("X-Oss-Server-Side-Encryption-Customer-Key"))
+	assert.Equal(t, "******", h.Get("X-Oss-Copy-Source-Server-Side-Encryption-Customer-Key"))
+	assert.Equal(t, "md5", h.Get("X-Oss-Server-Side-Encryption-Customer-Key-Md5"))
+	assert.Equal(t, "text/plain", h.Get("Content-Type"))
+	assert.Equal(t, "token", header.Get("X-Oss-Security-Token"))
+
+	assert.Equal(t, "", redactQuery(""))
+	assert.Equal(t, "acl", redactQuery("acl"))
+	assert.Equal(t, "OSSAccessKeyId=ak&Expires=1&Signature=******&security-token=******",
+		redactQuery("OSSAccessKeyId=ak&Expires=1&Signature=abc%2B&security-token=tk"))
+	assert.Equal(t, "x-oss-credential=ak%2F20240101&x-oss-signature=******&x-oss-security-token=******",
+		redactQuery("x-oss-credential=ak%2F20240101&x-oss-signature=abc&x-oss-security-token=tk"))
+}
+
+func TestLogFields_Client(t *testing.T) {
+	server := testSetupMockServer(t, 500, map[string]string{"x-oss-request-id": "id-1234"}, nil,
+		func(t *testing.T, r *http.Request) {})
+	defer server.Close()
+
+	buff := bytes.NewBuffer(nil)
+	cfg := LoadDefaultConfig().
+		WithCredentialsProvider(credentials.NewStaticCredentialsProvider("ak", "sk", "token")).
+		WithRegion("cn-hangzhou").
+		WithEndpoint(server.URL).
+		WithRetryMaxAttempts(2).
+		WithLogLevel(LogDebug).
+		WithLogPrinter(LogPrinterFunc(func(a ...any) {
+			fmt.Fprint(buff, a...)
+			buff.WriteString("\n")
+		}))
+
+	client := NewClient(cfg)
+	_, err := client.GetObject(WithCorrelationId(context.TODO(), "cid-123"), &GetObjectRequest{
+		Bucket: Ptr("bucket"),
+		Key:    Ptr("key"),
+		RequestCommon: RequestCommon{
+			Headers: map[string]string{
+				HeaderOssSSECKey: "MTIzNDU2Nzg5MDEyMzQ1Njc4OTAxMjM0NTY3ODkwMTI=",
+			},
+		},
+	})
+	assert.NotNil(t, err)
+
+	lines := strings.Split(strings.TrimSpace(buff.String()), "\n")
+	assert.NotEmpty(t, lines)
+	for _, line := range lines {
+		if strings.HasPrefix(line, "INFO ") || strings.HasPrefix(line, "DEBUG ") {
+			assert.Contains(t, line, "correlation_id:cid-123")
+		}
+	}
+	out := buff.String()
+	assert.Contains(t, out, "INFO Attempt retry, correlation_id:cid-123, op:GetObject, bucket:bucket, key:key, attempt:2")
+	assert.Contains(t, out, "DEBUG Attempt End, correlation_id:cid-123, op:GetObject, bucket:bucket, key:key, attempt:1, status:500, request_id:id-1234")
+	assert.Contains(t, out, "Authorization:******")
+	assert.Contains(t, out, "X-Oss-Security-Token:******")
+	assert.Contains(t, out, "X-Oss-Server-Side-Encryption-Customer-Key:******")
+	assert.NotContains(t, out, "token\t")
+	assert.NotContains(t, out, "MTIzNDU2Nzg5MDEyMzQ1Njc4OTAxMjM0NTY3ODkwMTI=")
+}
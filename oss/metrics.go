@@ -0,0 +1,162 @@
+package oss
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MetricsCollector receives the metrics of the client, the retryer,
+// the bandwidth limiter and the transfer managers.
+// It must be safe for concurrent use.
+type MetricsCollector interface {
+	// AddCounter adds the value to a monotonic counter.
+	AddCounter(name string, value float64, labels MetricLabels)
+
+	// AddGauge adds the delta, which may be negative, to a gauge.
+	AddGauge(name string, delta float64, labels MetricLabels)
+
+	// ObserveHistogram records a sample in a histogram.
+	ObserveHistogram(name string, value float64, labels MetricLabels)
+}
+
+// MetricLabels are the label names and values of a metric.
+type MetricLabels map[string]string
+
+// Metric names
+const (
+	// Histogram, the latency of every http attempt in seconds. Labels: operation, bucket.
+	MetricRequestDuration = "oss_request_duration_seconds"
+
+	// Counter, the number of http attempts. Labels: operation, bucket, status_code.
+	MetricRequestsTotal = "oss_requests_total"
+
+	// Counter, the number of failed http attempts. Labels: operation, bucket, error_code.
+	MetricErrorsTotal = "oss_errors_total"
+
+	// Counter, the number of retried http attempts. Labels: operation, bucket.
+	MetricRetriesTotal = "oss_retries_total"
+
+	// Histogram, the time spent waiting for the bandwidth limiter in seconds. Labels: direction.
+	MetricThrottleWaitDuration = "oss_throttle_wait_seconds"
+
+	// Counter, the number of request body bytes sent. Labels: operation, bucket.
+	MetricBytesSentTotal = "oss_bytes_sent_total"
+
+	// Counter, the number of response body bytes received. Labels: operation, bucket.
+	MetricBytesReceivedTotal = "oss_bytes_received_total"
+
+	// Gauge, the number of parts being transferred. Labels: transfer, bucket.
+	MetricPartsInFlight = "oss_transfer_parts_in_flight"
+)
+
+// Metric label names
+const (
+	MetricLabelOperation  = "operation"
+	MetricLabelBucket     = "bucket"
+	MetricLabelStatusCode = "status_code"
+	MetricLabelErrorCode  = "error_code"
+	MetricLabelDirection  = "direction"
+	MetricLabelTransfer   = "transfer"
+)
+
+// The error code label value of the errors that are not service errors
+const (
+	MetricErrorCodeClientError = "ClientError"
+	MetricErrorCodeCanceled    = "Canceled"
+)
+
+// getMetricsCollector returns the metrics collector of the client behind the api client.
+func getMetricsCollector(c any) MetricsCollector {
+	switch t := c.(type) {
+	case *Client:
+		return t.options.MetricsCollector
+	case *EncryptionClient:
+		return t.Unwrap().options.MetricsCollector
+	}
+	return nil
+}
+
+func operationMetricLabels(input *OperationInput) MetricLabels {
+	return MetricLabels{
+		MetricLabelOperation: input.OpName,
+		MetricLabelBucket:    ToString(input.Bucket),
+	}
+}
+
+func withMetricLabel(labels MetricLabels, name, value string) MetricLabels {
+	l := make(MetricLabels, len(labels)+1)
+	for k, v := range labels {
+		l[k] = v
+	}
+	l[name] = value
+	return l
+}
+
+// recordHttpAttempt records the latency, status code and error of a http attempt.
+func recordHttpAttempt(mc MetricsCollector, labels MetricLabels, statusCode int, err error, elapsed time.Duration) {
+	var serr *ServiceError
+	if errors.As(err, &serr) {
+		statusCode = serr.StatusCode
+	}
+	mc.ObserveHistogram(MetricRequestDuration, elapsed.Seconds(), labels)
+	mc.AddCounter(MetricRequestsTotal, 1, withMetricLabel(labels, MetricLabelStatusCode, strconv.Itoa(statusCode)))
+	if err == nil {
+		return
+	}
+	code := MetricErrorCodeClientError
+	var cerr *CanceledError
+	if serr != nil {
+		code = serr.Code
+	} else if errors.As(err, &cerr) {
+		code = MetricErrorCodeCanceled
+	}
+	mc.AddCounter(MetricErrorsTotal, 1, withMetricLabel(labels, MetricLabelErrorCode, code))
+}
+
+// trackPartInFlight increases the parts in flight gauge, the returned function decreases it.
+func trackPartInFlight(mc MetricsCollector, transfer string, bucket *string) func() {
+	if mc == nil {
+		return func() {}
+	}
+	labels := MetricLabels{
+		MetricLabelTransfer: transfer,
+		MetricLabelBucket:   ToString(bucket),
+	}
+	mc.AddGauge(MetricPartsInFlight, 1, labels)
+	return func() {
+		mc.AddGauge(MetricPartsInFlight, -1, labels)
+	}
+}
+
+// metricsReadCloser counts the bytes read from the response body,
+// the count is reported once, on EOF or on close.
+type metricsReadCloser struct {
+	io.ReadCloser
+	mc     MetricsCollector
+	labels MetricLabels
+	n      int64
+	once   sync.Once
+}
+
+func (r *metricsReadCloser) Read(p []byte) (n int, err error) {
+	n, err = r.ReadCloser.Read(p)
+	r.n += int64(n)
+	if err == io.EOF {
+		r.report()
+	}
+	return n, err
+}
+
+func (r *metricsReadCloser) Close() error {
+	r.report()
+	return r.ReadCloser.Close()
+}
+
+func (r *metricsReadCloser) report() {
+	r.once.Do(func() {
+		r.mc.AddCounter(MetricBytesReceivedTotal, float64(r.n), r.labels)
+	})
+}
@@ -0,0 +1,252 @@
+package oss
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultMetricsHistogramBuckets are the upper bounds of the histogram buckets, in seconds.
+var DefaultMetricsHistogramBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+type metricKind int
+
+const (
+	metricKindCounter metricKind = iota
+	metricKindGauge
+	metricKindHistogram
+)
+
+func (k metricKind) String() string {
+	switch k {
+	case metricKindGauge:
+		return "gauge"
+	case metricKindHistogram:
+		return "histogram"
+	}
+	return "counter"
+}
+
+type metricSeries struct {
+	name   string
+	kind   metricKind
+	labels MetricLabels
+
+	// counter or gauge
+	value float64
+
+	// histogram
+	count        uint64
+	sum          float64
+	bucketCounts []uint64
+}
+
+// MemoryMetricsCollector keeps the metrics in memory.
+// It is also a http.Handler that serves the metrics in the Prometheus text exposition format.
+type MemoryMetricsCollector struct {
+	mu      sync.Mutex
+	buckets []float64
+	series  map[string]*metricSeries
+}
+
+var _ MetricsCollector = (*MemoryMetricsCollector)(nil)
+
+// NewMemoryMetricsCollector creates an in-memory metrics collector.
+// The histogram buckets default to DefaultMetricsHistogramBuckets.
+func NewMemoryMetricsCollector(buckets ...float64) *MemoryMetricsCollector {
+	if len(buckets) == 0 {
+		buckets = DefaultMetricsHistogramBuckets
+	}
+	b := append([]float64(nil), buckets...)
+	sort.Float64s(b)
+	return &MemoryMetricsCollector{
+		buckets: b,
+		series:  map[string]*metricSeries{},
+	}
+}
+
+func (c *MemoryMetricsCollector) AddCounter(name string, value float64, labels MetricLabels) {
+	if value < 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.get(name, metricKindCounter, labels).value += value
+}
+
+func (c *MemoryMetricsCollector) AddGauge(name string, delta float64, labels MetricLabels) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.get(name, metricKindGauge, labels).value += delta
+}
+
+func (c *MemoryMetricsCollector) ObserveHistogram(name string, value float64, labels MetricLabels) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.get(name, metricKindHistogram, labels)
+	s.count++
+	s.sum += value
+	for i, b := range c.buckets {
+		if value <= b {
+			s.bucketCounts[i]++
+		}
+	}
+}
+
+// Counter returns the value of the counter, or 0 if it does not exist.
+func (c *MemoryMetricsCollector) Counter(name string, labels MetricLabels) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if s, ok := c.series[seriesKey(name, labels)]; ok && s.kind == metricKindCounter {
+		return s.value
+	}
+	return 0
+}
+
+// Gauge returns the value of the gauge, or 0 if it does not exist.
+func (c *MemoryMetricsCollector) Gauge(name string, labels MetricLabels) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if s, ok := c.series[seriesKey(name, labels)]; ok && s.kind == metricKindGauge {
+		return s.value
+	}
+	return 0
+}
+
+// Histogram returns the number and the sum of the samples in the histogram.
+func (c *MemoryMetricsCollector) Histogram(name string, labels MetricLabels) (count uint64, sum float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if s, ok := c.series[seriesKey(name, labels)]; ok && s.kind == metricKindHistogram {
+		return s.count, s.sum
+	}
+	return 0, 0
+}
+
+// Reset removes all metrics.
+func (c *MemoryMetricsCollector) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.series = map[string]*metricSeries{}
+}
+
+// WritePrometheus writes all metrics in the Prometheus text exposition format.
+func (c *MemoryMetricsCollector) WritePrometheus(w io.Writer) error {
+	c.mu.Lock()
+	series := make([]*metricSeries, 0, len(c.series))
+	keys := make([]string, 0, len(c.series))
+	for k := range c.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		s := *c.series[k]
+		s.bucketCounts = append([]uint64(nil), s.bucketCounts...)
+		series = append(series, &s)
+	}
+	buckets := c.buckets
+	c.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	var lastName string
+	for _, s := range series {
+		if s.name != lastName {
+			fmt.Fprintf(bw, "# TYPE %s %s\n", s.name, s.kind)
+			lastName = s.name
+		}
+		switch s.kind {
+		case metricKindHistogram:
+			for i, b := range buckets {
+				fmt.Fprintf(bw, "%s_bucket%s %d\n", s.name, formatLabels(s.labels, "le", formatFloat(b)), s.bucketCounts[i])
+			}
+			fmt.Fprintf(bw, "%s_bucket%s %d\n", s.name, formatLabels(s.labels, "le", "+Inf"), s.count)
+			fmt.Fprintf(bw, "%s_sum%s %s\n", s.name, formatLabels(s.labels, "", ""), formatFloat(s.sum))
+			fmt.Fprintf(bw, "%s_count%s %d\n", s.name, formatLabels(s.labels, "", ""), s.count)
+		default:
+			fmt.Fprintf(bw, "%s%s %s\n", s.name, formatLabels(s.labels, "", ""), formatFloat(s.value))
+		}
+	}
+	return bw.Flush()
+}
+
+// ServeHTTP serves the metrics in the Prometheus text exposition format.
+func (c *MemoryMetricsCollector) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.WritePrometheus(w)
+}
+
+func (c *MemoryMetricsCollector) get(name string, kind metricKind, labels MetricLabels) *metricSeries {
+	key := seriesKey(name, labels)
+	s, ok := c.series[key]
+	if !ok {
+		s = &metricSeries{
+			name:   name,
+			kind:   kind,
+			labels: make(MetricLabels, len(labels)),
+		}
+		for k, v := range labels {
+			s.labels[k] = v
+		}
+		if kind == metricKindHistogram {
+			s.bucketCounts = make([]uint64, len(c.buckets))
+		}
+		c.series[key] = s
+	}
+	return s
+}
+
+func sortedLabelNames(labels MetricLabels) []string {
+	names := make([]string, 0, len(labels))
+	for k := range labels {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func seriesKey(name string, labels MetricLabels) string {
+	var b strings.Builder
+	b.WriteString(name)
+	for _, k := range sortedLabelNames(labels) {
+		b.WriteByte(0)
+		b.WriteString(k)
+		b.WriteByte(0)
+		b.WriteString(labels[k])
+	}
+	return b.String()
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// formatLabels formats the labels as {k1="v1",k2="v2"}, the extra label is appended last if not empty.
+func formatLabels(labels MetricLabels, extraName, extraValue string) string {
+	var pairs []string
+	for _, k := range sortedLabelNames(labels) {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, k, labelValueReplacer.Replace(labels[k])))
+	}
+	if extraName != "" {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, extraName, extraValue))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
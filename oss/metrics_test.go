@@ -0,0 +1,242 @@
+package oss
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/credentials"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryMetricsCollector(t *testing.T) {
+	c := NewMemoryMetricsCollector(0.1, 1)
+	labels := MetricLabels{"operation": "PutObject", "bucket": "bucket"}
+
+	c.AddCounter("requests_total", 1, labels)
+	c.AddCounter("requests_total", 2, MetricLabels{"bucket": "bucket", "operation": "PutObject"})
+	c.AddCounter("requests_total", -1, labels)
+	assert.Equal(t, float64(3), c.Counter("requests_total", labels))
+	assert.Equal(t, float64(0), c.Counter("requests_total", nil))
+
+	c.AddGauge("in_flight", 2, nil)
+	c.AddGauge("in_flight", -1, nil)
+	assert.Equal(t, float64(1), c.Gauge("in_flight", nil))
+
+	c.ObserveHistogram("duration_seconds", 0.05, labels)
+	c.ObserveHistogram("duration_seconds", 0.5, labels)
+	c.ObserveHistogram("duration_seconds", 5, labels)
+	count, sum := c.Histogram("duration_seconds", labels)
+	assert.Equal(t, uint64(3), count)
+	assert.Equal(t, 5.55, sum)
+
+	var buf bytes.Buffer
+	assert.Nil(t, c.WritePrometheus(&buf))
+	assert.Equal(t, `# TYPE duration_seconds histogram
+duration_seconds_bucket{bucket="bucket",operation="PutObject",le="0.1"} 1
+duration_seconds_bucket{bucket="bucket",operation="PutObject",le="1"} 2
+duration_seconds_bucket{bucket="bucket",operation="PutObject",le="+Inf"} 3
+duration_seconds_sum{bucket="bucket",operation="PutObject"} 5.55
+duration_seconds_count{bucket="bucket",operation="PutObject"} 3
+# TYPE in_flight gauge
+in_flight 1
+# TYPE requests_total counter
+requests_total{bucket="bucket",operation="PutObject"} 3
+`, buf.String())
+
+	c.Reset()
+	c.AddCounter("escape", 1, MetricLabels{"key": "a\"b\\c\nd"})
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/plain; version=0.0.4")
+	assert.Equal(t, "# TYPE escape counter\nescape{key=\"a\\\"b\\\\c\\nd\"} 1\n", rec.Body.String())
+}
+
+func TestMetricsCollector_Client(t *testing.T) {
+	server := testSetupMockServer(t, 200, map[string]string{"x-oss-request-id": "id-1234"}, []byte("hello world"),
+		func(t *testing.T, r *http.Request) {})
+	defer server.Close()
+
+	mc := NewMemoryMetricsCollector()
+	cfg := LoadDefaultConfig().
+		WithCredentialsProvider(credentials.NewAnonymousCredentialsProvider()).
+		WithRegion("cn-hangzhou").
+		WithEndpoint(server.URL).
+		WithMetricsCollector(mc)
+
+	client := NewClient(cfg)
+	_, err := client.PutObject(context.TODO(), &PutObjectRequest{
+		Bucket: Ptr("bucket"),
+		Key:    Ptr("key"),
+		Body:   strings.NewReader("hello oss"),
+	})
+	assert.Nil(t, err)
+
+	labels := MetricLabels{MetricLabelOperation: "PutObject", MetricLabelBucket: "bucket"}
+	count, _ := mc.Histogram(MetricRequestDuration, labels)
+	assert.Equal(t, uint64(1), count)
+	assert.Equal(t, float64(1), mc.Counter(MetricRequestsTotal, withMetricLabel(labels, MetricLabelStatusCode, "200")))
+	assert.Equal(t, float64(9), mc.Counter(MetricBytesSentTotal, labels))
+	assert.Equal(t, float64(11), mc.Counter(MetricBytesReceivedTotal, labels))
+
+	result, err := client.GetObject(context.TODO(), &GetObjectRequest{
+		Bucket: Ptr("bucket"),
+		Key:    Ptr("key"),
+	})
+	assert.Nil(t, err)
+	labels = MetricLabels{MetricLabelOperation: "GetObject", MetricLabelBucket: "bucket"}
+	assert.Equal(t, float64(0), mc.Counter(MetricBytesReceivedTotal, labels))
+	data, err := io.ReadAll(result.Body)
+	assert.Nil(t, err)
+	assert.Equal(t, "hello world", string(data))
+	result.Body.Close()
+	assert.Equal(t, float64(11), mc.Counter(MetricBytesReceivedTotal, labels))
+
+	// presigning sends nothing, no attempt is recorded
+	_, err = client.Presign(context.TODO(), &GetObjectRequest{
+		Bucket: Ptr("bucket"),
+		Key:    Ptr("key"),
+	})
+	assert.Nil(t, err)
+	count, _ = mc.Histogram(MetricRequestDuration, labels)
+	assert.Equal(t, uint64(1), count)
+}
+
+func TestMetricsCollector_Retry(t *testing.T) {
+	server := testSetupMockServer(t, 503, map[string]string{"x-oss-request-id": "id-1234"},
+		[]byte(`<Error><Code>ServiceUnavailable</Code><Message>error</Message><RequestId>id-1234</RequestId></Error>`),
+		func(t *testing.T, r *http.Request) {})
+	defer server.Close()
+
+	mc := NewMemoryMetricsCollector()
+	cfg := LoadDefaultConfig().
+		WithCredentialsProvider(credentials.NewAnonymousCredentialsProvider()).
+		WithRegion("cn-hangzhou").
+		WithEndpoint(server.URL).
+		WithRetryMaxAttempts(3)
+
+	client := NewClient(cfg)
+	_, err := client.GetObject(context.TODO(), &GetObjectRequest{
+		Bucket: Ptr("bucket"),
+		Key:    Ptr("key"),
+	}, func(o *Options) { o.MetricsCollector = mc })
+	assert.NotNil(t, err)
+
+	labels := MetricLabels{MetricLabelOperation: "GetObject", MetricLabelBucket: "bucket"}
+	count, _ := mc.Histogram(MetricRequestDuration, labels)
+	assert.Equal(t, uint64(3), count)
+	assert.Equal(t, float64(2), mc.Counter(MetricRetriesTotal, labels))
+	assert.Equal(t, float64(3), mc.Counter(MetricRequestsTotal, withMetricLabel(labels, MetricLabelStatusCode, "503")))
+	assert.Equal(t, float64(3), mc.Counter(MetricErrorsTotal, withMetricLabel(labels, MetricLabelErrorCode, "ServiceUnavailable")))
+
+	// network error
+	mc.Reset()
+	cfg = LoadDefaultConfig().
+		WithCredentialsProvider(credentials.NewAnonymousCredentialsProvider()).
+		WithRegion("cn-hangzhou").
+		WithEndpoint("http://127.0.0.1:1").
+		WithRetryMaxAttempts(1).
+		WithMetricsCollector(mc)
+	client = NewClient(cfg)
+	_, err = client.GetObject(context.TODO(), &GetObjectRequest{
+		Bucket: Ptr("bucket"),
+		Key:    Ptr("key"),
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, float64(1), mc.Counter(MetricRequestsTotal, withMetricLabel(labels, MetricLabelStatusCode, "0")))
+	assert.Equal(t, float64(1), mc.Counter(MetricErrorsTotal, withMetricLabel(labels, MetricLabelErrorCode, MetricErrorCodeClientError)))
+}
+
+func TestMetricsCollector_BandwidthLimit(t *testing.T) {
+	mc := NewMemoryMetricsCollector()
+	tb := newBwTokenBucket(100 * 1024)
+	tb.setMetricsCollector(mc, "upload")
+	labels := MetricLabels{MetricLabelDirection: "upload"}
+
+	tb.LimitBandwidth(1)
+	count, _ := mc.Histogram(MetricThrottleWaitDuration, labels)
+	assert.Equal(t, uint64(0), count)
+
+	// the bucket is empty at first, wait about 100ms
+	tb.LimitBandwidth(10 * 1024)
+	count, sum := mc.Histogram(MetricThrottleWaitDuration, labels)
+	assert.Equal(t, uint64(1), count)
+	assert.True(t, sum > 0.05)
+
+	// no limit, no wait is observed
+	tb.SetBandwidth(0)
+	tb.LimitBandwidth(100 * 1024 * 1024)
+	count, _ = mc.Histogram(MetricThrottleWaitDuration, labels)
+	assert.Equal(t, uint64(1), count)
+
+	// limited again at runtime, wait about 100ms
+	tb.SetBandwidth(100 * 1024)
+	tb.LimitBandwidth(10 * 1024)
+	count, sum = mc.Histogram(MetricThrottleWaitDuration, labels)
+	assert.Equal(t, uint64(2), count)
+	assert.True(t, sum > 0.1)
+}
+
+func TestMetricsCollector_Uploader(t *testing.T) {
+	partSize := int64(100 * 1024)
+	length := 3*100*1024 + 123
+	partsNum := length/int(partSize) + 1
+	tracker := &uploaderMockTracker{
+		partNum:       partsNum,
+		saveDate:      make([][]byte, partsNum),
+		checkTime:     make([]time.Time, partsNum),
+		timeout:       make([]time.Duration, partsNum),
+		uploadPartErr: make([]bool, partsNum),
+	}
+
+	server := testSetupUploaderMockServer(t, tracker)
+	defer server.Close()
+
+	inFlight := &maxGaugeCollector{MemoryMetricsCollector: NewMemoryMetricsCollector()}
+	cfg := LoadDefaultConfig().
+		WithCredentialsProvider(credentials.NewAnonymousCredentialsProvider()).
+		WithRegion("cn-hangzhou").
+		WithEndpoint(server.URL).
+		WithMetricsCollector(inFlight)
+
+	client := NewClient(cfg)
+	u := NewUploader(client, func(uo *UploaderOptions) {
+		uo.ParallelNum = 2
+		uo.PartSize = partSize
+	})
+	tracker.timeout[0] = 200 * time.Millisecond
+	tracker.timeout[1] = 200 * time.Millisecond
+
+	_, err := u.UploadFrom(context.TODO(), &PutObjectRequest{
+		Bucket: Ptr("bucket"),
+		Key:    Ptr("key"),
+	}, bytes.NewReader([]byte(randStr(length))))
+	assert.Nil(t, err)
+
+	labels := MetricLabels{MetricLabelTransfer: "upload", MetricLabelBucket: "bucket"}
+	assert.Equal(t, float64(0), inFlight.Gauge(MetricPartsInFlight, labels))
+	assert.Equal(t, float64(2), inFlight.max)
+	assert.Equal(t, float64(partsNum),
+		inFlight.Counter(MetricRequestsTotal, MetricLabels{MetricLabelOperation: "UploadPart", MetricLabelBucket: "bucket", MetricLabelStatusCode: "200"}))
+	assert.Equal(t, float64(length),
+		inFlight.Counter(MetricBytesSentTotal, MetricLabels{MetricLabelOperation: "UploadPart", MetricLabelBucket: "bucket"}))
+}
+
+type maxGaugeCollector struct {
+	*MemoryMetricsCollector
+	max float64
+}
+
+func (c *maxGaugeCollector) AddGauge(name string, delta float64, labels MetricLabels) {
+	c.MemoryMetricsCollector.AddGauge(name, delta, labels)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if v := c.series[seriesKey(name, labels)].value; v > c.max {
+		c.max = v
+	}
+}
@@ -0,0 +1,125 @@
+package oss
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/signer"
+)
+
+// InitializeHandler runs the rest of the operation for the given input.
+type InitializeHandler func(ctx context.Context, input *OperationInput) (*OperationOutput, error)
+
+// SerializeHandler sends the http request built from the input and returns the operation output.
+type SerializeHandler func(ctx context.Context, input *OperationInput, request *http.Request) (*OperationOutput, error)
+
+// SignHandler signs the request in the signing context, it is called once per attempt.
+type SignHandler func(ctx context.Context, signingCtx *signer.SigningContext) error
+
+// SendHandler sends the signed http request, it is called once per attempt.
+type SendHandler func(ctx context.Context, request *http.Request) (*http.Response, error)
+
+// DeserializeHandler converts the final http response into the operation output.
+type DeserializeHandler func(ctx context.Context, input *OperationInput, response *http.Response) (*OperationOutput, error)
+
+// InitializeMiddleware is called before the input is converted into a http request.
+type InitializeMiddleware func(ctx context.Context, input *OperationInput, next InitializeHandler) (*OperationOutput, error)
+
+// SerializeMiddleware is called after the http request is built and before it is signed and sent.
+type SerializeMiddleware func(ctx context.Context, input *OperationInput, request *http.Request, next SerializeHandler) (*OperationOutput, error)
+
+// SignMiddleware is called around the signing of every attempt.
+type SignMiddleware func(ctx context.Context, signingCtx *signer.SigningContext, next SignHandler) error
+
+// SendMiddleware is called around the sending of every attempt.
+type SendMiddleware func(ctx context.Context, request *http.Request, next SendHandler) (*http.Response, error)
+
+// DeserializeMiddleware is called when the http response is converted into the operation output.
+type DeserializeMiddleware func(ctx context.Context, input *OperationInput, response *http.Response, next DeserializeHandler) (*OperationOutput, error)
+
+// Middleware hooks into one or more phases of an operation.
+// The phases run in the order initialize, serialize, sign, send and deserialize.
+// Within a phase, the middleware registered first is the outermost one,
+// and the client's middlewares are always outside the operation's middlewares.
+// A middleware may short-circuit a phase by not calling next.
+type Middleware struct {
+	// The name of the middleware, used to identify it.
+	Name string
+
+	Initialize InitializeMiddleware
+
+	Serialize SerializeMiddleware
+
+	Sign SignMiddleware
+
+	Send SendMiddleware
+
+	Deserialize DeserializeMiddleware
+}
+
+// AddMiddlewares appends the middlewares to the client or to the operation.
+func AddMiddlewares(middlewares ...Middleware) func(*Options) {
+	return func(o *Options) {
+		o.Middlewares = append(o.Middlewares, middlewares...)
+	}
+}
+
+func decorateInitializeHandler(h InitializeHandler, middlewares []Middleware) InitializeHandler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		if m := middlewares[i].Initialize; m != nil {
+			next := h
+			h = func(ctx context.Context, input *OperationInput) (*OperationOutput, error) {
+				return m(ctx, input, next)
+			}
+		}
+	}
+	return h
+}
+
+func decorateSerializeHandler(h SerializeHandler, middlewares []Middleware) SerializeHandler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		if m := middlewares[i].Serialize; m != nil {
+			next := h
+			h = func(ctx context.Context, input *OperationInput, request *http.Request) (*OperationOutput, error) {
+				return m(ctx, input, request, next)
+			}
+		}
+	}
+	return h
+}
+
+func decorateSignHandler(h SignHandler, middlewares []Middleware) SignHandler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		if m := middlewares[i].Sign; m != nil {
+			next := h
+			h = func(ctx context.Context, signingCtx *signer.SigningContext) error {
+				return m(ctx, signingCtx, next)
+			}
+		}
+	}
+	return h
+}
+
+func decorateSendHandler(h SendHandler, middlewares []Middleware) SendHandler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		if m := middlewares[i].Send; m != nil {
+			next := h
+			h = func(ctx context.Context, request *http.Request) (*http.Response, error) {
+				return m(ctx, request, next)
+			}
+		}
+	}
+	return h
+}
+
+func decorateDeserializeHandler(h DeserializeHandler, middlewares []Middleware) DeserializeHandler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		if m := middlewares[i].Deserialize; m != nil {
+			next := h
+			h = func(ctx context.Context, input *OperationInput, response *http.Response) (*OperationOutput, error) {
+				return m(ctx, input, response, next)
+			}
+		}
+	}
+	return h
+}
@@ -0,0 +1,342 @@
+package oss
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/credentials"
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/signer"
+	"github.com/stretchr/testify/assert"
+)
+
+func testRecordMiddleware(name string, records *[]string) Middleware {
+	return Middleware{
+		Name: name,
+		Initialize: func(ctx context.Context, input *OperationInput, next InitializeHandler) (*OperationOutput, error) {
+			*records = append(*records, name+":initialize")
+			return next(ctx, input)
+		},
+		Serialize: func(ctx context.Context, input *OperationInput, request *http.Request, next SerializeHandler) (*OperationOutput, error) {
+			*records = append(*records, name+":serialize")
+			return next(ctx, input, request)
+		},
+		Sign: func(ctx context.Context, signingCtx *signer.SigningContext, next SignHandler) error {
+			*records = append(*records, name+":sign")
+			return next(ctx, signingCtx)
+		},
+		Send: func(ctx context.Context, request *http.Request, next SendHandler) (*http.Response, error) {
+			*records = append(*records, name+":send")
+			return next(ctx, request)
+		},
+		Deserialize: func(ctx context.Context, input *OperationInput, response *http.Response, next DeserializeHandler) (*OperationOutput, error) {
+			*records = append(*records, name+":deserialize")
+			return next(ctx, input, response)
+		},
+	}
+}
+
+func TestMiddleware_Order(t *testing.T) {
+	server := testSetupMockServer(t, 200, map[string]string{"x-oss-request-id": "id-1234"}, nil,
+		func(t *testing.T, r *http.Request) {})
+	defer server.Close()
+
+	var records []string
+	cfg := LoadDefaultConfig().
+		WithCredentialsProvider(credentials.NewStaticCredentialsProvider("ak", "sk")).
+		WithRegion("cn-hangzhou").
+		WithEndpoint(server.URL).
+		WithMiddlewares(testRecordMiddleware("client1", &records), testRecordMiddleware("client2", &records))
+
+	client := NewClient(cfg)
+	assert.Len(t, client.options.Middlewares, 2)
+
+	input := &OperationInput{
+		OpName: "GetBucketAcl",
+		Method: "GET",
+		Bucket: Ptr("bucket"),
+		Parameters: map[string]string{
+			"acl": "",
+		},
+	}
+	output, err := client.InvokeOperation(context.TODO(), input, AddMiddlewares(testRecordMiddleware("op", &records)))
+	assert.Nil(t, err)
+	assert.Equal(t, 200, output.StatusCode)
+	assert.Equal(t, []string{
+		"client1:initialize", "client2:initialize", "op:initialize",
+		"client1:serialize", "client2:serialize", "op:serialize",
+		"client1:sign", "client2:sign", "op:sign",
+		"client1:send", "client2:send", "op:send",
+		"client1:deserialize", "client2:deserialize", "op:deserialize",
+	}, records)
+
+	// per-operation middlewares do not leak into the client
+	assert.Len(t, client.options.Middlewares, 2)
+	records = nil
+	_, err = client.InvokeOperation(context.TODO(), input)
+	assert.Nil(t, err)
+	assert.Len(t, records, 10)
+}
+
+func TestMiddleware_Retry(t *testing.T) {
+	server := testSetupMockServer(t, 500, nil, nil, func(t *testing.T, r *http.Request) {})
+	defer server.Close()
+
+	var records []string
+	cfg := LoadDefaultConfig().
+		WithCredentialsProvider(credentials.NewAnonymousCredentialsProvider()).
+		WithRegion("cn-hangzhou").
+		WithEndpoint(server.URL).
+		WithRetryMaxAttempts(2).
+		WithMiddlewares(testRecordMiddleware("mw", &records))
+
+	client := NewClient(cfg)
+	_, err := client.InvokeOperation(context.TODO(), &OperationInput{
+		OpName: "ListBuckets",
+		Method: "GET",
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, []string{
+		"mw:initialize", "mw:serialize",
+		"mw:sign", "mw:send",
+		"mw:sign", "mw:send",
+	}, records)
+}
+
+func TestMiddleware_MutateRequest(t *testing.T) {
+	server := testSetupMockServer(t, 200, nil, nil, func(t *testing.T, r *http.Request) {
+		assert.Equal(t, "tenant-1", r.Header.Get("X-Oss-Meta-Tenant"))
+		assert.Contains(t, r.Header.Get("Authorization"), "OSS4-HMAC-SHA256")
+		assert.Equal(t, "/bucket/key?tenant=tenant-1", r.URL.RequestURI())
+	})
+	defer server.Close()
+
+	tenant := Middleware{
+		Name: "tenant",
+		Initialize: func(ctx context.Context, input *OperationInput, next InitializeHandler) (*OperationOutput, error) {
+			if input.Parameters == nil {
+				input.Parameters = map[string]string{}
+			}
+			input.Parameters["tenant"] = "tenant-1"
+			return next(ctx, input)
+		},
+		Serialize: func(ctx context.Context, input *OperationInput, request *http.Request, next SerializeHandler) (*OperationOutput, error) {
+			request.Header.Set("X-Oss-Meta-Tenant", "tenant-1")
+			return next(ctx, input, request)
+		},
+		Sign: func(ctx context.Context, signingCtx *signer.SigningContext, next SignHandler) error {
+			err := next(ctx, signingCtx)
+			assert.Contains(t, signingCtx.StringToSign, "OSS4-HMAC-SHA256")
+			return err
+		},
+	}
+
+	cfg := LoadDefaultConfig().
+		WithCredentialsProvider(credentials.NewStaticCredentialsProvider("ak", "sk")).
+		WithRegion("cn-hangzhou").
+		WithEndpoint(server.URL).
+		WithUsePathStyle(true)
+
+	client := NewClient(cfg, AddMiddlewares(tenant))
+	_, err := client.GetObject(context.TODO(), &GetObjectRequest{
+		Bucket: Ptr("bucket"),
+		Key:    Ptr("key"),
+	})
+	assert.Nil(t, err)
+}
+
+func TestMiddleware_ShortCircuit(t *testing.T) {
+	cfg := LoadDefaultConfig().
+		WithCredentialsProvider(credentials.NewStaticCredentialsProvider("ak", "sk")).
+		WithRegion("cn-hangzhou").
+		WithEndpoint("oss-cn-hangzhou.aliyuncs.com")
+
+	// short-circuit the send phase, the response handlers still run
+	send := Middleware{
+		Name: "stub-send",
+		Send: func(ctx context.Context, request *http.Request, next SendHandler) (*http.Response, error) {
+			assert.Equal(t, "bucket.oss-cn-hangzhou.aliyuncs.com", request.URL.Host)
+			assert.NotEmpty(t, request.Header.Get("Authorization"))
+			return &http.Response{
+				Status:     "200 OK",
+				StatusCode: 200,
+				Header:     http.Header{"X-Oss-Request-Id": []string{"stub-id"}, "Content-Length": []string{"5"}},
+				Body:       io.NopCloser(strings.NewReader("hello")),
+				Request:    request,
+			}, nil
+		},
+	}
+	client := NewClient(cfg)
+	result, err := client.GetObject(context.TODO(), &GetObjectRequest{
+		Bucket: Ptr("bucket"),
+		Key:    Ptr("key"),
+	}, AddMiddlewares(send))
+	assert.Nil(t, err)
+	assert.Equal(t, "stub-id", result.Headers.Get(HeaderOssRequestID))
+	data, _ := io.ReadAll(result.Body)
+	assert.Equal(t, "hello", string(data))
+
+	send.Send = func(ctx context.Context, request *http.Request, next SendHandler) (*http.Response, error) {
+		return &http.Response{
+			Status:     "404 Not Found",
+			StatusCode: 404,
+			Header:     http.Header{"X-Oss-Request-Id": []string{"stub-id"}},
+			Body: io.NopCloser(bytes.NewReader([]byte(
+				`<Error><Code>NoSuchKey</Code><Message>not exist</Message><RequestId>stub-id</RequestId></Error>`))),
+			Request: request,
+		}, nil
+	}
+	_, err = client.GetObject(context.TODO(), &GetObjectRequest{
+		Bucket: Ptr("bucket"),
+		Key:    Ptr("key"),
+	}, AddMiddlewares(send))
+	var serr *ServiceError
+	assert.True(t, errors.As(err, &serr))
+	assert.Equal(t, "NoSuchKey", serr.Code)
+
+	// short-circuit the whole operation
+	stubErr := errors.New("stub error")
+	initialize := Middleware{
+		Name: "stub-initialize",
+		Initialize: func(ctx context.Context, input *OperationInput, next InitializeHandler) (*OperationOutput, error) {
+			assert.Equal(t, "DeleteObject", input.OpName)
+			return nil, stubErr
+		},
+	}
+	_, err = client.DeleteObject(context.TODO(), &DeleteObjectRequest{
+		Bucket: Ptr("bucket"),
+		Key:    Ptr("key"),
+	}, AddMiddlewares(initialize))
+	var operr *OperationError
+	assert.True(t, errors.As(err, &operr))
+	assert.Equal(t, "DeleteObject", operr.Operation())
+	assert.True(t, errors.Is(err, stubErr))
+}
+
+func TestMiddleware_Deserialize(t *testing.T) {
+	server := testSetupMockServer(t, 200, map[string]string{"x-oss-request-id": "id-1234"}, []byte("hello world"),
+		func(t *testing.T, r *http.Request) {})
+	defer server.Close()
+
+	audit := Middleware{
+		Name: "audit",
+		Deserialize: func(ctx context.Context, input *OperationInput, response *http.Response, next DeserializeHandler) (*OperationOutput, error) {
+			output, err := next(ctx, input, response)
+			if err == nil {
+				output.OpMetadata.Set("audit", response.Header.Get(HeaderOssRequestID))
+			}
+			return output, err
+		},
+	}
+
+	cfg := LoadDefaultConfig().
+		WithCredentialsProvider(credentials.NewAnonymousCredentialsProvider()).
+		WithRegion("cn-hangzhou").
+		WithEndpoint(server.URL).
+		WithMiddlewares(audit)
+
+	client := NewClient(cfg)
+	result, err := client.GetObject(context.TODO(), &GetObjectRequest{
+		Bucket: Ptr("bucket"),
+		Key:    Ptr("key"),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "id-1234", result.OpMetadata.Get("audit"))
+}
+
+type testCloseTrackingBody struct {
+	io.Reader
+	closed bool
+}
+
+func (b *testCloseTrackingBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestMiddleware_DeserializeClosesBody(t *testing.T) {
+	cfg := LoadDefaultConfig().
+		WithCredentialsProvider(credentials.NewStaticCredentialsProvider("ak", "sk")).
+		WithRegion("cn-hangzhou").
+		WithEndpoint("oss-cn-hangzhou.aliyuncs.com")
+	client := NewClient(cfg)
+
+	var body *testCloseTrackingBody
+	send := Middleware{
+		Name: "stub-send",
+		Send: func(ctx context.Context, request *http.Request, next SendHandler) (*http.Response, error) {
+			body = &testCloseTrackingBody{Reader: strings.NewReader("hello")}
+			return &http.Response{
+				Status:     "200 OK",
+				StatusCode: 200,
+				Header:     http.Header{},
+				Body:       body,
+				Request:    request,
+			}, nil
+		},
+	}
+	request := &GetObjectRequest{
+		Bucket: Ptr("bucket"),
+		Key:    Ptr("key"),
+	}
+
+	// the output carries the body
+	result, err := client.GetObject(context.TODO(), request, AddMiddlewares(send))
+	assert.Nil(t, err)
+	assert.False(t, body.closed)
+	result.Body.Close()
+	assert.True(t, body.closed)
+
+	// the deserialize middleware fails
+	stubErr := errors.New("stub error")
+	failed := Middleware{
+		Name: "failed",
+		Deserialize: func(ctx context.Context, input *OperationInput, response *http.Response, next DeserializeHandler) (*OperationOutput, error) {
+			return nil, stubErr
+		},
+	}
+	_, err = client.GetObject(context.TODO(), request, AddMiddlewares(send, failed))
+	assert.True(t, errors.Is(err, stubErr))
+	assert.True(t, body.closed)
+
+	failed.Deserialize = func(ctx context.Context, input *OperationInput, response *http.Response, next DeserializeHandler) (*OperationOutput, error) {
+		output, _ := next(ctx, input, response)
+		return output, stubErr
+	}
+	_, err = client.GetObject(context.TODO(), request, AddMiddlewares(send, failed))
+	assert.True(t, errors.Is(err, stubErr))
+	assert.True(t, body.closed)
+
+	// the deserialize middleware short-circuits without the response body
+	cached := Middleware{
+		Name: "cached",
+		Deserialize: func(ctx context.Context, input *OperationInput, response *http.Response, next DeserializeHandler) (*OperationOutput, error) {
+			return &OperationOutput{
+				Input:      input,
+				StatusCode: 200,
+				Headers:    http.Header{},
+				Body:       io.NopCloser(strings.NewReader("cached")),
+			}, nil
+		},
+	}
+	result, err = client.GetObject(context.TODO(), request, AddMiddlewares(send, cached))
+	assert.Nil(t, err)
+	assert.True(t, body.closed)
+	data, _ := io.ReadAll(result.Body)
+	assert.Equal(t, "cached", string(data))
+}
+
+func TestOptionsCopy_Middlewares(t *testing.T) {
+	opts := Options{
+		Middlewares: []Middleware{{Name: "m1"}},
+	}
+	cp := opts.Copy()
+	cp.Middlewares[0].Name = "m2"
+	cp.Middlewares = append(cp.Middlewares, Middleware{Name: "m3"})
+	assert.Len(t, opts.Middlewares, 1)
+	assert.Equal(t, "m1", opts.Middlewares[0].Name)
+}
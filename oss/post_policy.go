@@ -0,0 +1,100 @@
+package oss
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// The match types of the conditions in the PostObject policy.
+const (
+	PostPolicyEqual      = "eq"
+	PostPolicyStartsWith = "starts-with"
+	PostPolicyIn         = "in"
+	PostPolicyNotIn      = "not-in"
+)
+
+// PostPolicy is the policy of the PostObject form, the request is rejected if its form fields do not match the conditions.
+type PostPolicy struct {
+	expiration time.Time
+	conditions []any
+}
+
+// NewPostPolicy returns a policy that expires at the expiration.
+func NewPostPolicy(expiration time.Time) *PostPolicy {
+	return &PostPolicy{
+		expiration: expiration,
+	}
+}
+
+// Expiration returns the expiration time of the policy.
+func (p *PostPolicy) Expiration() time.Time {
+	return p.expiration
+}
+
+// WithBucket requires the request is sent to the bucket.
+func (p *PostPolicy) WithBucket(bucket string) *PostPolicy {
+	return p.withField("bucket", bucket)
+}
+
+// WithKey requires the object name is the key.
+func (p *PostPolicy) WithKey(key string) *PostPolicy {
+	return p.WithCondition(PostPolicyEqual, "key", key)
+}
+
+// WithKeyPrefix requires the object name starts with the prefix.
+func (p *PostPolicy) WithKeyPrefix(prefix string) *PostPolicy {
+	return p.WithCondition(PostPolicyStartsWith, "key", prefix)
+}
+
+// WithContentLengthRange requires the size of the object is between min and max bytes.
+func (p *PostPolicy) WithContentLengthRange(min, max int64) *PostPolicy {
+	p.conditions = append(p.conditions, []any{"content-length-range", min, max})
+	return p
+}
+
+// WithContentType requires the Content-Type field is the value.
+func (p *PostPolicy) WithContentType(value string) *PostPolicy {
+	return p.WithCondition(PostPolicyEqual, "content-type", value)
+}
+
+// WithContentTypePrefix requires the Content-Type field starts with the prefix, e.g. image/.
+func (p *PostPolicy) WithContentTypePrefix(prefix string) *PostPolicy {
+	return p.WithCondition(PostPolicyStartsWith, "content-type", prefix)
+}
+
+// WithMetadata requires the x-oss-meta-<key> field is the value.
+func (p *PostPolicy) WithMetadata(key, value string) *PostPolicy {
+	return p.WithCondition(PostPolicyEqual, "x-oss-meta-"+strings.ToLower(key), value)
+}
+
+// WithCondition adds a condition on the form field, the value is a string list for PostPolicyIn and PostPolicyNotIn.
+func (p *PostPolicy) WithCondition(matchType string, field string, value any) *PostPolicy {
+	p.conditions = append(p.conditions, []any{matchType, "$" + strings.ToLower(field), value})
+	return p
+}
+
+func (p *PostPolicy) withField(field, value string) *PostPolicy {
+	p.conditions = append(p.conditions, map[string]string{field: value})
+	return p
+}
+
+func (p *PostPolicy) clone() *PostPolicy {
+	cp := *p
+	cp.conditions = append([]any{}, p.conditions...)
+	return &cp
+}
+
+func (p *PostPolicy) MarshalJSON() ([]byte, error) {
+	conditions := p.conditions
+	if conditions == nil {
+		conditions = []any{}
+	}
+	return json.Marshal(struct {
+		Expiration string `json:"expiration"`
+		Conditions []any  `json:"conditions"`
+	}{
+		Expiration: p.expiration.UTC().Format("2006-01-02T15:04:05.000Z"),
+		Conditions: conditions,
+	})
+}
@@ -0,0 +1,240 @@
+package oss
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/credentials"
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/signer"
+)
+
+type ParsedURL struct {
+	// The scheme of the url, e.g. https, or oss for oss://bucket/key.
+	Scheme string
+
+	// The host of the url, it is the bucket for oss://bucket/key.
+	Host string
+
+	// The addressing style of the url.
+	UrlStyle UrlStyleType
+
+	// The name of the bucket, it is nil for a CNAME if the bucket is not specified.
+	Bucket *string
+
+	// The name of the object.
+	Key *string
+
+	// The region from the V4 credential scope or the endpoint.
+	Region *string
+
+	// The version id of the object.
+	VersionId *string
+
+	// The signature version of the presigned url, nil if the url is not presigned.
+	SignatureVersion *SignatureVersionType
+
+	// The access key id that signs the url.
+	AccessKeyID *string
+
+	// The time that the url is signed, it is only available for the V4 signature.
+	SignTime time.Time
+
+	// The expiration time of the presigned url.
+	Expiration time.Time
+
+	// The headers that are signed besides the default ones, the request must carry them with the same values.
+	SignedHeaders []string
+
+	// The query parameters.
+	Query url.Values
+}
+
+type ParseURLOptions struct {
+	// The bucket that the CNAME domain is bound to, the host is treated as a CNAME if it is set.
+	CNameBucket string
+}
+
+// ParseURL parses the url of OSS into the bucket, the key, the region and the parameters of the presigned url.
+// The virtual-hosted style, the path style, the CNAME and oss://bucket/key urls are supported.
+func ParseURL(rawURL string, optFns ...func(*ParseURLOptions)) (*ParsedURL, error) {
+	options := ParseURLOptions{}
+	for _, fn := range optFns {
+		fn(&options)
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, NewErrParamInvalid("url")
+	}
+
+	parsed := &ParsedURL{
+		Scheme: u.Scheme,
+		Host:   u.Host,
+		Query:  u.Query(),
+	}
+
+	host := strings.ToLower(u.Hostname())
+	path := strings.TrimPrefix(u.Path, "/")
+	labels := strings.Split(host, ".")
+	switch {
+	case u.Scheme == "oss":
+		parsed.Bucket = Ptr(u.Host)
+	case options.CNameBucket != "":
+		parsed.UrlStyle = UrlStyleCName
+		parsed.Bucket = Ptr(options.CNameBucket)
+	case net.ParseIP(host) != nil || host == "localhost" || isOssEndpointLabel(labels[0]):
+		parsed.UrlStyle = UrlStylePath
+		var bucket string
+		bucket, path, _ = strings.Cut(path, "/")
+		if bucket != "" {
+			parsed.Bucket = Ptr(bucket)
+		}
+	case len(labels) > 1 && isOssEndpointLabel(labels[1]):
+		parsed.UrlStyle = UrlStyleVirtualHosted
+		parsed.Bucket = Ptr(labels[0])
+	default:
+		parsed.UrlStyle = UrlStyleCName
+	}
+	if path != "" {
+		parsed.Key = Ptr(path)
+	}
+	for _, label := range labels {
+		if region := regionFromEndpointLabel(label); region != "" {
+			parsed.Region = Ptr(region)
+			break
+		}
+	}
+	if v, ok := parsed.Query["versionId"]; ok {
+		parsed.VersionId = Ptr(v[0])
+	}
+
+	if err = parsePresignQuery(parsed); err != nil {
+		return nil, err
+	}
+	return parsed, nil
+}
+
+// isOssEndpointLabel checks the first label of the endpoint, e.g. oss-cn-hangzhou or oss-accelerate.
+func isOssEndpointLabel(label string) bool {
+	return label == "oss" || strings.HasPrefix(label, "oss-")
+}
+
+func regionFromEndpointLabel(label string) string {
+	if !strings.HasPrefix(label, "oss-") || strings.HasPrefix(label, "oss-accelerate") {
+		return ""
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(label, "oss-"), "-internal")
+}
+
+func parsePresignQuery(parsed *ParsedURL) error {
+	query := parsed.Query
+	switch {
+	case query.Get("x-oss-signature-version") == "OSS4-HMAC-SHA256":
+		parsed.SignatureVersion = Ptr(SignatureVersionV4)
+		signTime, err := time.Parse("20060102T150405Z", query.Get("x-oss-date"))
+		if err != nil {
+			return NewErrParamInvalid("x-oss-date")
+		}
+		expires, err := strconv.ParseInt(query.Get("x-oss-expires"), 10, 64)
+		if err != nil {
+			return NewErrParamInvalid("x-oss-expires")
+		}
+		parsed.SignTime = signTime
+		parsed.Expiration = signTime.Add(time.Duration(expires) * time.Second)
+		// AccessKeyId/Date/Region/Product/aliyun_v4_request
+		scope := strings.Split(query.Get("x-oss-credential"), "/")
+		if len(scope) != 5 {
+			return NewErrParamInvalid("x-oss-credential")
+		}
+		parsed.AccessKeyID = Ptr(scope[0])
+		parsed.Region = Ptr(scope[2])
+		if v := query.Get("x-oss-additional-headers"); v != "" {
+			parsed.SignedHeaders = strings.Split(v, ";")
+		}
+	case query.Has("OSSAccessKeyId") && query.Has("Signature"):
+		parsed.SignatureVersion = Ptr(SignatureVersionV1)
+		expires, err := strconv.ParseInt(query.Get("Expires"), 10, 64)
+		if err != nil {
+			return NewErrParamInvalid("Expires")
+		}
+		parsed.Expiration = time.Unix(expires, 0)
+		parsed.AccessKeyID = Ptr(query.Get("OSSAccessKeyId"))
+	}
+	return nil
+}
+
+type VerifyPresignedURLOptions struct {
+	// The bucket that the CNAME domain is bound to, it is required to verify a CNAME url.
+	CNameBucket string
+
+	// The time to check the expiration, the current time if not set.
+	Now time.Time
+}
+
+// VerifyPresignedURL verifies the signature and the expiration of the presigned url locally with the access key secret,
+// e.g. to reject the expired or tampered links before they are proxied to OSS.
+// The header is the one of the request that carries the url, the signed headers are verified as well.
+// A *PresignedURLError is returned if the url is not valid.
+func VerifyPresignedURL(method string, rawURL string, header http.Header, accessKeySecret string, optFns ...func(*VerifyPresignedURLOptions)) (*ParsedURL, error) {
+	options := VerifyPresignedURLOptions{}
+	for _, fn := range optFns {
+		fn(&options)
+	}
+
+	parsed, err := ParseURL(rawURL, func(o *ParseURLOptions) {
+		o.CNameBucket = options.CNameBucket
+	})
+	if err != nil {
+		return nil, &PresignedURLError{Code: "InvalidArgument", Message: err.Error()}
+	}
+	if parsed.SignatureVersion == nil {
+		return parsed, &PresignedURLError{Code: "InvalidArgument", Message: "the url is not presigned"}
+	}
+	if parsed.UrlStyle == UrlStyleCName && parsed.Bucket == nil {
+		return parsed, &PresignedURLError{Code: "InvalidArgument", Message: "the bucket of the CNAME url is not specified"}
+	}
+
+	request, err := http.NewRequest(method, rawURL, nil)
+	if err != nil {
+		return parsed, &PresignedURLError{Code: "InvalidArgument", Message: err.Error()}
+	}
+	if header != nil {
+		request.Header = header.Clone()
+	}
+	signingCtx := &signer.SigningContext{
+		Bucket:      parsed.Bucket,
+		Key:         parsed.Key,
+		Request:     request,
+		Credentials: &credentials.Credentials{AccessKeySecret: accessKeySecret},
+	}
+
+	switch *parsed.SignatureVersion {
+	case SignatureVersionV1:
+		err = (&signer.SignerV1{}).VerifyQuery(context.Background(), signingCtx)
+	default:
+		err = (&signer.SignerV4{}).VerifyQuery(context.Background(), signingCtx)
+	}
+	if errors.Is(err, signer.ErrSignatureMismatch) {
+		return parsed, &PresignedURLError{Code: "SignatureDoesNotMatch", Message: err.Error(), StringToSign: signingCtx.StringToSign}
+	} else if err != nil {
+		return parsed, &PresignedURLError{Code: "InvalidArgument", Message: err.Error()}
+	}
+
+	now := options.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	if now.After(parsed.Expiration) {
+		return parsed, &PresignedURLError{Code: "Expired", Message: "the url expired at " + parsed.Expiration.UTC().Format(time.RFC3339)}
+	}
+	return parsed, nil
+}
@@ -0,0 +1,175 @@
+package oss
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/credentials"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseURL(t *testing.T) {
+	cases := []struct {
+		url       string
+		cname     string
+		style     UrlStyleType
+		bucket    *string
+		key       *string
+		region    *string
+		versionId *string
+	}{
+		{"https://bucket.oss-cn-hangzhou.aliyuncs.com/dir/key.txt", "", UrlStyleVirtualHosted, Ptr("bucket"), Ptr("dir/key.txt"), Ptr("cn-hangzhou"), nil},
+		{"https://bucket.oss-cn-hangzhou-internal.aliyuncs.com/key?versionId=v1", "", UrlStyleVirtualHosted, Ptr("bucket"), Ptr("key"), Ptr("cn-hangzhou"), Ptr("v1")},
+		{"https://bucket.oss-accelerate.aliyuncs.com/key", "", UrlStyleVirtualHosted, Ptr("bucket"), Ptr("key"), nil, nil},
+		{"https://bucket.oss-cn-hangzhou.aliyuncs.com/", "", UrlStyleVirtualHosted, Ptr("bucket"), nil, Ptr("cn-hangzhou"), nil},
+		{"http://oss-cn-shanghai.aliyuncs.com/bucket/dir/key%2B1.txt", "", UrlStylePath, Ptr("bucket"), Ptr("dir/key+1.txt"), Ptr("cn-shanghai"), nil},
+		{"http://oss-cn-shanghai.aliyuncs.com/", "", UrlStylePath, nil, nil, Ptr("cn-shanghai"), nil},
+		{"http://127.0.0.1:8080/bucket/key", "", UrlStylePath, Ptr("bucket"), Ptr("key"), nil, nil},
+		{"https://www.example.com/key", "", UrlStyleCName, nil, Ptr("key"), nil, nil},
+		{"https://www.example.com/key", "bucket", UrlStyleCName, Ptr("bucket"), Ptr("key"), nil, nil},
+		{"oss://bucket/dir/key", "", UrlStyleVirtualHosted, Ptr("bucket"), Ptr("dir/key"), nil, nil},
+		{"oss://bucket", "", UrlStyleVirtualHosted, Ptr("bucket"), nil, nil, nil},
+	}
+	for _, c := range cases {
+		parsed, err := ParseURL(c.url, func(o *ParseURLOptions) { o.CNameBucket = c.cname })
+		assert.Nil(t, err, c.url)
+		assert.Equal(t, c.style, parsed.UrlStyle, c.url)
+		assert.Equal(t, c.bucket, parsed.Bucket, c.url)
+		assert.Equal(t, c.key, parsed.Key, c.url)
+		assert.Equal(t, c.region, parsed.Region, c.url)
+		assert.Equal(t, c.versionId, parsed.VersionId, c.url)
+		assert.Nil(t, parsed.SignatureVersion, c.url)
+	}
+
+	// V4
+	parsed, err := ParseURL("https://bucket.oss-cn-hangzhou.aliyuncs.com/key?x-oss-additional-headers=content-length%3Bhost" +
+		"&x-oss-credential=ak%2F20231216%2Fcn-shanghai%2Foss%2Faliyun_v4_request&x-oss-date=20231216T162057Z&x-oss-expires=600" +
+		"&x-oss-signature=abc&x-oss-signature-version=OSS4-HMAC-SHA256")
+	assert.Nil(t, err)
+	assert.Equal(t, SignatureVersionV4, *parsed.SignatureVersion)
+	assert.Equal(t, "ak", ToString(parsed.AccessKeyID))
+	assert.Equal(t, "cn-shanghai", ToString(parsed.Region))
+	assert.Equal(t, time.Date(2023, 12, 16, 16, 20, 57, 0, time.UTC), parsed.SignTime)
+	assert.Equal(t, time.Date(2023, 12, 16, 16, 30, 57, 0, time.UTC), parsed.Expiration)
+	assert.Equal(t, []string{"content-length", "host"}, parsed.SignedHeaders)
+
+	// V1
+	parsed, err = ParseURL("https://bucket.oss-cn-hangzhou.aliyuncs.com/key?Expires=1702743657&OSSAccessKeyId=ak&Signature=abc")
+	assert.Nil(t, err)
+	assert.Equal(t, SignatureVersionV1, *parsed.SignatureVersion)
+	assert.Equal(t, "ak", ToString(parsed.AccessKeyID))
+	assert.Equal(t, int64(1702743657), parsed.Expiration.Unix())
+	assert.True(t, parsed.SignTime.IsZero())
+
+	// invalid
+	_, err = ParseURL("bucket/key")
+	assert.Contains(t, err.Error(), "invalid field, url")
+	_, err = ParseURL("https://bucket.oss-cn-hangzhou.aliyuncs.com/key?Expires=abc&OSSAccessKeyId=ak&Signature=abc")
+	assert.Contains(t, err.Error(), "invalid field, Expires")
+	_, err = ParseURL("https://bucket.oss-cn-hangzhou.aliyuncs.com/key?x-oss-signature-version=OSS4-HMAC-SHA256&x-oss-date=abc")
+	assert.Contains(t, err.Error(), "invalid field, x-oss-date")
+}
+
+func TestVerifyPresignedURL(t *testing.T) {
+	cfg := LoadDefaultConfig().
+		WithCredentialsProvider(credentials.NewStaticCredentialsProvider("ak", "sk", "token")).
+		WithRegion("cn-hangzhou").
+		WithEndpoint("oss-cn-hangzhou.aliyuncs.com").
+		WithAdditionalHeaders([]string{"content-length"})
+
+	codeOf := func(err error) string {
+		var perr *PresignedURLError
+		if errors.As(err, &perr) {
+			return perr.Code
+		}
+		return ""
+	}
+
+	for _, version := range []SignatureVersionType{SignatureVersionV1, SignatureVersionV4} {
+		for _, style := range []UrlStyleType{UrlStyleVirtualHosted, UrlStylePath, UrlStyleCName} {
+			c := cfg.Copy()
+			c.WithSignatureVersion(version)
+			var cname string
+			if style == UrlStyleCName {
+				c.WithEndpoint("www.example.com").WithUseCName(true)
+				cname = "bucket"
+			} else if style == UrlStylePath {
+				c.WithUsePathStyle(true)
+			}
+			client := NewClient(&c)
+			withCName := func(o *VerifyPresignedURLOptions) { o.CNameBucket = cname }
+
+			// GET with the version id
+			result, err := client.Presign(context.TODO(), &GetObjectRequest{
+				Bucket:    Ptr("bucket"),
+				Key:       Ptr("dir/key 1+2.txt"),
+				VersionId: Ptr("v1"),
+			}, PresignExpires(time.Hour))
+			assert.Nil(t, err)
+			parsed, err := VerifyPresignedURL("GET", result.URL, nil, "sk", withCName)
+			assert.Nil(t, err, result.URL)
+			assert.Equal(t, "bucket", ToString(parsed.Bucket))
+			assert.Equal(t, "dir/key 1+2.txt", ToString(parsed.Key))
+			assert.Equal(t, "v1", ToString(parsed.VersionId))
+			assert.Equal(t, "ak", ToString(parsed.AccessKeyID))
+			assert.Equal(t, version, *parsed.SignatureVersion)
+			assert.Equal(t, result.Expiration.Unix(), parsed.Expiration.Unix())
+
+			// wrong secret
+			_, err = VerifyPresignedURL("GET", result.URL, nil, "sk1", withCName)
+			assert.Equal(t, "SignatureDoesNotMatch", codeOf(err))
+			assert.NotEmpty(t, err.(*PresignedURLError).StringToSign)
+
+			// tampered
+			_, err = VerifyPresignedURL("PUT", result.URL, nil, "sk", withCName)
+			assert.Equal(t, "SignatureDoesNotMatch", codeOf(err))
+			_, err = VerifyPresignedURL("GET", strings.Replace(result.URL, "versionId=v1", "versionId=v2", 1), nil, "sk", withCName)
+			assert.Equal(t, "SignatureDoesNotMatch", codeOf(err))
+			_, err = VerifyPresignedURL("GET", strings.Replace(result.URL, "key%201", "key%202", 1), nil, "sk", withCName)
+			assert.Equal(t, "SignatureDoesNotMatch", codeOf(err))
+
+			// expired
+			_, err = VerifyPresignedURL("GET", result.URL, nil, "sk", withCName, func(o *VerifyPresignedURLOptions) {
+				o.Now = time.Now().Add(2 * time.Hour)
+			})
+			assert.Equal(t, "Expired", codeOf(err))
+
+			// PUT with the signed headers
+			result, err = client.Presign(context.TODO(), &PutObjectRequest{
+				Bucket:        Ptr("bucket"),
+				Key:           Ptr("key"),
+				ContentType:   Ptr("text/plain"),
+				ContentLength: Ptr(int64(100)),
+				Metadata:      map[string]string{"user": "value"},
+			})
+			assert.Nil(t, err)
+			header := http.Header{}
+			for k, v := range result.SignedHeaders {
+				header.Set(k, v)
+			}
+			_, err = VerifyPresignedURL("PUT", result.URL, header, "sk", withCName)
+			assert.Nil(t, err, result.URL)
+			header.Set("x-oss-meta-user", "value1")
+			_, err = VerifyPresignedURL("PUT", result.URL, header, "sk", withCName)
+			assert.Equal(t, "SignatureDoesNotMatch", codeOf(err))
+			if version == SignatureVersionV4 {
+				header.Set("x-oss-meta-user", "value")
+				header.Set("Content-Length", "101")
+				_, err = VerifyPresignedURL("PUT", result.URL, header, "sk", withCName)
+				assert.Equal(t, "SignatureDoesNotMatch", codeOf(err))
+			}
+		}
+	}
+
+	_, err := VerifyPresignedURL("GET", "https://bucket.oss-cn-hangzhou.aliyuncs.com/key", nil, "sk")
+	assert.Equal(t, "InvalidArgument", codeOf(err))
+	_, err = VerifyPresignedURL("GET", "https://www.example.com/key?Expires=1702743657&OSSAccessKeyId=ak&Signature=abc", nil, "sk")
+	assert.Equal(t, "InvalidArgument", codeOf(err))
+	assert.Contains(t, err.Error(), "the bucket of the CNAME url is not specified")
+	_, err = VerifyPresignedURL("GET", "://", nil, "sk")
+	assert.Equal(t, "InvalidArgument", codeOf(err))
+}
@@ -0,0 +1,272 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultRetryQuota       = 500
+	DefaultRetryCost        = 5
+	DefaultRetryTimeoutCost = 10
+	DefaultNoRetryIncrement = 1
+)
+
+// DefaultNonIdempotentOperations are the operations that are not safe to retry blindly,
+// e.g. an AppendObject retried at a stale position, or a CompleteMultipartUpload that has been processed.
+var DefaultNonIdempotentOperations = []string{
+	"AppendObject",
+	"CompleteMultipartUpload",
+}
+
+// ErrRetryQuotaExceeded is returned by RetryDelay when the retry quota is exhausted.
+var ErrRetryQuotaExceeded = errors.New("retry quota exceeded")
+
+// 503 is not a throttle status code by itself, OSS returns it for the unavailable service too,
+// the throttled 503 responses are identified by their error codes.
+var throttleStatusCodes = map[int]struct{}{
+	429: {},
+}
+
+var throttleServiceErrorCodes = map[string]struct{}{
+	"Throttling":          {},
+	"ThrottlingException": {},
+	"SlowDown":            {},
+	"TooManyRequests":     {},
+	"RequestThrottled":    {},
+	"QpsLimitExceeded":    {},
+}
+
+type AdaptiveOptions struct {
+	RetryOptions
+
+	// The capacity of the retry quota, shared by all requests of the retryer.
+	RetryQuota int
+
+	// The quota cost of a retry.
+	RetryCost int
+
+	// The quota cost of a retry caused by a timeout error.
+	RetryTimeoutCost int
+
+	// The quota given back by a request that succeeds without retry.
+	NoRetryIncrement int
+
+	// The operations that are retried only when the error proves the request was not processed.
+	NonIdempotentOperations []string
+
+	// Disables the client-side send rate limiting after throttling responses.
+	DisableRateLimit bool
+}
+
+// Adaptive is a retryer that keeps a retry quota and adapts the send rate to the throttling responses.
+//
+// Every retry takes RetryCost (RetryTimeoutCost for timeouts) from the quota and a successful request gives it back,
+// so the retries stop when most of the requests fail. After a throttling response,
+// the sends are rate limited by a CUBIC based token bucket.
+type Adaptive struct {
+	standard      *Standard
+	maxBackoff    time.Duration
+	quota         *retryQuota
+	limiter       *clientRateLimiter
+	nonIdempotent map[string]struct{}
+
+	retryCost        int
+	retryTimeoutCost int
+	noRetryIncrement int
+}
+
+var _ AttemptRetryer = (*Adaptive)(nil)
+
+func NewAdaptive(fnOpts ...func(*AdaptiveOptions)) *Adaptive {
+	o := AdaptiveOptions{
+		RetryOptions: RetryOptions{
+			MaxAttempts:     DefaultMaxAttempts,
+			MaxBackoff:      DefaultMaxBackoff,
+			BaseDelay:       DefaultBaseDelay,
+			ErrorRetryables: DefaultErrorRetryables,
+		},
+		RetryQuota:              DefaultRetryQuota,
+		RetryCost:               DefaultRetryCost,
+		RetryTimeoutCost:        DefaultRetryTimeoutCost,
+		NoRetryIncrement:        DefaultNoRetryIncrement,
+		NonIdempotentOperations: DefaultNonIdempotentOperations,
+	}
+
+	for _, fn := range fnOpts {
+		fn(&o)
+	}
+
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = DefaultMaxBackoff
+	}
+
+	a := &Adaptive{
+		standard: NewStandard(func(ro *RetryOptions) {
+			*ro = o.RetryOptions
+		}),
+		maxBackoff:       o.MaxBackoff,
+		quota:            newRetryQuota(o.RetryQuota),
+		nonIdempotent:    map[string]struct{}{},
+		retryCost:        o.RetryCost,
+		retryTimeoutCost: o.RetryTimeoutCost,
+		noRetryIncrement: o.NoRetryIncrement,
+	}
+
+	if !o.DisableRateLimit {
+		a.limiter = newClientRateLimiter(time.Now)
+	}
+
+	for _, op := range o.NonIdempotentOperations {
+		a.nonIdempotent[op] = struct{}{}
+	}
+
+	return a
+}
+
+func (a *Adaptive) MaxAttempts() int {
+	return a.standard.MaxAttempts()
+}
+
+func (a *Adaptive) IsErrorRetryable(err error) bool {
+	return a.standard.IsErrorRetryable(err)
+}
+
+// IsAttemptRetryable is like IsErrorRetryable, except that a non-idempotent operation is retried
+// only on throttling or on errors that prove the request was not processed by the server.
+func (a *Adaptive) IsAttemptRetryable(attempt Attempt, err error) bool {
+	if !a.standard.IsErrorRetryable(err) {
+		return false
+	}
+	if _, ok := a.nonIdempotent[attempt.OpName]; ok {
+		return isThrottleError(err) || isNotProcessedError(err)
+	}
+	return true
+}
+
+// RetryDelay takes the retry cost from the quota and returns the backoff delay.
+// The delay is extended to the server's hint if the error carries one, but never beyond MaxBackoff.
+func (a *Adaptive) RetryDelay(attempt int, err error) (time.Duration, error) {
+	cost := a.retryCost
+	if isTimeoutError(err) {
+		cost = a.retryTimeoutCost
+	}
+	if !a.quota.acquire(cost) {
+		return 0, ErrRetryQuotaExceeded
+	}
+
+	delay, derr := a.standard.RetryDelay(attempt, err)
+	if derr != nil {
+		return 0, derr
+	}
+
+	var hint RetryAfterHint
+	if errors.As(err, &hint) {
+		if d := hint.RetryAfter(); d > delay {
+			delay = d
+		}
+	}
+	if delay > a.maxBackoff {
+		delay = a.maxBackoff
+	}
+	return delay, nil
+}
+
+// BeforeAttempt waits for a send token when the sends are rate limited.
+func (a *Adaptive) BeforeAttempt(ctx context.Context, _ Attempt) error {
+	if a.limiter == nil {
+		return nil
+	}
+	return a.limiter.acquire(ctx)
+}
+
+// AfterAttempt gives the quota back on success and updates the send rate.
+func (a *Adaptive) AfterAttempt(attempt Attempt, err error) {
+	if err == nil {
+		if attempt.Number > 1 {
+			a.quota.release(a.retryCost)
+		} else {
+			a.quota.release(a.noRetryIncrement)
+		}
+	}
+	if a.limiter != nil {
+		a.limiter.update(isThrottleError(err))
+	}
+}
+
+func isThrottleError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var s interface{ HttpStatusCode() int }
+	if errors.As(err, &s) {
+		if _, ok := throttleStatusCodes[s.HttpStatusCode()]; ok {
+			return true
+		}
+	}
+	var c interface{ ErrorCode() string }
+	if errors.As(err, &c) {
+		if _, ok := throttleServiceErrorCodes[c.ErrorCode()]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func isTimeoutError(err error) bool {
+	var nerr net.Error
+	return errors.As(err, &nerr) && nerr.Timeout()
+}
+
+// isNotProcessedError reports whether the error proves that the server did not process the request.
+func isNotProcessedError(err error) bool {
+	var c interface{ ErrorCode() string }
+	if errors.As(err, &c) && c.ErrorCode() == "RequestTimeTooSkewed" {
+		return true
+	}
+	var oerr *net.OpError
+	if errors.As(err, &oerr) && oerr.Op == "dial" {
+		return true
+	}
+	var derr *net.DNSError
+	if errors.As(err, &derr) {
+		return true
+	}
+	return strings.Contains(err.Error(), "connection refused")
+}
+
+type retryQuota struct {
+	mu        sync.Mutex
+	capacity  int
+	available int
+}
+
+func newRetryQuota(capacity int) *retryQuota {
+	return &retryQuota{
+		capacity:  capacity,
+		available: capacity,
+	}
+}
+
+func (q *retryQuota) acquire(cost int) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if cost > q.available {
+		return false
+	}
+	q.available -= cost
+	return true
+}
+
+func (q *retryQuota) release(amount int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.available += amount
+	if q.available > q.capacity {
+		q.available = q.capacity
+	}
+}
@@ -0,0 +1,140 @@
+package retry
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	rateLimiterMinFillRate = 0.5
+	rateLimiterBeta        = 0.7
+	rateLimiterScale       = 0.4
+	rateLimiterSmooth      = 0.8
+	rateLimiterBucketSpan  = 0.5
+)
+
+// clientRateLimiter is a token bucket whose fill rate follows the CUBIC congestion control algorithm.
+// It is enabled by the first throttling response, the rate is reduced on throttling
+// and grows back along a cubic curve on success.
+type clientRateLimiter struct {
+	mu  sync.Mutex
+	now func() time.Time
+
+	enabled         bool
+	fillRate        float64
+	maxCapacity     float64
+	currentCapacity float64
+	lastTimestamp   time.Time
+
+	measuredTxRate   float64
+	lastTxRateBucket float64
+	requestCount     int64
+
+	lastMaxRate      float64
+	lastThrottleTime time.Time
+	timeWindow       float64
+}
+
+func newClientRateLimiter(now func() time.Time) *clientRateLimiter {
+	t := now()
+	return &clientRateLimiter{
+		now:              now,
+		lastTxRateBucket: math.Floor(seconds(t)),
+		lastThrottleTime: t,
+	}
+}
+
+func seconds(t time.Time) float64 {
+	return float64(t.UnixNano()) / float64(time.Second)
+}
+
+func (l *clientRateLimiter) acquire(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		if !l.enabled {
+			l.mu.Unlock()
+			return nil
+		}
+		l.refill()
+		if l.currentCapacity >= 1 {
+			l.currentCapacity--
+			l.mu.Unlock()
+			return nil
+		}
+		wait := floatSecondsDuration((1 - l.currentCapacity) / l.fillRate)
+		l.mu.Unlock()
+
+		t := time.NewTimer(wait)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+func (l *clientRateLimiter) refill() {
+	now := l.now()
+	if l.lastTimestamp.IsZero() {
+		l.lastTimestamp = now
+		return
+	}
+	fill := now.Sub(l.lastTimestamp).Seconds() * l.fillRate
+	l.currentCapacity = math.Min(l.maxCapacity, l.currentCapacity+fill)
+	l.lastTimestamp = now
+}
+
+func (l *clientRateLimiter) update(throttled bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.updateMeasuredRate()
+
+	var rate float64
+	if throttled {
+		rateToUse := l.measuredTxRate
+		if l.enabled {
+			rateToUse = math.Min(rateToUse, l.fillRate)
+		}
+		l.lastMaxRate = rateToUse
+		l.calculateTimeWindow()
+		l.lastThrottleTime = l.now()
+		rate = rateToUse * rateLimiterBeta
+		l.enabled = true
+	} else {
+		l.calculateTimeWindow()
+		rate = l.cubicSuccess(l.now())
+	}
+	l.updateRate(math.Min(rate, 2*l.measuredTxRate))
+}
+
+func (l *clientRateLimiter) calculateTimeWindow() {
+	l.timeWindow = math.Cbrt(l.lastMaxRate * (1 - rateLimiterBeta) / rateLimiterScale)
+}
+
+func (l *clientRateLimiter) cubicSuccess(t time.Time) float64 {
+	dt := t.Sub(l.lastThrottleTime).Seconds()
+	return rateLimiterScale*math.Pow(dt-l.timeWindow, 3) + l.lastMaxRate
+}
+
+func (l *clientRateLimiter) updateRate(rate float64) {
+	l.refill()
+	l.fillRate = math.Max(rate, rateLimiterMinFillRate)
+	l.maxCapacity = math.Max(rate, 1)
+	l.currentCapacity = math.Min(l.currentCapacity, l.maxCapacity)
+}
+
+func (l *clientRateLimiter) updateMeasuredRate() {
+	t := seconds(l.now())
+	bucket := math.Floor(t/rateLimiterBucketSpan) * rateLimiterBucketSpan
+	l.requestCount++
+	if bucket > l.lastTxRateBucket {
+		current := float64(l.requestCount) / (bucket - l.lastTxRateBucket)
+		l.measuredTxRate = current*rateLimiterSmooth + l.measuredTxRate*(1-rateLimiterSmooth)
+		l.requestCount = 0
+		l.lastTxRateBucket = bucket
+	}
+}
@@ -1,6 +1,7 @@
 package retry
 
 import (
+	"context"
 	"errors"
 	"io"
 	"net"
@@ -190,3 +191,192 @@ func TestStandard(t *testing.T) {
 		assert.False(t, r.IsErrorRetryable(errors.New(pattern)))
 	}
 }
+
+type serviceCodeError struct {
+	StatusCode int
+	Code       string
+	Hint       time.Duration
+}
+
+func (e *serviceCodeError) Error() string {
+	return "error " + e.Code
+}
+
+func (e *serviceCodeError) HttpStatusCode() int {
+	return e.StatusCode
+}
+
+func (e *serviceCodeError) ErrorCode() string {
+	return e.Code
+}
+
+func (e *serviceCodeError) RetryAfter() time.Duration {
+	return e.Hint
+}
+
+func TestAdaptive_RetryQuota(t *testing.T) {
+	r := NewAdaptive(func(o *AdaptiveOptions) {
+		o.RetryQuota = 10
+		o.BaseDelay = time.Millisecond
+		o.MaxBackoff = 10 * time.Millisecond
+	})
+	assert.Equal(t, DefaultMaxAttempts, r.MaxAttempts())
+
+	serr := &statusCodeError{StatusCode: 500}
+	_, err := r.RetryDelay(2, serr)
+	assert.Nil(t, err)
+	_, err = r.RetryDelay(3, serr)
+	assert.Nil(t, err)
+	_, err = r.RetryDelay(2, serr)
+	assert.Equal(t, ErrRetryQuotaExceeded, err)
+
+	// a successful retry gives the cost back
+	r.AfterAttempt(Attempt{OpName: "GetObject", Number: 2}, nil)
+	_, err = r.RetryDelay(2, serr)
+	assert.Nil(t, err)
+	_, err = r.RetryDelay(2, serr)
+	assert.Equal(t, ErrRetryQuotaExceeded, err)
+
+	// a timeout costs more
+	for i := 0; i < 10; i++ {
+		r.AfterAttempt(Attempt{OpName: "GetObject", Number: 1}, nil)
+	}
+	terr := &net.OpError{Op: "read", Err: &timeoutError{}}
+	_, err = r.RetryDelay(2, terr)
+	assert.Nil(t, err)
+	_, err = r.RetryDelay(2, serr)
+	assert.Equal(t, ErrRetryQuotaExceeded, err)
+}
+
+type timeoutError struct{}
+
+func (e *timeoutError) Error() string   { return "i/o timeout" }
+func (e *timeoutError) Timeout() bool   { return true }
+func (e *timeoutError) Temporary() bool { return true }
+
+func TestAdaptive_RetryAfter(t *testing.T) {
+	r := NewAdaptive(func(o *AdaptiveOptions) {
+		o.BaseDelay = time.Millisecond
+		o.MaxBackoff = 2 * time.Second
+	})
+	delay, err := r.RetryDelay(2, &serviceCodeError{StatusCode: 503, Hint: time.Second})
+	assert.Nil(t, err)
+	assert.Equal(t, time.Second, delay)
+
+	delay, err = r.RetryDelay(2, &serviceCodeError{StatusCode: 503, Hint: time.Minute})
+	assert.Nil(t, err)
+	assert.Equal(t, 2*time.Second, delay)
+
+	delay, err = r.RetryDelay(2, &serviceCodeError{StatusCode: 503})
+	assert.Nil(t, err)
+	assert.True(t, delay < 4*time.Millisecond)
+}
+
+func TestAdaptive_NonIdempotent(t *testing.T) {
+	r := NewAdaptive()
+	get := Attempt{OpName: "GetObject", Number: 1}
+	appendObj := Attempt{OpName: "AppendObject", Number: 1}
+	complete := Attempt{OpName: "CompleteMultipartUpload", Number: 1}
+
+	serr := &statusCodeError{StatusCode: 500}
+	assert.True(t, r.IsAttemptRetryable(get, serr))
+	assert.False(t, r.IsAttemptRetryable(appendObj, serr))
+	assert.False(t, r.IsAttemptRetryable(complete, serr))
+
+	// the request may be processed
+	rerr := &net.OpError{Op: "read", Err: errors.New("connection reset by peer")}
+	assert.True(t, r.IsAttemptRetryable(get, rerr))
+	assert.False(t, r.IsAttemptRetryable(appendObj, rerr))
+	assert.False(t, r.IsAttemptRetryable(appendObj, io.ErrUnexpectedEOF))
+
+	// the request is not processed
+	derr := &net.OpError{Op: "dial", Err: errors.New("connection refused")}
+	assert.True(t, r.IsAttemptRetryable(appendObj, derr))
+	assert.True(t, r.IsAttemptRetryable(complete, &serviceCodeError{StatusCode: 503, Code: "SlowDown"}))
+	assert.True(t, r.IsAttemptRetryable(complete, &statusCodeError{StatusCode: 429}))
+	assert.False(t, r.IsAttemptRetryable(complete, &serviceCodeError{StatusCode: 503, Code: "ServiceUnavailable"}))
+	assert.False(t, r.IsAttemptRetryable(complete, &statusCodeError{StatusCode: 503}))
+	assert.True(t, r.IsAttemptRetryable(get, &statusCodeError{StatusCode: 503}))
+	assert.True(t, r.IsAttemptRetryable(complete, &serviceCodeError{StatusCode: 403, Code: "RequestTimeTooSkewed"}))
+
+	// not retryable at all
+	assert.False(t, r.IsAttemptRetryable(get, &statusCodeError{StatusCode: 403}))
+
+	r = NewAdaptive(func(o *AdaptiveOptions) {
+		o.NonIdempotentOperations = nil
+	})
+	assert.True(t, r.IsAttemptRetryable(appendObj, serr))
+}
+
+func TestClientRateLimiter(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	clock := func() time.Time { return now }
+	l := newClientRateLimiter(clock)
+
+	// disabled until the first throttling response
+	for i := 0; i < 100; i++ {
+		assert.Nil(t, l.acquire(context.TODO()))
+		l.update(false)
+	}
+	assert.False(t, l.enabled)
+
+	// about 20 requests per second
+	for i := 0; i < 40; i++ {
+		now = now.Add(50 * time.Millisecond)
+		l.update(false)
+	}
+	rate := l.measuredTxRate
+	assert.True(t, rate > 10)
+
+	l.update(true)
+	assert.True(t, l.enabled)
+	assert.InDelta(t, rate*rateLimiterBeta, l.fillRate, 0.001)
+	throttled := l.fillRate
+
+	// grows back on success
+	for i := 0; i < 20; i++ {
+		now = now.Add(50 * time.Millisecond)
+		l.update(false)
+	}
+	assert.True(t, l.fillRate > throttled)
+
+	// never below the min fill rate
+	for i := 0; i < 50; i++ {
+		now = now.Add(time.Second)
+		l.update(true)
+	}
+	assert.Equal(t, rateLimiterMinFillRate, l.fillRate)
+
+	// waits for a token
+	l.currentCapacity = 0
+	ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, l.acquire(ctx))
+}
+
+func TestAdaptive_DisableRateLimit(t *testing.T) {
+	r := NewAdaptive(func(o *AdaptiveOptions) {
+		o.DisableRateLimit = true
+	})
+	throttle := &serviceCodeError{StatusCode: 503, Code: "SlowDown"}
+	for i := 0; i < 10; i++ {
+		r.AfterAttempt(Attempt{OpName: "GetObject", Number: 1}, throttle)
+		assert.Nil(t, r.BeforeAttempt(context.TODO(), Attempt{OpName: "GetObject", Number: 1}))
+	}
+}
+
+func TestAdaptive_ThrottleError(t *testing.T) {
+	assert.True(t, isThrottleError(&statusCodeError{StatusCode: 429}))
+	assert.True(t, isThrottleError(&serviceCodeError{StatusCode: 503, Code: "SlowDown"}))
+	assert.False(t, isThrottleError(&statusCodeError{StatusCode: 503}))
+	assert.False(t, isThrottleError(&serviceCodeError{StatusCode: 503, Code: "ServiceUnavailable"}))
+
+	// the unavailable service does not slow down the sends
+	r := NewAdaptive()
+	for i := 0; i < 10; i++ {
+		r.AfterAttempt(Attempt{OpName: "GetObject", Number: 1}, &statusCodeError{StatusCode: 503})
+	}
+	assert.False(t, r.limiter.enabled)
+	r.AfterAttempt(Attempt{OpName: "GetObject", Number: 1}, &serviceCodeError{StatusCode: 503, Code: "SlowDown"})
+	assert.True(t, r.limiter.enabled)
+}
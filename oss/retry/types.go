@@ -1,6 +1,9 @@
 package retry
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 type RetryOptions struct {
 	MaxAttempts     int
@@ -17,3 +20,31 @@ type BackoffDelayer interface {
 type ErrorRetryable interface {
 	IsErrorRetryable(error) bool
 }
+
+// Attempt describes an attempt of an operation.
+type Attempt struct {
+	// The name of the operation, e.g. PutObject.
+	OpName string
+
+	// The attempt number, starting at 1.
+	Number int
+}
+
+// AttemptRetryer is a Retryer that is notified of every attempt.
+// The client calls BeforeAttempt before sending an attempt, AfterAttempt with its result,
+// and IsAttemptRetryable instead of IsErrorRetryable.
+type AttemptRetryer interface {
+	Retryer
+
+	// BeforeAttempt may block to rate-limit the sends.
+	BeforeAttempt(ctx context.Context, attempt Attempt) error
+
+	AfterAttempt(attempt Attempt, err error)
+
+	IsAttemptRetryable(attempt Attempt, err error) bool
+}
+
+// RetryAfterHint is implemented by the errors that carry the server's retry hint.
+type RetryAfterHint interface {
+	RetryAfter() time.Duration
+}
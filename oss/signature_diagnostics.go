@@ -0,0 +1,319 @@
+package oss
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/signer"
+)
+
+// The kinds of the signature differences.
+const (
+	SignatureDiffMethod            = "Method"
+	SignatureDiffResource          = "Resource"
+	SignatureDiffQuery             = "Query"
+	SignatureDiffHeader            = "Header"
+	SignatureDiffAdditionalHeaders = "AdditionalHeaders"
+	SignatureDiffPayload           = "Payload"
+	SignatureDiffDate              = "Date"
+	SignatureDiffScope             = "Scope"
+	SignatureDiffClockSkew         = "ClockSkew"
+	SignatureDiffCredentials       = "Credentials"
+)
+
+// the server rejects the request if the time differs by more than 15 minutes
+const maxSignatureClockSkew = 15 * time.Minute
+
+// SignatureDifference is a difference between the request signed by the client and the one received by the server.
+type SignatureDifference struct {
+	Kind string
+
+	// The name of the header, it is empty for the other kinds.
+	Name string
+
+	Client string
+	Server string
+
+	// The likely cause of the difference.
+	Hint string
+}
+
+func (d SignatureDifference) String() string {
+	name := d.Kind
+	if d.Name != "" {
+		name += " " + d.Name
+	}
+	s := fmt.Sprintf("%s: client %q, server %q", name, d.Client, d.Server)
+	if d.Hint != "" {
+		s += ", " + d.Hint
+	}
+	return s
+}
+
+// SignatureDiagnostics compares the signature calculated by the client with the one calculated by the server,
+// it is attached to the SignatureDoesNotMatch error.
+type SignatureDiagnostics struct {
+	ClientStringToSign     string
+	ServerStringToSign     string
+	ClientCanonicalRequest string
+	ServerCanonicalRequest string
+
+	// The server time minus the signing time of the client.
+	ClockSkew time.Duration
+
+	Differences []SignatureDifference
+}
+
+func (d *SignatureDiagnostics) String() string {
+	if len(d.Differences) == 0 {
+		if d.ServerStringToSign == "" {
+			return "the server does not return the string to sign"
+		}
+		return "no difference is found in the string to sign"
+	}
+	items := make([]string, len(d.Differences))
+	for i, diff := range d.Differences {
+		items[i] = diff.String()
+	}
+	return strings.Join(items, "; ")
+}
+
+type signatureErrorDetail struct {
+	StringToSign     string `xml:"StringToSign"`
+	CanonicalRequest string `xml:"CanonicalRequest"`
+}
+
+// newSignatureDiagnostics builds the diagnostics from the signing context and the body of the SignatureDoesNotMatch error.
+func newSignatureDiagnostics(signingCtx *signer.SigningContext, se *ServiceError) *SignatureDiagnostics {
+	detail := signatureErrorDetail{}
+	xml.Unmarshal(se.Snapshot, &detail)
+
+	d := &SignatureDiagnostics{
+		ClientStringToSign:     signingCtx.StringToSign,
+		ServerStringToSign:     detail.StringToSign,
+		ClientCanonicalRequest: signingCtx.CanonicalRequest,
+		ServerCanonicalRequest: detail.CanonicalRequest,
+	}
+	if !se.Timestamp.IsZero() && !signingCtx.Time.IsZero() && !signingCtx.AuthMethodQuery {
+		d.ClockSkew = se.Timestamp.Sub(signingCtx.Time)
+	}
+
+	if d.ClientCanonicalRequest != "" && d.ServerCanonicalRequest != "" {
+		d.Differences = append(d.Differences, diffStringToSignV4(d.ClientStringToSign, d.ServerStringToSign)...)
+		d.Differences = append(d.Differences, diffCanonicalRequest(d.ClientCanonicalRequest, d.ServerCanonicalRequest)...)
+	} else if d.ClientStringToSign != "" && d.ServerStringToSign != "" {
+		if strings.HasPrefix(d.ClientStringToSign, "OSS4-HMAC-SHA256\n") {
+			d.Differences = append(d.Differences, diffStringToSignV4(d.ClientStringToSign, d.ServerStringToSign)...)
+		} else {
+			d.Differences = append(d.Differences, diffStringToSignV1(d.ClientStringToSign, d.ServerStringToSign)...)
+		}
+	}
+
+	if d.ClockSkew > maxSignatureClockSkew || d.ClockSkew < -maxSignatureClockSkew {
+		d.Differences = append(d.Differences, SignatureDifference{
+			Kind:   SignatureDiffClockSkew,
+			Client: signingCtx.Time.UTC().Format(time.RFC3339),
+			Server: se.Timestamp.UTC().Format(time.RFC3339),
+			Hint:   fmt.Sprintf("the clock of the client is off by %v", d.ClockSkew),
+		})
+	}
+
+	if len(d.Differences) == 0 && d.ClientStringToSign != "" && d.ClientStringToSign == d.ServerStringToSign {
+		d.Differences = append(d.Differences, SignatureDifference{
+			Kind: SignatureDiffCredentials,
+			Hint: "the string to sign is the same, the access key secret is wrong",
+		})
+	}
+	return d
+}
+
+// diffStringToSignV4 compares the date and the scope, the hash of the canonical request is compared by diffCanonicalRequest.
+//
+//	"OSS4-HMAC-SHA256" + "\n" + TimeStamp + "\n" + Scope + "\n" + Hex(SHA256Hash(Canonical Request))
+func diffStringToSignV4(client, server string) []SignatureDifference {
+	var diffs []SignatureDifference
+	c := strings.Split(client, "\n")
+	s := strings.Split(server, "\n")
+	if len(c) != 4 || len(s) != 4 {
+		return diffs
+	}
+	if c[1] != s[1] {
+		diffs = append(diffs, SignatureDifference{Kind: SignatureDiffDate, Client: c[1], Server: s[1]})
+	}
+	if c[2] != s[2] {
+		diff := SignatureDifference{Kind: SignatureDiffScope, Client: c[2], Server: s[2]}
+		// Date/Region/Product/aliyun_v4_request
+		cs := strings.Split(c[2], "/")
+		ss := strings.Split(s[2], "/")
+		if len(cs) == 4 && len(ss) == 4 && cs[1] != ss[1] {
+			diff.Hint = fmt.Sprintf("the region of the client is %s, but the server expects %s", cs[1], ss[1])
+		}
+		diffs = append(diffs, diff)
+	}
+	return diffs
+}
+
+// diffStringToSignV1 compares the lines of the V1 string to sign.
+//
+//	VERB + "\n" + Content-MD5 + "\n" + Content-Type + "\n" + Date + "\n" + CanonicalizedOSSHeaders + CanonicalizedResource
+func diffStringToSignV1(client, server string) []SignatureDifference {
+	var diffs []SignatureDifference
+	c := strings.Split(client, "\n")
+	s := strings.Split(server, "\n")
+	if len(c) < 5 || len(s) < 5 {
+		return diffs
+	}
+	if c[0] != s[0] {
+		diffs = append(diffs, SignatureDifference{Kind: SignatureDiffMethod, Client: c[0], Server: s[0]})
+	}
+	for i, name := range []string{"content-md5", "content-type"} {
+		if c[i+1] != s[i+1] {
+			diffs = append(diffs, diffHeaderValue(name, c[i+1], s[i+1]))
+		}
+	}
+	if c[3] != s[3] {
+		diffs = append(diffs, SignatureDifference{Kind: SignatureDiffDate, Client: c[3], Server: s[3]})
+	}
+	diffs = append(diffs, diffHeaderLines(c[4:len(c)-1], s[4:len(s)-1], nil, nil)...)
+	if c[len(c)-1] != s[len(s)-1] {
+		diffs = append(diffs, diffResource(c[len(c)-1], s[len(s)-1]))
+	}
+	return diffs
+}
+
+// diffCanonicalRequest compares the parts of the V4 canonical request.
+//
+//	HTTP Verb + "\n" + Canonical URI + "\n" + Canonical Query String + "\n" +
+//	Canonical Headers + "\n" + Additional Headers + "\n" + Hashed PayLoad
+func diffCanonicalRequest(client, server string) []SignatureDifference {
+	var diffs []SignatureDifference
+	c := strings.Split(client, "\n")
+	s := strings.Split(server, "\n")
+	if len(c) < 6 || len(s) < 6 {
+		return diffs
+	}
+	if c[0] != s[0] {
+		diffs = append(diffs, SignatureDifference{Kind: SignatureDiffMethod, Client: c[0], Server: s[0]})
+	}
+	if c[1] != s[1] {
+		diffs = append(diffs, diffResource(c[1], s[1]))
+	}
+	if c[2] != s[2] {
+		diffs = append(diffs, SignatureDifference{Kind: SignatureDiffQuery, Client: c[2], Server: s[2]})
+	}
+
+	cAdditional := c[len(c)-2]
+	sAdditional := s[len(s)-2]
+	cHeaders := c[3 : len(c)-3]
+	sHeaders := s[3 : len(s)-3]
+	diffs = append(diffs, diffHeaderLines(cHeaders, sHeaders, splitAdditionalHeaders(cAdditional), splitAdditionalHeaders(sAdditional))...)
+	if cAdditional != sAdditional {
+		diff := SignatureDifference{Kind: SignatureDiffAdditionalHeaders, Client: cAdditional, Server: sAdditional}
+		if strings.EqualFold(cAdditional, sAdditional) {
+			diff.Hint = "the header names differ in casing, they must be lowercase"
+		}
+		diffs = append(diffs, diff)
+	}
+
+	if c[len(c)-1] != s[len(s)-1] {
+		diffs = append(diffs, SignatureDifference{Kind: SignatureDiffPayload, Client: c[len(c)-1], Server: s[len(s)-1]})
+	}
+	return diffs
+}
+
+func splitAdditionalHeaders(v string) map[string]bool {
+	names := map[string]bool{}
+	for _, name := range strings.Split(v, ";") {
+		if name != "" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+func parseHeaderLines(lines []string) map[string]string {
+	headers := map[string]string{}
+	for _, line := range lines {
+		if k, v, ok := strings.Cut(line, ":"); ok {
+			headers[k] = v
+		}
+	}
+	return headers
+}
+
+// diffHeaderLines compares the signed headers, each line is formatted as "name:value".
+func diffHeaderLines(client, server []string, cAdditional, sAdditional map[string]bool) []SignatureDifference {
+	var diffs []SignatureDifference
+	c := parseHeaderLines(client)
+	s := parseHeaderLines(server)
+	names := map[string]bool{}
+	for k := range c {
+		names[k] = true
+	}
+	for k := range s {
+		names[k] = true
+	}
+	keys := make([]string, 0, len(names))
+	for k := range names {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		cv, cok := c[k]
+		sv, sok := s[k]
+		switch {
+		case cok && !sok:
+			diff := SignatureDifference{Kind: SignatureDiffHeader, Name: k, Client: cv}
+			if cAdditional[k] && !sAdditional[k] {
+				diff.Hint = "the additional header is signed by the client, but it is not received by the server"
+			} else {
+				diff.Hint = "the header is signed by the client, but it is not received by the server, e.g. it is removed by a proxy"
+			}
+			diffs = append(diffs, diff)
+		case !cok && sok:
+			diffs = append(diffs, SignatureDifference{Kind: SignatureDiffHeader, Name: k, Server: sv,
+				Hint: "the header is received by the server, but it is not signed by the client, e.g. it is added after signing"})
+		case cv != sv:
+			diffs = append(diffs, diffHeaderValue(k, cv, sv))
+		}
+	}
+	return diffs
+}
+
+func diffHeaderValue(name, client, server string) SignatureDifference {
+	diff := SignatureDifference{Kind: SignatureDiffHeader, Name: name, Client: client, Server: server}
+	switch {
+	case strings.EqualFold(client, server):
+		diff.Hint = "the header value differs in casing"
+	case strings.TrimSpace(client) == strings.TrimSpace(server):
+		diff.Hint = "the header value differs in the leading or trailing spaces"
+	case client == "":
+		diff.Hint = "the header is not signed by the client, e.g. it is added after signing"
+	case server == "":
+		diff.Hint = "the header is not received by the server, e.g. it is removed by a proxy"
+	}
+	return diff
+}
+
+func diffResource(client, server string) SignatureDifference {
+	diff := SignatureDifference{Kind: SignatureDiffResource, Client: client, Server: server}
+	cPath, _, _ := strings.Cut(client, "?")
+	sPath, _, _ := strings.Cut(server, "?")
+	cu, cerr := url.PathUnescape(cPath)
+	su, serr := url.PathUnescape(sPath)
+	switch {
+	case cPath == sPath:
+		diff.Hint = "the sub-resources differ"
+	case cerr == nil && serr == nil && cu == su:
+		diff.Hint = "the key is encoded differently, e.g. by a proxy"
+	case strings.Count(cPath, "/") > 1 && strings.HasSuffix(cPath, sPath[strings.LastIndex(sPath, "/")+1:]) &&
+		strings.SplitN(strings.TrimPrefix(cPath, "/"), "/", 2)[0] != strings.SplitN(strings.TrimPrefix(sPath, "/"), "/", 2)[0]:
+		diff.Hint = "the bucket differs, e.g. the CNAME is bound to another bucket"
+	}
+	return diff
+}
@@ -0,0 +1,180 @@
+package oss
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/credentials"
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/signer"
+	"github.com/stretchr/testify/assert"
+)
+
+func findSignatureDifference(d *SignatureDiagnostics, kind, name string) *SignatureDifference {
+	for i := range d.Differences {
+		if d.Differences[i].Kind == kind && d.Differences[i].Name == name {
+			return &d.Differences[i]
+		}
+	}
+	return nil
+}
+
+// newSignatureMismatchServer returns a server that signs the request it receives after dropping the header,
+// as a proxy between the client and the server does.
+func newSignatureMismatchServer(t *testing.T, dropHeader string, skew time.Duration) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		r.Header.Del(dropHeader)
+
+		var s signer.Signer
+		signTime := time.Now()
+		if v := r.Header.Get("x-oss-date"); v != "" {
+			signTime, _ = time.Parse("20060102T150405Z", v)
+			s = &signer.SignerV4{}
+		} else {
+			signTime, _ = http.ParseTime(r.Header.Get("Date"))
+			s = &signer.SignerV1{}
+		}
+		signingCtx := &signer.SigningContext{
+			Product:     Ptr("oss"),
+			Region:      Ptr("cn-hangzhou"),
+			Bucket:      Ptr("bucket"),
+			Key:         Ptr("key"),
+			Request:     r,
+			Credentials: &credentials.Credentials{AccessKeyID: "ak", AccessKeySecret: "sk"},
+			Time:        signTime,
+		}
+		assert.Nil(t, s.Sign(context.TODO(), signingCtx))
+
+		w.Header().Set("x-oss-request-id", "id-1234")
+		w.Header().Set("Content-Type", "application/xml")
+		w.Header().Set("Date", time.Now().Add(skew).UTC().Format(http.TimeFormat))
+		w.WriteHeader(403)
+		fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<Error>
+  <Code>SignatureDoesNotMatch</Code>
+  <Message>The request signature we calculated does not match the signature you provided.</Message>
+  <RequestId>id-1234</RequestId>
+  <StringToSign>%s</StringToSign>
+  <CanonicalRequest>%s</CanonicalRequest>
+</Error>`, signingCtx.StringToSign, signingCtx.CanonicalRequest)
+	}))
+}
+
+func TestSignatureDiagnostics(t *testing.T) {
+	server := newSignatureMismatchServer(t, "x-oss-meta-tag", 0)
+	defer server.Close()
+
+	cfg := LoadDefaultConfig().
+		WithCredentialsProvider(credentials.NewStaticCredentialsProvider("ak", "sk")).
+		WithRegion("cn-hangzhou").
+		WithEndpoint(server.URL)
+	client := NewClient(cfg)
+
+	request := &PutObjectRequest{
+		Bucket:   Ptr("bucket"),
+		Key:      Ptr("key"),
+		Metadata: map[string]string{"tag": "value"},
+	}
+
+	// v4
+	_, err := client.PutObject(context.TODO(), request)
+	var serr *ServiceError
+	assert.True(t, errors.As(err, &serr))
+	assert.Equal(t, "SignatureDoesNotMatch", serr.Code)
+	d := serr.SignatureDiagnostics
+	assert.NotNil(t, d)
+	assert.Contains(t, d.ClientCanonicalRequest, "x-oss-meta-tag:value\n")
+	assert.NotContains(t, d.ServerCanonicalRequest, "x-oss-meta-tag")
+	assert.Contains(t, d.ServerStringToSign, "OSS4-HMAC-SHA256\n")
+	assert.Len(t, d.Differences, 1)
+	diff := findSignatureDifference(d, SignatureDiffHeader, "x-oss-meta-tag")
+	assert.NotNil(t, diff)
+	assert.Equal(t, "value", diff.Client)
+	assert.Equal(t, "", diff.Server)
+	assert.Contains(t, diff.Hint, "not received by the server")
+	assert.Less(t, d.ClockSkew, time.Minute)
+	assert.Contains(t, err.Error(), "Signature Diagnostics: Header x-oss-meta-tag")
+
+	// v1
+	_, err = client.PutObject(context.TODO(), request, OpSigner(&signer.SignerV1{}))
+	assert.True(t, errors.As(err, &serr))
+	d = serr.SignatureDiagnostics
+	assert.NotNil(t, d)
+	assert.Equal(t, "", d.ClientCanonicalRequest)
+	assert.Len(t, d.Differences, 1)
+	assert.NotNil(t, findSignatureDifference(d, SignatureDiffHeader, "x-oss-meta-tag"))
+
+	// the other errors are not diagnosed
+	server2 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		w.WriteHeader(404)
+		io.WriteString(w, `<Error><Code>NoSuchKey</Code><RequestId>id-1234</RequestId></Error>`)
+	}))
+	defer server2.Close()
+	cfg2 := cfg.Copy()
+	cfg2.WithEndpoint(server2.URL)
+	client = NewClient(&cfg2)
+	_, err = client.PutObject(context.TODO(), request)
+	assert.True(t, errors.As(err, &serr))
+	assert.Equal(t, "NoSuchKey", serr.Code)
+	assert.Nil(t, serr.SignatureDiagnostics)
+	assert.NotContains(t, err.Error(), "Signature Diagnostics")
+}
+
+func TestSignatureDiagnostics_ClockSkew(t *testing.T) {
+	server := newSignatureMismatchServer(t, "", time.Hour)
+	defer server.Close()
+
+	cfg := LoadDefaultConfig().
+		WithCredentialsProvider(credentials.NewStaticCredentialsProvider("ak", "sk")).
+		WithRegion("cn-hangzhou").
+		WithEndpoint(server.URL)
+	client := NewClient(cfg)
+
+	_, err := client.PutObject(context.TODO(), &PutObjectRequest{Bucket: Ptr("bucket"), Key: Ptr("key")})
+	var serr *ServiceError
+	assert.True(t, errors.As(err, &serr))
+	d := serr.SignatureDiagnostics
+	assert.NotNil(t, d)
+	assert.Greater(t, d.ClockSkew, 50*time.Minute)
+	assert.Len(t, d.Differences, 1)
+	assert.NotNil(t, findSignatureDifference(d, SignatureDiffClockSkew, ""))
+}
+
+func TestSignatureDiagnostics_Diff(t *testing.T) {
+	client := "PUT\n/bucket/dir%2Fkey\n\ncontent-type:text/plain\nx-oss-content-sha256:UNSIGNED-PAYLOAD\nx-oss-date:20241201T120000Z\n\nhost\nUNSIGNED-PAYLOAD"
+	server := "PUT\n/bucket/dir/key\n\ncontent-type:Text/Plain\nx-oss-content-sha256:UNSIGNED-PAYLOAD\nx-oss-date:20241201T120000Z\nx-oss-meta-a:b\n\n\nUNSIGNED-PAYLOAD"
+	diffs := diffCanonicalRequest(client, server)
+	d := &SignatureDiagnostics{Differences: diffs}
+
+	diff := findSignatureDifference(d, SignatureDiffResource, "")
+	assert.NotNil(t, diff)
+	assert.Contains(t, diff.Hint, "encoded differently")
+
+	diff = findSignatureDifference(d, SignatureDiffHeader, "content-type")
+	assert.NotNil(t, diff)
+	assert.Contains(t, diff.Hint, "casing")
+
+	diff = findSignatureDifference(d, SignatureDiffHeader, "x-oss-meta-a")
+	assert.NotNil(t, diff)
+	assert.Contains(t, diff.Hint, "not signed by the client")
+
+	diff = findSignatureDifference(d, SignatureDiffAdditionalHeaders, "")
+	assert.NotNil(t, diff)
+	assert.Equal(t, "host", diff.Client)
+	assert.Equal(t, "", diff.Server)
+	assert.Len(t, diffs, 4)
+
+	diffs = diffStringToSignV4(
+		"OSS4-HMAC-SHA256\n20241201T120000Z\n20241201/cn-hangzhou/oss/aliyun_v4_request\nhash",
+		"OSS4-HMAC-SHA256\n20241201T120000Z\n20241201/cn-shanghai/oss/aliyun_v4_request\nhash")
+	assert.Len(t, diffs, 1)
+	assert.Equal(t, SignatureDiffScope, diffs[0].Kind)
+	assert.Contains(t, diffs[0].Hint, "cn-shanghai")
+}
@@ -0,0 +1,32 @@
+package signer
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"hash"
+	"io"
+	"time"
+
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/credentials"
+)
+
+// SignPostPolicy returns the signature of the base64 encoded policy of the PostObject form.
+func (*SignerV1) SignPostPolicy(cred *credentials.Credentials, policy string) string {
+	// Signature = base64(hmac-sha1(AccessKeySecret, Policy))
+	h := hmac.New(func() hash.Hash { return sha1.New() }, []byte(cred.AccessKeySecret))
+	io.WriteString(h, policy)
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// PostPolicyCredential returns the value of x-oss-credential in the V4 policy of the PostObject form.
+func (*SignerV4) PostPolicyCredential(cred *credentials.Credentials, region, product string, signTime time.Time) string {
+	return cred.AccessKeyID + "/" + buildScope(signTime.UTC().Format(iso8601DateFormat), region, product)
+}
+
+// SignPostPolicy returns the signature of the base64 encoded policy of the PostObject form,
+// signTime must be the same as x-oss-date in the policy.
+func (s *SignerV4) SignPostPolicy(cred *credentials.Credentials, region, product string, signTime time.Time, policy string) string {
+	// Signature = hex(hmac-sha256(SigningKey, Policy))
+	return s.calcSignature(cred.AccessKeySecret, signTime.UTC().Format(iso8601DateFormat), region, product, policy)
+}
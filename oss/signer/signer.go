@@ -28,13 +28,18 @@ type SigningContext struct {
 
 	AuthMethodQuery bool
 
+	// Sign the payload chunk by chunk, it only takes effect for the header authentication of SignerV4.
+	StreamingPayload bool
+
 	// input and output
 	Time        time.Time
 	ClockOffset time.Duration
 
 	// output
-	SignedHeaders map[string]string
-	StringToSign  string
+	SignedHeaders    map[string]string
+	StringToSign     string
+	CanonicalRequest string
+	ChunkSigner      *ChunkSigner
 
 	// for test
 	signTime *time.Time
@@ -2,8 +2,12 @@ package signer
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"net/http"
 	"net/url"
+	"strings"
 	"testing"
 	"time"
 
@@ -345,6 +349,96 @@ func TestV4AuthHeader(t *testing.T) {
 	assert.Equal(t, authPat, request.Header.Get("Authorization"))
 }
 
+func TestV4AuthHeaderStreamingPayload(t *testing.T) {
+	provider := credentials.NewStaticCredentialsProvider("ak", "sk")
+	cred, _ := provider.GetCredentials(context.TODO())
+
+	request, _ := http.NewRequest("PUT", "http://bucket.oss-cn-hangzhou.aliyuncs.com", nil)
+	request.Header = http.Header{}
+	request.Header.Add("x-oss-trailer", "x-oss-hash-crc64ecma")
+	signTime := time.Unix(1702743657, 0).UTC()
+	signCtx := &SigningContext{
+		Bucket:           ptr("bucket"),
+		Key:              ptr("key"),
+		Request:          request,
+		Credentials:      &cred,
+		Product:          ptr("oss"),
+		Region:           ptr("cn-hangzhou"),
+		Time:             signTime,
+		StreamingPayload: true,
+	}
+
+	signer := &SignerV4{}
+	err := signer.Sign(context.TODO(), signCtx)
+	assert.Nil(t, err)
+	assert.Equal(t, StreamingPayloadTrailer, request.Header.Get("x-oss-content-sha256"))
+	assert.Contains(t, signCtx.CanonicalRequest, "x-oss-content-sha256:STREAMING-OSS4-HMAC-SHA256-PAYLOAD-TRAILER\n")
+	assert.True(t, strings.HasSuffix(signCtx.CanonicalRequest, "\n"+StreamingPayloadTrailer))
+	assert.NotNil(t, signCtx.ChunkSigner)
+
+	auth := request.Header.Get("Authorization")
+	seed := auth[len(auth)-64:]
+
+	// the string to sign of the first chunk
+	hmacSha256 := func(key []byte, data string) []byte {
+		h := hmac.New(sha256.New, key)
+		h.Write([]byte(data))
+		return h.Sum(nil)
+	}
+	key := hmacSha256([]byte("aliyun_v4sk"), "20231216")
+	key = hmacSha256(key, "cn-hangzhou")
+	key = hmacSha256(key, "oss")
+	key = hmacSha256(key, "aliyun_v4_request")
+	dataHash := sha256.Sum256([]byte("hello"))
+	emptyHash := sha256.Sum256(nil)
+	expected := hex.EncodeToString(hmacSha256(key, "OSS4-HMAC-SHA256-PAYLOAD\n20231216T162057Z\n20231216/cn-hangzhou/oss/aliyun_v4_request\n"+
+		seed+"\n"+hex.EncodeToString(emptyHash[:])+"\n"+hex.EncodeToString(dataHash[:])))
+
+	// the signatures are chained from the seed
+	verifier := NewChunkSigner("sk", "cn-hangzhou", "oss", signTime, seed)
+	sig1 := signCtx.ChunkSigner.SignChunk([]byte("hello"))
+	assert.Equal(t, expected, sig1)
+	assert.Equal(t, sig1, verifier.SignChunk([]byte("hello")))
+	sig2 := signCtx.ChunkSigner.SignChunk([]byte("hello"))
+	assert.NotEqual(t, sig1, sig2)
+	assert.Equal(t, sig2, verifier.SignChunk([]byte("hello")))
+	sig3 := signCtx.ChunkSigner.SignChunk(nil)
+	assert.Equal(t, sig3, verifier.SignChunk(nil))
+
+	trailerHash := sha256.Sum256([]byte("x-oss-hash-crc64ecma:1\n"))
+	expected = hex.EncodeToString(hmacSha256(key, "OSS4-HMAC-SHA256-TRAILER\n20231216T162057Z\n20231216/cn-hangzhou/oss/aliyun_v4_request\n"+
+		sig3+"\n"+hex.EncodeToString(trailerHash[:])))
+	assert.Equal(t, expected, signCtx.ChunkSigner.SignTrailer([]byte("x-oss-hash-crc64ecma:1\n")))
+	assert.Equal(t, expected, verifier.SignTrailer([]byte("x-oss-hash-crc64ecma:1\n")))
+
+	// a different seed
+	verifier = NewChunkSigner("sk", "cn-hangzhou", "oss", signTime, sig1)
+	assert.NotEqual(t, sig1, verifier.SignChunk([]byte("hello")))
+
+	// signed again without the streaming payload
+	signCtx.StreamingPayload = false
+	err = signer.Sign(context.TODO(), signCtx)
+	assert.Nil(t, err)
+	assert.Equal(t, "UNSIGNED-PAYLOAD", request.Header.Get("x-oss-content-sha256"))
+	assert.Nil(t, signCtx.ChunkSigner)
+
+	// the query authentication does not support the streaming payload
+	request, _ = http.NewRequest("PUT", "http://bucket.oss-cn-hangzhou.aliyuncs.com", nil)
+	signCtx = &SigningContext{
+		Bucket:           ptr("bucket"),
+		Key:              ptr("key"),
+		Request:          request,
+		Credentials:      &cred,
+		Product:          ptr("oss"),
+		Region:           ptr("cn-hangzhou"),
+		AuthMethodQuery:  true,
+		StreamingPayload: true,
+	}
+	err = signer.Sign(context.TODO(), signCtx)
+	assert.Nil(t, err)
+	assert.Nil(t, signCtx.ChunkSigner)
+}
+
 func TestV4AuthHeaderWithCloudBox(t *testing.T) {
 	var provider credentials.CredentialsProvider
 	var cred credentials.Credentials
@@ -909,3 +1003,53 @@ func TestSignerVectorV4BuildBucketArn(t *testing.T) {
 	arn = buildArnUri(signCtx, signer.AccountId)
 	assert.Equal(t, "/acs:ossvector:cn-hangzhou:"+accountId+":bucket/key-1/key-2", arn)
 }
+
+func TestVerifyQuery(t *testing.T) {
+	cred := credentials.Credentials{AccessKeyID: "ak", AccessKeySecret: "sk", SecurityToken: "token"}
+	for _, signer := range []interface {
+		Signer
+		VerifyQuery(context.Context, *SigningContext) error
+	}{&SignerV1{}, &SignerV4{}} {
+		request, _ := http.NewRequest("GET", "http://bucket.oss-cn-hangzhou.aliyuncs.com/key?versionId=v1", nil)
+		request.Header.Set("x-oss-meta-user", "value")
+		expiration := time.Now().Add(time.Hour).Truncate(time.Second)
+		signCtx := &SigningContext{
+			Bucket:          ptr("bucket"),
+			Key:             ptr("key"),
+			Request:         request,
+			Credentials:     &cred,
+			Product:         ptr("oss"),
+			Region:          ptr("cn-hangzhou"),
+			AuthMethodQuery: true,
+			Time:            expiration,
+		}
+		assert.Nil(t, signer.Sign(context.TODO(), signCtx))
+
+		verify := func(method, rawURL, secret string) (*SigningContext, error) {
+			request, _ := http.NewRequest(method, rawURL, nil)
+			request.Header.Set("x-oss-meta-user", "value")
+			verifyCtx := &SigningContext{
+				Bucket:      ptr("bucket"),
+				Key:         ptr("key"),
+				Request:     request,
+				Credentials: &credentials.Credentials{AccessKeySecret: secret},
+			}
+			return verifyCtx, signer.VerifyQuery(context.TODO(), verifyCtx)
+		}
+
+		verifyCtx, err := verify("GET", request.URL.String(), "sk")
+		assert.Nil(t, err)
+		assert.Equal(t, expiration.Unix(), verifyCtx.Time.Unix())
+		assert.Equal(t, signCtx.StringToSign, verifyCtx.StringToSign)
+
+		_, err = verify("GET", request.URL.String(), "sk1")
+		assert.Equal(t, ErrSignatureMismatch, err)
+		_, err = verify("HEAD", request.URL.String(), "sk")
+		assert.Equal(t, ErrSignatureMismatch, err)
+		_, err = verify("GET", "http://bucket.oss-cn-hangzhou.aliyuncs.com/key?versionId=v1", "sk")
+		assert.NotNil(t, err)
+		assert.NotEqual(t, ErrSignatureMismatch, err)
+		_, err = verify("GET", request.URL.String(), "")
+		assert.Contains(t, err.Error(), "SigningContext.Credentials is null or empty")
+	}
+}
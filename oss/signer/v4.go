@@ -213,6 +213,14 @@ func buildScope(date, region, product string) string {
 }
 
 func (s *SignerV4) calcSignature(sk, date, region, product, stringToSign string) string {
+	h := hmac.New(func() hash.Hash { return sha256.New() }, deriveSigningKey(sk, date, region, product))
+	io.WriteString(h, stringToSign)
+	signature := hex.EncodeToString(h.Sum(nil))
+
+	return signature
+}
+
+func deriveSigningKey(sk, date, region, product string) []byte {
 	hmacHash := func() hash.Hash { return sha256.New() }
 
 	signingKey := "aliyun_v4" + sk
@@ -231,13 +239,7 @@ func (s *SignerV4) calcSignature(sk, date, region, product, stringToSign string)
 
 	h4 := hmac.New(hmacHash, h3Key)
 	io.WriteString(h4, "aliyun_v4_request")
-	h4Key := h4.Sum(nil)
-
-	h := hmac.New(hmacHash, h4Key)
-	io.WriteString(h, stringToSign)
-	signature := hex.EncodeToString(h.Sum(nil))
-
-	return signature
+	return h4.Sum(nil)
 }
 
 func (s *SignerV4) authHeader(ctx context.Context, signingCtx *SigningContext) error {
@@ -260,7 +262,11 @@ func (s *SignerV4) authHeader(ctx context.Context, signingCtx *SigningContext) e
 	}
 
 	// Other Headers
-	request.Header.Set(contentSha256Header, unsignedPayload)
+	if signingCtx.StreamingPayload {
+		request.Header.Set(contentSha256Header, StreamingPayloadTrailer)
+	} else {
+		request.Header.Set(contentSha256Header, unsignedPayload)
+	}
 
 	// Scope
 	region := toString(signingCtx.Region)
@@ -271,6 +277,7 @@ func (s *SignerV4) authHeader(ctx context.Context, signingCtx *SigningContext) e
 
 	// CanonicalRequest
 	canonicalRequest := s.calcCanonicalRequest(signingCtx, additionalHeaders)
+	signingCtx.CanonicalRequest = canonicalRequest
 
 	// StringToSign
 	stringToSign := s.calcStringToSign(datetime, scope, canonicalRequest)
@@ -292,6 +299,17 @@ func (s *SignerV4) authHeader(ctx context.Context, signingCtx *SigningContext) e
 
 	request.Header.Set(authorizationHeader, buf.String())
 
+	// the signature of the request is the seed of the chunk signatures
+	signingCtx.ChunkSigner = nil
+	if signingCtx.StreamingPayload {
+		signingCtx.ChunkSigner = &ChunkSigner{
+			signingKey:    deriveSigningKey(cred.AccessKeySecret, date, region, product),
+			datetime:      datetime,
+			scope:         scope,
+			prevSignature: signature,
+		}
+	}
+
 	//fmt.Printf("canonicalRequest:\n%s\n", canonicalRequest)
 
 	//fmt.Printf("stringToSign:\n%s\n", stringToSign)
@@ -338,6 +356,7 @@ func (s *SignerV4) authQuery(ctx context.Context, signingCtx *SigningContext) er
 
 	// CanonicalRequest
 	canonicalRequest := s.calcCanonicalRequest(signingCtx, additionalHeaders)
+	signingCtx.CanonicalRequest = canonicalRequest
 
 	// StringToSign
 	stringToSign := s.calcStringToSign(datetime, scope, canonicalRequest)
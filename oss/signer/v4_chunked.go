@@ -0,0 +1,87 @@
+package signer
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"time"
+)
+
+const (
+	// StreamingPayloadTrailer is the value of x-oss-content-sha256 if the payload is signed chunk by chunk,
+	// and the checksum of the payload is sent in the trailer.
+	StreamingPayloadTrailer = "STREAMING-OSS4-HMAC-SHA256-PAYLOAD-TRAILER"
+
+	algorithmV4Payload = "OSS4-HMAC-SHA256-PAYLOAD"
+	algorithmV4Trailer = "OSS4-HMAC-SHA256-TRAILER"
+
+	// hex(sha256(""))
+	emptyStringSha256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// ChunkSigner signs the chunks of a streaming payload. The signature of a chunk is chained from the previous one,
+// and the first one is chained from the signature of the request.
+type ChunkSigner struct {
+	signingKey    []byte
+	datetime      string
+	scope         string
+	prevSignature string
+}
+
+// NewChunkSigner returns a chunk signer seeded with the signature of the request, e.g. to verify a streaming payload.
+func NewChunkSigner(sk, region, product string, signTime time.Time, seedSignature string) *ChunkSigner {
+	utcTime := signTime.UTC()
+	date := utcTime.Format(iso8601DateFormat)
+	return &ChunkSigner{
+		signingKey:    deriveSigningKey(sk, date, region, product),
+		datetime:      utcTime.Format(iso8601DatetimeFormat),
+		scope:         buildScope(date, region, product),
+		prevSignature: seedSignature,
+	}
+}
+
+// SignChunk returns the signature of the chunk, the last chunk is empty.
+func (s *ChunkSigner) SignChunk(data []byte) string {
+	/*
+		StringToSign
+		"OSS4-HMAC-SHA256-PAYLOAD" + "\n" +
+		TimeStamp + "\n" +
+		Scope + "\n" +
+		PreviousSignature + "\n" +
+		Hex(SHA256Hash("")) + "\n" +
+		Hex(SHA256Hash(ChunkData))
+	*/
+	dataHash := sha256.Sum256(data)
+	return s.sign(algorithmV4Payload + "\n" +
+		s.datetime + "\n" +
+		s.scope + "\n" +
+		s.prevSignature + "\n" +
+		emptyStringSha256 + "\n" +
+		hex.EncodeToString(dataHash[:]))
+}
+
+// SignTrailer returns the signature of the trailing headers, each one is formatted as "name:value\n".
+func (s *ChunkSigner) SignTrailer(trailer []byte) string {
+	/*
+		StringToSign
+		"OSS4-HMAC-SHA256-TRAILER" + "\n" +
+		TimeStamp + "\n" +
+		Scope + "\n" +
+		PreviousSignature + "\n" +
+		Hex(SHA256Hash(TrailingHeaders))
+	*/
+	dataHash := sha256.Sum256(trailer)
+	return s.sign(algorithmV4Trailer + "\n" +
+		s.datetime + "\n" +
+		s.scope + "\n" +
+		s.prevSignature + "\n" +
+		hex.EncodeToString(dataHash[:]))
+}
+
+func (s *ChunkSigner) sign(stringToSign string) string {
+	h := hmac.New(sha256.New, s.signingKey)
+	io.WriteString(h, stringToSign)
+	s.prevSignature = hex.EncodeToString(h.Sum(nil))
+	return s.prevSignature
+}
@@ -0,0 +1,153 @@
+package signer
+
+import (
+	"context"
+	"crypto/hmac"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/credentials"
+)
+
+// ErrSignatureMismatch is returned by VerifyQuery if the signature in the query is not the expected one.
+var ErrSignatureMismatch = errors.New("signature does not match")
+
+func checkVerifyContext(signingCtx *SigningContext) error {
+	if signingCtx == nil {
+		return fmt.Errorf("SigningContext is null.")
+	}
+
+	if signingCtx.Credentials == nil || signingCtx.Credentials.AccessKeySecret == "" {
+		return fmt.Errorf("SigningContext.Credentials is null or empty.")
+	}
+
+	if signingCtx.Request == nil || signingCtx.Request.URL == nil {
+		return fmt.Errorf("SigningContext.Request is null.")
+	}
+	return nil
+}
+
+func compareSignature(expected, actual string) error {
+	if !hmac.Equal([]byte(expected), []byte(actual)) {
+		return ErrSignatureMismatch
+	}
+	return nil
+}
+
+// VerifyQuery signs the presigned request again with the AccessKeySecret of signingCtx.Credentials, and compares the signatures.
+// The access key id, the security token and the expiration are read from the query, signingCtx.Time is set to the expiration.
+func (s *SignerV1) VerifyQuery(ctx context.Context, signingCtx *SigningContext) error {
+	if err := checkVerifyContext(signingCtx); err != nil {
+		return err
+	}
+
+	query := signingCtx.Request.URL.Query()
+	signature := query.Get(signatureQuery)
+	if signature == "" {
+		return fmt.Errorf("missing %s in the query", signatureQuery)
+	}
+	expires, err := strconv.ParseInt(query.Get(expiresQuery), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid %s in the query, %v", expiresQuery, err)
+	}
+	cred := credentials.Credentials{
+		AccessKeyID:     query.Get(accessKeyIdQuery),
+		AccessKeySecret: signingCtx.Credentials.AccessKeySecret,
+		SecurityToken:   query.Get(securityTokenQuery),
+	}
+	for _, k := range []string{signatureQuery, expiresQuery, accessKeyIdQuery, securityTokenQuery} {
+		query.Del(k)
+	}
+
+	request := signingCtx.Request.Clone(ctx)
+	request.URL.RawQuery = query.Encode()
+	verifyCtx := &SigningContext{
+		Bucket:          signingCtx.Bucket,
+		Key:             signingCtx.Key,
+		Request:         request,
+		SubResource:     signingCtx.SubResource,
+		Credentials:     &cred,
+		AuthMethodQuery: true,
+		Time:            time.Unix(expires, 0),
+	}
+	if err = s.authQuery(ctx, verifyCtx); err != nil {
+		return err
+	}
+	signingCtx.Time = verifyCtx.Time
+	signingCtx.StringToSign = verifyCtx.StringToSign
+
+	return compareSignature(request.URL.Query().Get(signatureQuery), signature)
+}
+
+// VerifyQuery signs the presigned request again with the AccessKeySecret of signingCtx.Credentials, and compares the signatures.
+// The access key id, the scope, the security token, the additional headers and the time are read from the query,
+// signingCtx.Time is set to the expiration.
+func (s *SignerV4) VerifyQuery(ctx context.Context, signingCtx *SigningContext) error {
+	if err := checkVerifyContext(signingCtx); err != nil {
+		return err
+	}
+
+	query := signingCtx.Request.URL.Query()
+	if v := query.Get("x-oss-signature-version"); v != algorithmV4 {
+		return fmt.Errorf("invalid x-oss-signature-version %q in the query", v)
+	}
+	signature := query.Get("x-oss-signature")
+	if signature == "" {
+		return fmt.Errorf("missing x-oss-signature in the query")
+	}
+	signTime, err := time.Parse(iso8601DatetimeFormat, query.Get("x-oss-date"))
+	if err != nil {
+		return fmt.Errorf("invalid x-oss-date in the query, %v", err)
+	}
+	expires, err := strconv.ParseInt(query.Get("x-oss-expires"), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid x-oss-expires in the query, %v", err)
+	}
+	// AccessKeyId/Date/Region/Product/aliyun_v4_request
+	scope := strings.Split(query.Get("x-oss-credential"), "/")
+	if len(scope) != 5 || scope[1] != signTime.Format(iso8601DateFormat) || scope[4] != "aliyun_v4_request" {
+		return fmt.Errorf("invalid x-oss-credential %q in the query", query.Get("x-oss-credential"))
+	}
+	cred := credentials.Credentials{
+		AccessKeyID:     scope[0],
+		AccessKeySecret: signingCtx.Credentials.AccessKeySecret,
+		SecurityToken:   query.Get("x-oss-security-token"),
+	}
+	var additionalHeaders []string
+	if v := query.Get("x-oss-additional-headers"); v != "" {
+		additionalHeaders = strings.Split(v, ";")
+	}
+	for _, k := range []string{"x-oss-signature-version", "x-oss-signature", "x-oss-date", "x-oss-expires",
+		"x-oss-credential", "x-oss-security-token", "x-oss-additional-headers"} {
+		query.Del(k)
+	}
+
+	request := signingCtx.Request.Clone(ctx)
+	request.URL.RawQuery = strings.Replace(query.Encode(), "+", "%20", -1)
+	verifyCtx := &SigningContext{
+		Product:           &scope[3],
+		Region:            &scope[2],
+		Bucket:            signingCtx.Bucket,
+		Key:               signingCtx.Key,
+		Request:           request,
+		SubResource:       signingCtx.SubResource,
+		AdditionalHeaders: additionalHeaders,
+		Credentials:       &cred,
+		AuthMethodQuery:   true,
+		Time:              signTime.Add(time.Duration(expires) * time.Second),
+		signTime:          &signTime,
+	}
+	if err = s.authQuery(ctx, verifyCtx); err != nil {
+		return err
+	}
+	signingCtx.Time = verifyCtx.Time
+	signingCtx.StringToSign = verifyCtx.StringToSign
+	signingCtx.CanonicalRequest = verifyCtx.CanonicalRequest
+
+	expected, _ := url.ParseQuery(request.URL.RawQuery)
+	return compareSignature(expected.Get("x-oss-signature"), signature)
+}
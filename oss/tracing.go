@@ -0,0 +1,232 @@
+package oss
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+)
+
+// Tracer creates spans for OSS operations.
+// It has the same shape as an OpenTelemetry trace.Tracer, so an adapter is only a few lines:
+//
+//	type otelTracer struct{ t trace.Tracer }
+//
+//	func (o otelTracer) Start(ctx context.Context, name string, attrs ...oss.Attribute) (context.Context, oss.Span) {
+//		ctx, span := o.t.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient))
+//		s := otelSpan{span}
+//		s.SetAttributes(attrs...)
+//		return ctx, s
+//	}
+//
+// The returned context must carry the new span, so that the spans of retries,
+// parts and nested calls become its children.
+type Tracer interface {
+	Start(ctx context.Context, spanName string, attrs ...Attribute) (context.Context, Span)
+}
+
+// Span is a single traced unit of work.
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	RecordError(err error)
+	End()
+}
+
+// Attribute is a key-value pair attached to a span.
+// The value is one of string, bool, int, int64 or float64.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// Span attribute keys
+const (
+	AttributeOperation    = "oss.operation"
+	AttributeBucket       = "oss.bucket"
+	AttributeKey          = "oss.key"
+	AttributeRegion       = "oss.region"
+	AttributeRequestId    = "oss.request_id"
+	AttributeAttempts     = "oss.attempts"
+	AttributeAttempt      = "oss.attempt"
+	AttributeBytesSent    = "oss.bytes_sent"
+	AttributeBytesRecv    = "oss.bytes_received"
+	AttributeUploadId     = "oss.upload_id"
+	AttributeRange        = "oss.range"
+	AttributeStatusCode   = "http.response.status_code"
+	AttributeHttpMethod   = "http.request.method"
+	AttributeServerAddr   = "server.address"
+	AttributeErrorCode    = "oss.error_code"
+	AttributeFilePath     = "oss.file_path"
+	AttributeSourceBucket = "oss.source_bucket"
+	AttributeSourceKey    = "oss.source_key"
+)
+
+type nopSpan struct{}
+
+func (nopSpan) SetAttributes(...Attribute) {}
+func (nopSpan) RecordError(error)          {}
+func (nopSpan) End()                       {}
+
+type spanContextKey struct{}
+
+// startSpan starts a span with the tracer, the span is also saved into the context.
+// It returns a nop span if the tracer is nil.
+func startSpan(ctx context.Context, tracer Tracer, name string, attrs ...Attribute) (context.Context, Span) {
+	if tracer == nil {
+		return ctx, nopSpan{}
+	}
+	ctx, span := tracer.Start(ctx, name, attrs...)
+	if span == nil {
+		return ctx, nopSpan{}
+	}
+	return context.WithValue(ctx, spanContextKey{}, span), span
+}
+
+// endSpan records the error, if any, and ends the span.
+func endSpan(span Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+// spanFromContext returns the span of the operation that the context belongs to.
+func spanFromContext(ctx context.Context) Span {
+	if ctx != nil {
+		if span, ok := ctx.Value(spanContextKey{}).(Span); ok {
+			return span
+		}
+	}
+	return nopSpan{}
+}
+
+// getTracer returns the tracer of the client behind the api client.
+func getTracer(c any) Tracer {
+	switch t := c.(type) {
+	case *Client:
+		return t.options.Tracer
+	case *EncryptionClient:
+		return t.Unwrap().options.Tracer
+	}
+	return nil
+}
+
+func operationSpanAttributes(input *OperationInput, opts *Options) []Attribute {
+	attrs := []Attribute{
+		{Key: AttributeOperation, Value: input.OpName},
+		{Key: AttributeRegion, Value: opts.Region},
+	}
+	if input.Bucket != nil {
+		attrs = append(attrs, Attribute{Key: AttributeBucket, Value: *input.Bucket})
+	}
+	if input.Key != nil {
+		attrs = append(attrs, Attribute{Key: AttributeKey, Value: *input.Key})
+	}
+	return attrs
+}
+
+func objectSpanAttributes(bucket, key *string) []Attribute {
+	var attrs []Attribute
+	if bucket != nil {
+		attrs = append(attrs, Attribute{Key: AttributeBucket, Value: *bucket})
+	}
+	if key != nil {
+		attrs = append(attrs, Attribute{Key: AttributeKey, Value: *key})
+	}
+	return attrs
+}
+
+// byteCounter counts the bytes written to it.
+type byteCounter struct {
+	n int64
+}
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	atomic.AddInt64(&c.n, int64(len(p)))
+	return len(p), nil
+}
+
+func (c *byteCounter) Count() int64 {
+	return atomic.LoadInt64(&c.n)
+}
+
+var _ io.Writer = (*byteCounter)(nil)
+
+func setErrorSpanAttributes(span Span, err error) {
+	var serr *ServiceError
+	if errors.As(err, &serr) {
+		span.SetAttributes(
+			Attribute{Key: AttributeStatusCode, Value: serr.StatusCode},
+			Attribute{Key: AttributeRequestId, Value: serr.RequestID},
+			Attribute{Key: AttributeErrorCode, Value: serr.Code},
+		)
+	}
+}
+
+func setResponseSpanAttributes(span Span, output *OperationOutput, err error) {
+	if err != nil {
+		setErrorSpanAttributes(span, err)
+		return
+	}
+	if output == nil {
+		return
+	}
+	attrs := []Attribute{
+		{Key: AttributeStatusCode, Value: output.StatusCode},
+	}
+	if output.Headers != nil {
+		attrs = append(attrs, Attribute{Key: AttributeRequestId, Value: output.Headers.Get(HeaderOssRequestID)})
+		if n, perr := strconv.ParseInt(output.Headers.Get(HTTPHeaderContentLength), 10, 64); perr == nil {
+			attrs = append(attrs, Attribute{Key: AttributeBytesRecv, Value: n})
+		}
+	}
+	span.SetAttributes(attrs...)
+}
+
+func setHttpResponseSpanAttributes(span Span, response *http.Response, err error) {
+	if err != nil {
+		setErrorSpanAttributes(span, err)
+		return
+	}
+	if response == nil {
+		return
+	}
+	span.SetAttributes(
+		Attribute{Key: AttributeStatusCode, Value: response.StatusCode},
+		Attribute{Key: AttributeRequestId, Value: response.Header.Get(HeaderOssRequestID)},
+	)
+}
+
+func uploadSpanAttributes(request *PutObjectRequest) []Attribute {
+	if request == nil {
+		return nil
+	}
+	return objectSpanAttributes(request.Bucket, request.Key)
+}
+
+func downloadSpanAttributes(request *GetObjectRequest) []Attribute {
+	if request == nil {
+		return nil
+	}
+	attrs := objectSpanAttributes(request.Bucket, request.Key)
+	if request.Range != nil {
+		attrs = append(attrs, Attribute{Key: AttributeRange, Value: *request.Range})
+	}
+	return attrs
+}
+
+func copySpanAttributes(request *CopyObjectRequest) []Attribute {
+	if request == nil {
+		return nil
+	}
+	attrs := objectSpanAttributes(request.Bucket, request.Key)
+	if request.SourceBucket != nil {
+		attrs = append(attrs, Attribute{Key: AttributeSourceBucket, Value: *request.SourceBucket})
+	}
+	if request.SourceKey != nil {
+		attrs = append(attrs, Attribute{Key: AttributeSourceKey, Value: *request.SourceKey})
+	}
+	return attrs
+}
@@ -0,0 +1,224 @@
+package oss
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/credentials"
+	"github.com/stretchr/testify/assert"
+)
+
+type testSpan struct {
+	name   string
+	parent *testSpan
+	attrs  map[string]any
+	errs   []error
+	ended  bool
+	mu     *sync.Mutex
+}
+
+func (s *testSpan) SetAttributes(attrs ...Attribute) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, a := range attrs {
+		s.attrs[a.Key] = a.Value
+	}
+}
+
+func (s *testSpan) RecordError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errs = append(s.errs, err)
+}
+
+func (s *testSpan) End() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ended = true
+}
+
+type testTracer struct {
+	mu    sync.Mutex
+	spans []*testSpan
+}
+
+type testTracerKey struct{}
+
+func (t *testTracer) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	parent, _ := ctx.Value(testTracerKey{}).(*testSpan)
+	s := &testSpan{name: name, parent: parent, attrs: map[string]any{}, mu: &t.mu}
+	s.SetAttributes(attrs...)
+	t.mu.Lock()
+	t.spans = append(t.spans, s)
+	t.mu.Unlock()
+	return context.WithValue(ctx, testTracerKey{}, s), s
+}
+
+func (t *testTracer) find(name string) []*testSpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var spans []*testSpan
+	for _, s := range t.spans {
+		if s.name == name {
+			spans = append(spans, s)
+		}
+	}
+	return spans
+}
+
+func TestTracer_Operation(t *testing.T) {
+	server := testSetupMockServer(t, 200, map[string]string{"x-oss-request-id": "id-1234"}, []byte("hello world"),
+		func(t *testing.T, r *http.Request) {})
+	defer server.Close()
+
+	tracer := &testTracer{}
+	cfg := LoadDefaultConfig().
+		WithCredentialsProvider(credentials.NewAnonymousCredentialsProvider()).
+		WithRegion("cn-hangzhou").
+		WithEndpoint(server.URL).
+		WithTracer(tracer)
+
+	client := NewClient(cfg)
+	_, err := client.PutObject(context.TODO(), &PutObjectRequest{
+		Bucket: Ptr("bucket"),
+		Key:    Ptr("key"),
+		Body:   bytes.NewReader([]byte("hello oss")),
+	})
+	assert.Nil(t, err)
+
+	spans := tracer.find("PutObject")
+	assert.Len(t, spans, 1)
+	op := spans[0]
+	assert.True(t, op.ended)
+	assert.Nil(t, op.parent)
+	assert.Equal(t, "PutObject", op.attrs[AttributeOperation])
+	assert.Equal(t, "bucket", op.attrs[AttributeBucket])
+	assert.Equal(t, "key", op.attrs[AttributeKey])
+	assert.Equal(t, "cn-hangzhou", op.attrs[AttributeRegion])
+	assert.Equal(t, 200, op.attrs[AttributeStatusCode])
+	assert.Equal(t, "id-1234", op.attrs[AttributeRequestId])
+	assert.Equal(t, int64(9), op.attrs[AttributeBytesSent])
+	assert.Equal(t, 1, op.attrs[AttributeAttempts])
+
+	spans = tracer.find("HTTP PUT")
+	assert.Len(t, spans, 1)
+	assert.Equal(t, op, spans[0].parent)
+	assert.Equal(t, 1, spans[0].attrs[AttributeAttempt])
+	assert.Equal(t, 200, spans[0].attrs[AttributeStatusCode])
+	assert.True(t, spans[0].ended)
+
+	// per-operation tracer
+	opTracer := &testTracer{}
+	_, err = client.GetObject(context.TODO(), &GetObjectRequest{
+		Bucket: Ptr("bucket"),
+		Key:    Ptr("key"),
+	}, func(o *Options) { o.Tracer = opTracer })
+	assert.Nil(t, err)
+	assert.Len(t, opTracer.find("GetObject"), 1)
+	assert.Len(t, tracer.find("GetObject"), 0)
+
+	// presigning sends nothing, there is no attempt span
+	_, err = client.Presign(context.TODO(), &GetObjectRequest{
+		Bucket: Ptr("bucket"),
+		Key:    Ptr("key"),
+	})
+	assert.Nil(t, err)
+	assert.Len(t, tracer.find("HTTP GET"), 0)
+}
+
+func TestTracer_Retry(t *testing.T) {
+	server := testSetupMockServer(t, 500, map[string]string{"x-oss-request-id": "id-1234"},
+		[]byte(`<Error><Code>InternalError</Code><Message>error</Message><RequestId>id-1234</RequestId></Error>`),
+		func(t *testing.T, r *http.Request) {})
+	defer server.Close()
+
+	tracer := &testTracer{}
+	cfg := LoadDefaultConfig().
+		WithCredentialsProvider(credentials.NewAnonymousCredentialsProvider()).
+		WithRegion("cn-hangzhou").
+		WithEndpoint(server.URL).
+		WithRetryMaxAttempts(3).
+		WithTracer(tracer)
+
+	client := NewClient(cfg)
+	_, err := client.GetObject(context.TODO(), &GetObjectRequest{
+		Bucket: Ptr("bucket"),
+		Key:    Ptr("key"),
+	})
+	assert.NotNil(t, err)
+
+	spans := tracer.find("GetObject")
+	assert.Len(t, spans, 1)
+	op := spans[0]
+	assert.Equal(t, 3, op.attrs[AttributeAttempts])
+	assert.Equal(t, "InternalError", op.attrs[AttributeErrorCode])
+	assert.Len(t, op.errs, 1)
+	var serr *ServiceError
+	assert.True(t, errors.As(op.errs[0], &serr))
+
+	spans = tracer.find("HTTP GET")
+	assert.Len(t, spans, 3)
+	for i, s := range spans {
+		assert.Equal(t, op, s.parent)
+		assert.Equal(t, i+1, s.attrs[AttributeAttempt])
+		assert.Equal(t, 500, s.attrs[AttributeStatusCode])
+		assert.Len(t, s.errs, 1)
+		assert.True(t, s.ended)
+	}
+}
+
+func TestTracer_Uploader(t *testing.T) {
+	partSize := int64(100 * 1024)
+	length := 3*100*1024 + 123
+	partsNum := length/int(partSize) + 1
+	tracker := &uploaderMockTracker{
+		partNum:       partsNum,
+		saveDate:      make([][]byte, partsNum),
+		checkTime:     make([]time.Time, partsNum),
+		timeout:       make([]time.Duration, partsNum),
+		uploadPartErr: make([]bool, partsNum),
+	}
+
+	server := testSetupUploaderMockServer(t, tracker)
+	defer server.Close()
+
+	tracer := &testTracer{}
+	cfg := LoadDefaultConfig().
+		WithCredentialsProvider(credentials.NewAnonymousCredentialsProvider()).
+		WithRegion("cn-hangzhou").
+		WithEndpoint(server.URL).
+		WithTracer(tracer)
+
+	client := NewClient(cfg)
+	u := NewUploader(client, func(uo *UploaderOptions) {
+		uo.ParallelNum = 2
+		uo.PartSize = partSize
+	})
+
+	_, err := u.UploadFrom(context.TODO(), &PutObjectRequest{
+		Bucket: Ptr("bucket"),
+		Key:    Ptr("key"),
+	}, bytes.NewReader([]byte(randStr(length))))
+	assert.Nil(t, err)
+
+	spans := tracer.find("Uploader.UploadFrom")
+	assert.Len(t, spans, 1)
+	root := spans[0]
+	assert.True(t, root.ended)
+	assert.Equal(t, "bucket", root.attrs[AttributeBucket])
+	assert.Equal(t, "uploadId-1234", root.attrs[AttributeUploadId])
+
+	for _, name := range []string{"InitiateMultipartUpload", "UploadPart", "CompleteMultipartUpload"} {
+		spans = tracer.find(name)
+		assert.NotEmpty(t, spans, name)
+		for _, s := range spans {
+			assert.Equal(t, root, s.parent)
+		}
+	}
+	assert.Len(t, tracer.find("UploadPart"), partsNum)
+}
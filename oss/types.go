@@ -83,6 +83,12 @@ type ResultCommon struct {
 	OpMetadata OperationMetadata
 }
 
+// Diagnostics returns how the operation went, or nil if not available.
+func (r *ResultCommon) Diagnostics() *Diagnostics {
+	d, _ := r.OpMetadata.Get(OpMetaKeyDiagnostics).(*Diagnostics)
+	return d
+}
+
 type ResultCommonInterface interface {
 	CopyIn(status string, statusCode int, headers http.Header, meta OperationMetadata)
 }
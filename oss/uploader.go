@@ -1,6 +1,7 @@
 package oss
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"fmt"
@@ -31,6 +32,8 @@ type Uploader struct {
 	client             UploadAPIClient
 	featureFlags       FeatureFlagsType
 	isEncryptionClient bool
+	tracer             Tracer
+	metrics            MetricsCollector
 }
 
 // NewUploader creates a new Uploader instance to upload objects.
@@ -50,6 +53,8 @@ func NewUploader(c UploadAPIClient, optFns ...func(*UploaderOptions)) *Uploader
 		client:             c,
 		options:            options,
 		isEncryptionClient: false,
+		tracer:             getTracer(c),
+		metrics:            getMetricsCollector(c),
 	}
 
 	//Get Client Feature
@@ -94,7 +99,11 @@ func (m *UploadError) Unwrap() error {
 	return m.Err
 }
 
-func (u *Uploader) UploadFrom(ctx context.Context, request *PutObjectRequest, body io.Reader, optFns ...func(*UploaderOptions)) (*UploadResult, error) {
+func (u *Uploader) UploadFrom(ctx context.Context, request *PutObjectRequest, body io.Reader, optFns ...func(*UploaderOptions)) (result *UploadResult, err error) {
+	ctx = ensureCorrelationId(ctx)
+	ctx, span := startSpan(ctx, u.tracer, "Uploader.UploadFrom", uploadSpanAttributes(request)...)
+	defer func() { endSpan(span, err) }()
+
 	// Uploader wrapper
 	delegate, err := u.newDelegate(ctx, request, optFns...)
 	if err != nil {
@@ -109,7 +118,12 @@ func (u *Uploader) UploadFrom(ctx context.Context, request *PutObjectRequest, bo
 	return delegate.upload()
 }
 
-func (u *Uploader) UploadFile(ctx context.Context, request *PutObjectRequest, filePath string, optFns ...func(*UploaderOptions)) (*UploadResult, error) {
+func (u *Uploader) UploadFile(ctx context.Context, request *PutObjectRequest, filePath string, optFns ...func(*UploaderOptions)) (result *UploadResult, err error) {
+	ctx = ensureCorrelationId(ctx)
+	ctx, span := startSpan(ctx, u.tracer, "Uploader.UploadFile",
+		append(uploadSpanAttributes(request), Attribute{Key: AttributeFilePath, Value: filePath})...)
+	defer func() { endSpan(span, err) }()
+
 	// Uploader wrapper
 	delegate, err := u.newDelegate(ctx, request, optFns...)
 	if err != nil {
@@ -139,7 +153,7 @@ func (u *Uploader) UploadFile(ctx context.Context, request *PutObjectRequest, fi
 		return nil, err
 	}
 
-	result, err := delegate.upload()
+	result, err = delegate.upload()
 
 	return result, delegate.closeReader(file, err)
 }
@@ -157,6 +171,9 @@ type uploaderDelegate struct {
 	hashCRC64   uint64
 	transferred int64
 
+	// the parts are streamed from the body without buffering
+	streaming bool
+
 	// Source's Info, from file or reader
 	filePath string
 	fileInfo os.FileInfo
@@ -258,6 +275,10 @@ func (u *uploaderDelegate) applySource() error {
 	u.totalSize = totalSize
 	u.options.PartSize = partSize
 
+	// the parts of unknown length are signed chunk by chunk, so they are not buffered
+	u.streaming = totalSize < 0 && !u.base.isEncryptionClient &&
+		(u.base.featureFlags&FeatureEnableChunkedSigning) > 0
+
 	return nil
 }
 
@@ -386,7 +407,7 @@ func (d *uploaderDelegate) resumeCSEContext(result *ListPartsResult) (*Encryptio
 		return nil, err
 	}
 
-	cc, err := sc.defualtCCBuilder.ContentCipherEnv(envelope)
+	cc, err := sc.getContentCipherBuilder(envelope).ContentCipherEnv(envelope)
 	if err != nil {
 		return nil, err
 	}
@@ -483,8 +504,11 @@ func (u *uploaderDelegate) nextReader() (io.ReadSeeker, int, func(), error) {
 type uploaderChunk struct {
 	partNum int32
 	size    int
-	body    io.ReadSeeker
+	body    io.Reader
 	cleanup func()
+
+	// counts the size of a streamed part
+	counter *byteCounter
 }
 
 type uploadPartCRC struct {
@@ -540,6 +564,7 @@ func (u *uploaderDelegate) multiPart() (*UploadResult, error) {
 	//fmt.Printf("getUploadId result: %v, %#v\n", uploadId, err)
 	uploadId := uploadIdInfo.uploadId
 	startPartNum := uploadIdInfo.startNum
+	spanFromContext(u.context).SetAttributes(Attribute{Key: AttributeUploadId, Value: uploadId})
 
 	// Update Checkpoint
 	if u.checkpoint != nil {
@@ -563,6 +588,49 @@ func (u *uploaderDelegate) multiPart() (*UploadResult, error) {
 		return e.Unwrap()
 	}
 
+	uploadPartFn := func(data uploaderChunk) {
+		done := trackPartInFlight(u.base.metrics, "upload", u.request.Bucket)
+		upResult, err := u.client.UploadPart(
+			u.context,
+			&UploadPartRequest{
+				Bucket:              u.request.Bucket,
+				Key:                 u.request.Key,
+				UploadId:            Ptr(uploadId),
+				PartNumber:          data.partNum,
+				Body:                data.body,
+				CSEMultiPartContext: uploadIdInfo.cseContext,
+				RequestPayer:        u.request.RequestPayer,
+			},
+			u.options.ClientOptions...)
+		done()
+		//fmt.Printf("UploadPart result: %#v, %#v\n", upResult, err)
+
+		if data.counter != nil {
+			data.size = int(data.counter.Count())
+		}
+
+		if err == nil {
+			mu.Lock()
+			parts = append(parts, UploadPart{ETag: upResult.ETag, PartNumber: data.partNum})
+			if enableCRC {
+				// the crc64 is calculated on the uploaded data, which is longer if it is encrypted by aes gcm
+				size := data.size
+				if uploadIdInfo.cseContext != nil {
+					size = int(uploadIdInfo.cseContext.ContentCipher.GetEncryptedLen(int64(size)))
+				}
+				crcParts = append(crcParts,
+					uploadPartCRC{partNumber: data.partNum, hashCRC64: upResult.HashCRC64, size: size})
+			}
+			if u.request.ProgressFn != nil {
+				u.transferred += int64(data.size)
+				u.request.ProgressFn(int64(data.size), u.transferred, u.totalSize)
+			}
+			mu.Unlock()
+		} else {
+			saveErrFn(err)
+		}
+	}
+
 	// readChunk runs in worker goroutines to pull chunks off of the ch channel
 	readChunkFn := func(ch chan uploaderChunk) {
 		defer wg.Done()
@@ -573,52 +641,46 @@ func (u *uploaderDelegate) multiPart() (*UploadResult, error) {
 			}
 
 			if getErrFn() == nil {
-				upResult, err := u.client.UploadPart(
-					u.context,
-					&UploadPartRequest{
-						Bucket:              u.request.Bucket,
-						Key:                 u.request.Key,
-						UploadId:            Ptr(uploadId),
-						PartNumber:          data.partNum,
-						Body:                data.body,
-						CSEMultiPartContext: uploadIdInfo.cseContext,
-						RequestPayer:        u.request.RequestPayer,
-					},
-					u.options.ClientOptions...)
-				//fmt.Printf("UploadPart result: %#v, %#v\n", upResult, err)
-
-				if err == nil {
-					mu.Lock()
-					parts = append(parts, UploadPart{ETag: upResult.ETag, PartNumber: data.partNum})
-					if enableCRC {
-						crcParts = append(crcParts,
-							uploadPartCRC{partNumber: data.partNum, hashCRC64: upResult.HashCRC64, size: data.size})
-					}
-					if u.request.ProgressFn != nil {
-						u.transferred += int64(data.size)
-						u.request.ProgressFn(int64(data.size), u.transferred, u.totalSize)
-					}
-					mu.Unlock()
-				} else {
-					saveErrFn(err)
-				}
+				uploadPartFn(data)
 			}
 			data.cleanup()
 		}
 	}
 
-	ch := make(chan uploaderChunk, u.options.ParallelNum)
-	for i := 0; i < u.options.ParallelNum; i++ {
-		wg.Add(1)
-		go readChunkFn(ch)
-	}
-
 	// Read and queue the parts
 	var (
 		qnum int32 = startPartNum
 		qerr error = nil
 	)
 
+	if u.streaming {
+		// the parts are read from the body while they are uploaded, so they are uploaded one by one
+		stream := bufio.NewReader(u.body)
+		for getErrFn() == nil {
+			if _, qerr = stream.Peek(1); qerr != nil {
+				if qerr != io.EOF {
+					saveErrFn(qerr)
+				}
+				break
+			}
+			qnum++
+			counter := &byteCounter{}
+			uploadPartFn(uploaderChunk{
+				partNum: qnum,
+				body:    io.TeeReader(io.LimitReader(stream, u.options.PartSize), counter),
+				counter: counter,
+			})
+		}
+		// no more parts are queued
+		qerr = io.EOF
+	}
+
+	ch := make(chan uploaderChunk, u.options.ParallelNum)
+	for i := 0; i < u.options.ParallelNum; i++ {
+		wg.Add(1)
+		go readChunkFn(ch)
+	}
+
 	// consume uploaded parts
 	if u.readerPos > 0 {
 		for _, p := range u.uploadedParts {